@@ -0,0 +1,224 @@
+// Package apikey manages API keys for service-to-service callers. Only a
+// SHA-256 hash of each key is stored; the plain text key is returned once,
+// when it is created.
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgtype"
+
+	"Booking/tracing"
+)
+
+const (
+	keyPrefix = "bk"
+	// lastUsedResolution limits how often last_used_at is written for a key.
+	lastUsedResolution = time.Minute
+)
+
+var (
+	// ErrInvalidKey is returned for malformed, unknown or revoked keys.
+	ErrInvalidKey = errors.New("invalid API key")
+	// ErrNotFound is returned when a key id does not exist.
+	ErrNotFound = errors.New("API key not found")
+)
+
+// Key is a stored API key. It never holds the plain text key.
+type Key struct {
+	ID         int64
+	Name       string
+	Prefix     string
+	Scopes     []string
+	CreatedBy  string
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+// Store keeps API keys in the api_keys table.
+type Store struct {
+	db *tracing.DB
+
+	mu       sync.Mutex
+	lastUsed map[int64]time.Time
+}
+
+func NewStore(db *tracing.DB) *Store {
+	return &Store{db: db, lastUsed: make(map[int64]time.Time)}
+}
+
+// generate returns a new plain text key of the form bk_<prefix>_<secret>,
+// together with its prefix.
+func generate() (key, prefix string, err error) {
+	idBytes := make([]byte, 6)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	prefix = hex.EncodeToString(idBytes)
+	key = keyPrefix + "_" + prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, nil
+}
+
+func parse(key string) (prefix string, ok bool) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != keyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+func hash(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+const keyColumns = "id, name, prefix, scopes, created_by, created_at, last_used_at, revoked_at"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanKey reads a key selected with keyColumns, followed by any extra
+// columns.
+func scanKey(row rowScanner, extra ...interface{}) (*Key, error) {
+	k := &Key{}
+	scopes := pgtype.TextArray{}
+	dest := append([]interface{}{&k.ID, &k.Name, &k.Prefix, &scopes, &k.CreatedBy, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	if err := scopes.AssignTo(&k.Scopes); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Create stores a new key and returns it with its plain text value.
+func (s *Store) Create(ctx context.Context, name string, scopes []string, createdBy string) (*Key, string, error) {
+	plain, prefix, err := generate()
+	if err != nil {
+		return nil, "", fmt.Errorf("generate API key: %w", err)
+	}
+	if scopes == nil {
+		scopes = []string{}
+	}
+	scopesArray := &pgtype.TextArray{}
+	if err := scopesArray.Set(scopes); err != nil {
+		return nil, "", err
+	}
+
+	sqlStatement := `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + keyColumns
+
+	key, err := scanKey(s.db.QueryRowContext(ctx, sqlStatement, name, prefix, hash(plain), scopesArray, createdBy))
+	if err != nil {
+		return nil, "", err
+	}
+	return key, plain, nil
+}
+
+// Revoke marks a key as revoked. Revoking a revoked key is a no-op.
+func (s *Store) Revoke(ctx context.Context, id int64) (*Key, error) {
+	sqlStatement := `
+		UPDATE api_keys
+		SET revoked_at = coalesce(revoked_at, now())
+		WHERE id = $1
+		RETURNING ` + keyColumns
+
+	key, err := scanKey(s.db.QueryRowContext(ctx, sqlStatement, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return key, err
+}
+
+// List returns the keys ordered by id.
+func (s *Store) List(ctx context.Context, includeRevoked bool) ([]*Key, error) {
+	sqlStatement := `
+		SELECT ` + keyColumns + `
+		FROM api_keys
+		WHERE $1 OR revoked_at IS NULL
+		ORDER BY id
+	`
+
+	rows, err := s.db.QueryContext(ctx, sqlStatement, includeRevoked)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*Key
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Verify returns the active key matching the plain text key and records
+// that it was used.
+func (s *Store) Verify(ctx context.Context, plain string) (*Key, error) {
+	prefix, ok := parse(plain)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	sqlStatement := `
+		SELECT ` + keyColumns + `, key_hash
+		FROM api_keys
+		WHERE prefix = $1
+	`
+
+	var keyHash []byte
+	k, err := scanKey(s.db.QueryRowContext(ctx, sqlStatement, prefix), &keyHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(keyHash, hash(plain)) != 1 || k.RevokedAt.Valid {
+		return nil, ErrInvalidKey
+	}
+
+	if err := s.touch(ctx, k.ID); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// touch updates last_used_at, at most once per lastUsedResolution per key.
+func (s *Store) touch(ctx context.Context, id int64) error {
+	now := time.Now()
+	s.mu.Lock()
+	if last, ok := s.lastUsed[id]; ok && now.Sub(last) < lastUsedResolution {
+		s.mu.Unlock()
+		return nil
+	}
+	s.lastUsed[id] = now
+	s.mu.Unlock()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = now() WHERE id = $1`, id)
+	return err
+}
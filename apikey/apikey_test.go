@@ -0,0 +1,206 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"Booking/migrations"
+	"Booking/tracing"
+)
+
+func TestGenerateAndParse(t *testing.T) {
+	key, prefix, err := generate()
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	if !strings.HasPrefix(key, keyPrefix+"_"+prefix+"_") {
+		t.Errorf("key %q does not start with %s_%s_", key, keyPrefix, prefix)
+	}
+	got, ok := parse(key)
+	if !ok || got != prefix {
+		t.Errorf("parse(%q) = %q, %v, want %q, true", key, got, ok, prefix)
+	}
+
+	other, _, err := generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == key {
+		t.Error("generate() returned the same key twice")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		key    string
+		prefix string
+		ok     bool
+	}{
+		{"bk_a1b2c3_secret", "a1b2c3", true},
+		{"bk_a1b2c3_sec_ret", "a1b2c3", true},
+		{"bk_a1b2c3_", "", false},
+		{"bk__secret", "", false},
+		{"bk_a1b2c3", "", false},
+		{"xx_a1b2c3_secret", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		prefix, ok := parse(tt.key)
+		if prefix != tt.prefix || ok != tt.ok {
+			t.Errorf("parse(%q) = %q, %v, want %q, %v", tt.key, prefix, ok, tt.prefix, tt.ok)
+		}
+	}
+}
+
+// newTestStore returns a store on the scratch database named by
+// TEST_DATABASE_DSN, migrated and without keys, or skips the test when it is
+// unset.
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	if err := migrations.Apply(ctx, db); err != nil {
+		t.Fatalf("migrations.Apply() error = %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `TRUNCATE api_keys RESTART IDENTITY`); err != nil {
+		t.Fatal(err)
+	}
+	return NewStore(tracing.WrapDB(db))
+}
+
+func TestVerify(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	key, plain, err := s.Create(ctx, "orders", []string{"orders_service"}, "admin")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	revoked, revokedPlain, err := s.Create(ctx, "old", nil, "admin")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := s.Revoke(ctx, revoked.ID); err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+
+	got, err := s.Verify(ctx, plain)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if got.ID != key.ID || len(got.Scopes) != 1 || got.Scopes[0] != "orders_service" {
+		t.Errorf("Verify() = %+v, want key %d with scope orders_service", got, key.ID)
+	}
+
+	parts := strings.SplitN(plain, "_", 3)
+	forged := parts[0] + "_" + parts[1] + "_" + strings.Repeat("A", len(parts[2]))
+	for name, candidate := range map[string]string{
+		"wrong secret":   forged,
+		"unknown prefix": "bk_000000000000_" + parts[2],
+		"malformed":      "not-a-key",
+		"revoked":        revokedPlain,
+	} {
+		if _, err := s.Verify(ctx, candidate); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Verify() of %s key error = %v, want ErrInvalidKey", name, err)
+		}
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	key, _, err := s.Create(ctx, "orders", nil, "admin")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	first, err := s.Revoke(ctx, key.ID)
+	if err != nil {
+		t.Fatalf("Revoke() error = %v", err)
+	}
+	again, err := s.Revoke(ctx, key.ID)
+	if err != nil {
+		t.Fatalf("second Revoke() error = %v", err)
+	}
+	if !first.RevokedAt.Valid || !again.RevokedAt.Time.Equal(first.RevokedAt.Time) {
+		t.Errorf("revoked_at = %v then %v, want it set once", first.RevokedAt, again.RevokedAt)
+	}
+	if _, err := s.Revoke(ctx, key.ID+100); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Revoke() of a missing key error = %v, want ErrNotFound", err)
+	}
+
+	active, err := s.List(ctx, false)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	all, err := s.List(ctx, true)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(active) != 0 || len(all) != 1 {
+		t.Errorf("List() = %d active and %d in total, want 0 and 1", len(active), len(all))
+	}
+}
+
+func TestLastUsedThrottling(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	key, plain, err := s.Create(ctx, "orders", nil, "admin")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	lastUsed := func() sql.NullTime {
+		t.Helper()
+		var at sql.NullTime
+		if err := s.db.QueryRowContext(ctx, `SELECT last_used_at FROM api_keys WHERE id = $1`, key.ID).Scan(&at); err != nil {
+			t.Fatal(err)
+		}
+		return at
+	}
+	verify := func() {
+		t.Helper()
+		if _, err := s.Verify(ctx, plain); err != nil {
+			t.Fatalf("Verify() error = %v", err)
+		}
+	}
+
+	verify()
+	first := lastUsed()
+	if !first.Valid {
+		t.Fatal("last_used_at was not set by the first use")
+	}
+
+	// Rewind last_used_at so that any further write is visible.
+	past := first.Time.Add(-time.Hour)
+	if _, err := s.db.ExecContext(ctx, `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`, key.ID, past); err != nil {
+		t.Fatal(err)
+	}
+	verify()
+	if got := lastUsed(); !got.Time.Equal(past) {
+		t.Errorf("last_used_at = %v after a use within %v, want it left at %v", got.Time, lastUsedResolution, past)
+	}
+
+	s.mu.Lock()
+	s.lastUsed[key.ID] = time.Now().Add(-lastUsedResolution)
+	s.mu.Unlock()
+	verify()
+	if got := lastUsed(); !got.Time.After(past) {
+		t.Errorf("last_used_at = %v after %v, want it updated", got.Time, lastUsedResolution)
+	}
+}
@@ -0,0 +1,186 @@
+// Package auth authenticates callers of the BookingService with JWT bearer
+// tokens signed with HS256 or RS256.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"Booking/config"
+)
+
+// AdminRole grants access to the administrative RPCs listed in
+// Policy.Admin. It can be held as a role or as a scope.
+const AdminRole = "admin"
+
+// Principal is the authenticated caller of an RPC: a user with a JWT or a
+// service with an API key.
+type Principal struct {
+	Subject string
+	Roles   []string
+	Scopes  []string
+	// APIKeyID is set when the caller authenticated with an API key.
+	APIKeyID int64
+}
+
+// HasRole reports whether the principal holds role.
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HasScope reports whether the principal was granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx that carries p.
+func ContextWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the authenticated caller, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// Claims are the JWT claims understood by the service.
+type Claims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"`
+	Scope string   `json:"scope,omitempty"`
+}
+
+var errNoKey = errors.New("no key configured for token")
+
+// APIKeyVerifier resolves an API key to the principal it belongs to.
+type APIKeyVerifier func(ctx context.Context, key string) (*Principal, error)
+
+// Authenticator validates JWT bearer tokens and, if a verifier is set, API
+// keys.
+type Authenticator struct {
+	hmacKey []byte
+	jwks    *keySet
+	parser  *jwt.Parser
+	apiKeys APIKeyVerifier
+}
+
+// NewAuthenticator builds an Authenticator from cfg and loads the JWKS, if
+// one is configured.
+func NewAuthenticator(ctx context.Context, cfg config.AuthConfig) (*Authenticator, error) {
+	a := &Authenticator{}
+
+	var methods []string
+	if secret := cfg.HMACSecret.Value(); secret != "" {
+		a.hmacKey = []byte(secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKS != "" {
+		a.jwks = newKeySet(cfg.JWKS)
+		if err := a.jwks.load(ctx); err != nil {
+			return nil, err
+		}
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("no JWT verification key configured")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	a.parser = jwt.NewParser(opts...)
+	return a, nil
+}
+
+// SetAPIKeyVerifier enables authentication with the x-api-key header.
+func (a *Authenticator) SetAPIKeyVerifier(v APIKeyVerifier) {
+	a.apiKeys = v
+}
+
+// RefreshJWKS reloads the JWKS every interval until ctx is cancelled, so
+// that rotated keys are picked up.
+func (a *Authenticator) RefreshJWKS(ctx context.Context, interval time.Duration) {
+	if a.jwks == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.jwks.load(ctx); err != nil {
+				slog.Error("Failed to refresh JWKS", "error", err)
+			}
+		}
+	}
+}
+
+// Authenticate validates a raw JWT and returns the principal it describes.
+func (a *Authenticator) Authenticate(token string) (*Principal, error) {
+	claims := &Claims{}
+	_, err := a.parser.ParseWithClaims(token, claims, a.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+
+	return &Principal{
+		Subject: claims.Subject,
+		Roles:   claims.Roles,
+		Scopes:  strings.Fields(claims.Scope),
+	}, nil
+}
+
+func (a *Authenticator) keyFunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		if a.hmacKey == nil {
+			return nil, errNoKey
+		}
+		return a.hmacKey, nil
+	case jwt.SigningMethodRS256.Alg():
+		if a.jwks == nil {
+			return nil, errNoKey
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := a.jwks.lookupOrReload(context.Background(), kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+}
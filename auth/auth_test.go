@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"Booking/config"
+)
+
+const testSecret = "test-secret"
+
+var testRSAKey = func() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// writeJWKS writes a JWKS holding the public half of key under each kid.
+func writeJWKS(t *testing.T, path string, key *rsa.PrivateKey, kids ...string) {
+	t.Helper()
+	var set jsonWebKeySet
+	for _, kid := range kids {
+		set.Keys = append(set.Keys, jsonWebKey{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestAuthenticator(t *testing.T, cfg config.AuthConfig) *Authenticator {
+	t.Helper()
+	a, err := NewAuthenticator(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewAuthenticator() error = %v", err)
+	}
+	return a
+}
+
+func claims(mutate func(*Claims)) *Claims {
+	c := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "user-1",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Roles: []string{"editor"},
+		Scope: "books:write books:read",
+	}
+	if mutate != nil {
+		mutate(c)
+	}
+	return c
+}
+
+func signHS256(t *testing.T, c *Claims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func signRS256(t *testing.T, kid string, c *Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(testRSAKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestAuthenticate(t *testing.T) {
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksPath, testRSAKey, "k1")
+	both := newTestAuthenticator(t, config.AuthConfig{HMACSecret: testSecret, JWKS: jwksPath, Leeway: 30 * time.Second})
+	hmacOnly := newTestAuthenticator(t, config.AuthConfig{HMACSecret: testSecret})
+	jwksOnly := newTestAuthenticator(t, config.AuthConfig{JWKS: jwksPath})
+	claimed := newTestAuthenticator(t, config.AuthConfig{HMACSecret: testSecret, Issuer: "https://issuer", Audience: "books"})
+
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims(nil)).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs384, err := jwt.NewWithClaims(jwt.SigningMethodHS384, claims(nil)).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An RS256 verifier must not accept an HS256 token keyed with its
+	// public key.
+	publicKeyAsSecret, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims(nil)).SignedString(testRSAKey.N.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrongSecret, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims(nil)).SignedString([]byte("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		a     *Authenticator
+		token string
+		ok    bool
+	}{
+		{"HS256", both, signHS256(t, claims(nil)), true},
+		{"RS256", both, signRS256(t, "k1", claims(nil)), true},
+		{"RS256 without kid from a single-key set", both, signRS256(t, "", claims(nil)), true},
+		{"HS256 without a secret", jwksOnly, signHS256(t, claims(nil)), false},
+		{"RS256 without a JWKS", hmacOnly, signRS256(t, "k1", claims(nil)), false},
+		{"alg none", both, none, false},
+		{"HS384", both, hs384, false},
+		{"public key as HMAC secret", jwksOnly, publicKeyAsSecret, false},
+		{"wrong secret", both, wrongSecret, false},
+		{"malformed", both, "not.a.token", false},
+		{"no subject", both, signHS256(t, claims(func(c *Claims) { c.Subject = "" })), false},
+		{"no expiry", both, signHS256(t, claims(func(c *Claims) { c.ExpiresAt = nil })), false},
+		{"expired", both, signHS256(t, claims(func(c *Claims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		})), false},
+		{"expired within leeway", both, signHS256(t, claims(func(c *Claims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-10 * time.Second))
+		})), true},
+		{"not yet valid", both, signHS256(t, claims(func(c *Claims) {
+			c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Minute))
+		})), false},
+		{"not yet valid within leeway", both, signHS256(t, claims(func(c *Claims) {
+			c.NotBefore = jwt.NewNumericDate(time.Now().Add(10 * time.Second))
+		})), true},
+		{"issuer and audience", claimed, signHS256(t, claims(func(c *Claims) {
+			c.Issuer = "https://issuer"
+			c.Audience = jwt.ClaimStrings{"books", "other"}
+		})), true},
+		{"wrong issuer", claimed, signHS256(t, claims(func(c *Claims) {
+			c.Issuer = "https://evil"
+			c.Audience = jwt.ClaimStrings{"books"}
+		})), false},
+		{"wrong audience", claimed, signHS256(t, claims(func(c *Claims) {
+			c.Issuer = "https://issuer"
+			c.Audience = jwt.ClaimStrings{"other"}
+		})), false},
+		{"missing issuer and audience", claimed, signHS256(t, claims(nil)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := tt.a.Authenticate(tt.token)
+			if tt.ok != (err == nil) {
+				t.Fatalf("Authenticate() error = %v, want ok = %v", err, tt.ok)
+			}
+			if tt.ok && principal.Subject != "user-1" {
+				t.Errorf("Authenticate() subject = %q, want %q", principal.Subject, "user-1")
+			}
+		})
+	}
+}
+
+func TestAuthenticatePrincipal(t *testing.T) {
+	a := newTestAuthenticator(t, config.AuthConfig{HMACSecret: testSecret})
+	principal, err := a.Authenticate(signHS256(t, claims(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !principal.HasRole("editor") || principal.HasRole("admin") {
+		t.Errorf("roles = %v, want [editor]", principal.Roles)
+	}
+	if !principal.HasScope("books:write") || !principal.HasScope("books:read") {
+		t.Errorf("scopes = %v, want books:write and books:read", principal.Scopes)
+	}
+}
+
+func TestNewAuthenticatorNeedsKey(t *testing.T) {
+	if _, err := NewAuthenticator(context.Background(), config.AuthConfig{}); err == nil {
+		t.Error("NewAuthenticator() without a key succeeded")
+	}
+}
+
+func TestUnknownKeyIDReloadsJWKS(t *testing.T) {
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksPath, testRSAKey, "k1")
+	a := newTestAuthenticator(t, config.AuthConfig{JWKS: jwksPath})
+
+	// The issuer rotates to k2.
+	writeJWKS(t, jwksPath, testRSAKey, "k1", "k2")
+	rotated := signRS256(t, "k2", claims(nil))
+
+	// The JWKS was just loaded, so it is not fetched again yet.
+	if _, err := a.Authenticate(rotated); err == nil || !strings.Contains(err.Error(), `unknown key id "k2"`) {
+		t.Fatalf("Authenticate() right after loading = %v, want an unknown key id error", err)
+	}
+
+	a.jwks.mu.Lock()
+	a.jwks.loadedAt = time.Now().Add(-minJWKSReload)
+	a.jwks.mu.Unlock()
+	a.jwks.reloadMu.Lock()
+	a.jwks.reloadedAt = time.Time{}
+	a.jwks.reloadMu.Unlock()
+	if _, err := a.Authenticate(rotated); err != nil {
+		t.Fatalf("Authenticate() with a rotated key = %v", err)
+	}
+
+	// An id that is still unknown does not cause another fetch within
+	// minJWKSReload.
+	writeJWKS(t, jwksPath, testRSAKey, "k1", "k2", "k3")
+	if _, err := a.Authenticate(signRS256(t, "k3", claims(nil))); err == nil {
+		t.Error("Authenticate() reloaded the JWKS again within minJWKSReload")
+	}
+}
+
+func TestReloadKeepsKeysOnInvalidJWKS(t *testing.T) {
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksPath, testRSAKey, "k1")
+	a := newTestAuthenticator(t, config.AuthConfig{JWKS: jwksPath})
+
+	if err := os.WriteFile(jwksPath, []byte(`{"keys": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.jwks.load(context.Background()); err == nil {
+		t.Fatal("load() of an empty JWKS succeeded")
+	}
+	if _, err := a.Authenticate(signRS256(t, "k1", claims(nil))); err != nil {
+		t.Errorf("Authenticate() after a failed reload = %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Policy decides which RPCs need an authenticated caller.
+type Policy struct {
+	// Public lists the full method names that may be called anonymously.
+	// Every other method requires a valid token.
+	Public map[string]bool
+	// RequiredScope, if set, must be granted to callers of non-public
+	// methods.
+	RequiredScope string
+	// Admin lists the full method names that require AdminRole.
+	Admin map[string]bool
+}
+
+// IsPublic reports whether method may be called anonymously.
+func (p Policy) IsPublic(method string) bool {
+	if p.Public[method] {
+		return true
+	}
+	// Services such as grpc.health.v1.Health can be made public as a whole
+	// by listing their "/package.Service/" prefix.
+	if i := strings.LastIndex(method, "/"); i > 0 {
+		return p.Public[method[:i+1]]
+	}
+	return false
+}
+
+// APIKeyMetadataKey is the gRPC metadata key, and lower-cased HTTP header,
+// carrying an API key.
+const APIKeyMetadataKey = "x-api-key"
+
+func apiKey(ctx context.Context) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+	values := md.Get(APIKeyMetadataKey)
+	if len(values) == 0 || values[0] == "" {
+		return "", false
+	}
+	return values[0], true
+}
+
+func bearerToken(ctx context.Context) (string, bool, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false, nil
+	}
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return "", false, nil
+	}
+
+	scheme, token, found := strings.Cut(values[0], " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", true, status.Error(codes.Unauthenticated, "authorization header must use the Bearer scheme")
+	}
+	return strings.TrimSpace(token), true, nil
+}
+
+// authenticate returns the caller described by the API key or bearer token
+// of ctx. A nil principal and error means the call is anonymous.
+func (a *Authenticator) authenticate(ctx context.Context) (*Principal, error) {
+	if key, ok := apiKey(ctx); ok && a.apiKeys != nil {
+		principal, err := a.apiKeys(ctx, key)
+		if st, ok := status.FromError(err); ok && err != nil {
+			return nil, st.Err()
+		}
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid API key")
+		}
+		return principal, nil
+	}
+
+	token, present, err := bearerToken(ctx)
+	if err != nil || !present {
+		return nil, err
+	}
+	principal, err := a.Authenticate(token)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+	}
+	return principal, nil
+}
+
+// authorize authenticates the caller of method and returns a context that
+// carries the principal, if any.
+func (a *Authenticator) authorize(ctx context.Context, method string, policy Policy) (context.Context, error) {
+	principal, err := a.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	public := policy.IsPublic(method)
+	if principal == nil {
+		if public {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token or API key")
+	}
+
+	if policy.Admin[method] && !principal.HasRole(AdminRole) && !principal.HasScope(AdminRole) {
+		return nil, status.Error(codes.PermissionDenied, "admin role required")
+	}
+	if !public && policy.RequiredScope != "" && !principal.HasScope(policy.RequiredScope) {
+		return nil, status.Errorf(codes.PermissionDenied, "caller lacks the %q scope", policy.RequiredScope)
+	}
+	return ContextWithPrincipal(ctx, principal), nil
+}
+
+// UnaryServerInterceptor rejects unary calls that do not satisfy policy.
+func (a *Authenticator) UnaryServerInterceptor(policy Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.authorize(ctx, info.FullMethod, policy)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+type authServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// StreamServerInterceptor rejects streaming calls that do not satisfy
+// policy.
+func (a *Authenticator) StreamServerInterceptor(policy Policy) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authorize(ss.Context(), info.FullMethod, policy)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// RequireAdminHTTP wraps a gateway handler that is served outside of gRPC,
+// such as /admin/loglevel, so that it requires an admin caller.
+func (a *Authenticator) RequireAdminHTTP(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		md := metadata.MD{}
+		if v := r.Header.Get("Authorization"); v != "" {
+			md.Set("authorization", v)
+		}
+		if v := r.Header.Get(APIKeyMetadataKey); v != "" {
+			md.Set(APIKeyMetadataKey, v)
+		}
+		ctx := metadata.NewIncomingContext(r.Context(), md)
+
+		principal, err := a.authenticate(ctx)
+		switch {
+		case err != nil:
+			http.Error(w, status.Convert(err).Message(), runtime.HTTPStatusFromCode(status.Code(err)))
+			return
+		case principal == nil:
+			http.Error(w, "missing bearer token or API key", http.StatusUnauthorized)
+			return
+		case !principal.HasRole(AdminRole) && !principal.HasScope(AdminRole):
+			http.Error(w, "admin role required", http.StatusForbidden)
+			return
+		}
+		next(w, r.WithContext(ContextWithPrincipal(r.Context(), principal)), params)
+	}
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"Booking/config"
+)
+
+func TestIsPublic(t *testing.T) {
+	policy := Policy{Public: map[string]bool{
+		"/booking.BookingService/ReadBook": true,
+		"/grpc.health.v1.Health/":          true,
+	}}
+	tests := []struct {
+		method string
+		want   bool
+	}{
+		{"/booking.BookingService/ReadBook", true},
+		{"/booking.BookingService/DeleteBook", false},
+		{"/grpc.health.v1.Health/Check", true},
+		{"/grpc.health.v1.Health/Watch", true},
+		{"/grpc.health.v1.HealthX/Check", false},
+		{"/grpc.health.v1/Health/Check", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := policy.IsPublic(tt.method); got != tt.want {
+			t.Errorf("IsPublic(%q) = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  []string
+		want    string
+		present bool
+		wantErr bool
+	}{
+		{"absent", nil, "", false, false},
+		{"bearer", []string{"Bearer abc"}, "abc", true, false},
+		{"scheme is case-insensitive", []string{"bearer abc"}, "abc", true, false},
+		{"surrounding space", []string{"Bearer   abc  "}, "abc", true, false},
+		{"other scheme", []string{"Basic dXNlcjpwYXNz"}, "", true, true},
+		{"no token", []string{"Bearer"}, "", true, true},
+		{"blank token", []string{"Bearer    "}, "", true, true},
+		{"bare token", []string{"abc"}, "", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.header != nil {
+				ctx = metadata.NewIncomingContext(ctx, metadata.MD{"authorization": tt.header})
+			}
+			got, present, err := bearerToken(ctx)
+			if tt.wantErr {
+				if status.Code(err) != codes.Unauthenticated {
+					t.Errorf("bearerToken() error = %v, want Unauthenticated", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want || present != tt.present {
+				t.Errorf("bearerToken() = %q, %v, %v, want %q, %v", got, present, err, tt.want, tt.present)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	a := newTestAuthenticator(t, config.AuthConfig{HMACSecret: testSecret})
+	a.SetAPIKeyVerifier(func(ctx context.Context, key string) (*Principal, error) {
+		switch key {
+		case "admin-key":
+			return &Principal{Subject: "billing", Scopes: []string{AdminRole, "books:write"}, APIKeyID: 1}, nil
+		case "revoked-key":
+			return nil, status.Error(codes.Unauthenticated, "API key revoked")
+		}
+		return nil, errors.New("unknown key")
+	})
+	policy := Policy{
+		Public:        map[string]bool{"/svc/Read": true, "/grpc.health.v1.Health/": true},
+		RequiredScope: "books:write",
+		Admin:         map[string]bool{"/svc/Admin": true},
+	}
+
+	writer := signHS256(t, claims(nil))
+	reader := signHS256(t, claims(func(c *Claims) { c.Scope = "books:read" }))
+	admin := signHS256(t, claims(func(c *Claims) { c.Roles = []string{AdminRole} }))
+
+	tests := []struct {
+		name    string
+		method  string
+		md      metadata.MD
+		want    codes.Code
+		subject string
+	}{
+		{"anonymous public", "/svc/Read", nil, codes.OK, ""},
+		{"anonymous health check", "/grpc.health.v1.Health/Check", nil, codes.OK, ""},
+		{"anonymous write", "/svc/Write", nil, codes.Unauthenticated, ""},
+		{"malformed header on a public method", "/svc/Read", metadata.Pairs("authorization", "Token abc"), codes.Unauthenticated, ""},
+		{"invalid token on a public method", "/svc/Read", metadata.Pairs("authorization", "Bearer abc"), codes.Unauthenticated, ""},
+		{"writer", "/svc/Write", metadata.Pairs("authorization", "Bearer "+writer), codes.OK, "user-1"},
+		{"token on a public method", "/svc/Read", metadata.Pairs("authorization", "Bearer "+reader), codes.OK, "user-1"},
+		{"missing scope", "/svc/Write", metadata.Pairs("authorization", "Bearer "+reader), codes.PermissionDenied, ""},
+		{"non-admin on admin method", "/svc/Admin", metadata.Pairs("authorization", "Bearer "+writer), codes.PermissionDenied, ""},
+		{"admin role on admin method", "/svc/Admin", metadata.Pairs("authorization", "Bearer "+admin), codes.OK, "user-1"},
+		{"admin scope on admin method", "/svc/Admin", metadata.Pairs(APIKeyMetadataKey, "admin-key"), codes.OK, "billing"},
+		{"unknown API key", "/svc/Write", metadata.Pairs(APIKeyMetadataKey, "nope"), codes.Unauthenticated, ""},
+		{"revoked API key", "/svc/Write", metadata.Pairs(APIKeyMetadataKey, "revoked-key"), codes.Unauthenticated, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tt.md)
+			}
+			ctx, err := a.authorize(ctx, tt.method, policy)
+			if got := status.Code(err); got != tt.want {
+				t.Fatalf("authorize() error = %v, want %v", err, tt.want)
+			}
+			if err != nil {
+				return
+			}
+			principal, ok := PrincipalFromContext(ctx)
+			if tt.subject == "" {
+				if ok {
+					t.Errorf("anonymous call carries principal %q", principal.Subject)
+				}
+				return
+			}
+			if !ok || principal.Subject != tt.subject {
+				t.Errorf("principal = %v, want subject %q", principal, tt.subject)
+			}
+		})
+	}
+}
+
+func TestRequireAdminHTTP(t *testing.T) {
+	a := newTestAuthenticator(t, config.AuthConfig{HMACSecret: testSecret})
+	handler := a.RequireAdminHTTP(func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		if _, ok := PrincipalFromContext(r.Context()); !ok {
+			t.Error("admin handler called without a principal")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"malformed", "Basic abc", http.StatusUnauthorized},
+		{"non-admin", "Bearer " + signHS256(t, claims(nil)), http.StatusForbidden},
+		{"admin", "Bearer " + signHS256(t, claims(func(c *Claims) { c.Roles = []string{AdminRole} })), http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/admin/loglevel", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r, nil)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	jwksFetchTimeout = 10 * time.Second
+
+	// minJWKSReload is the least time between two loads of the JWKS caused
+	// by tokens with an unknown key id, so that such tokens cannot make the
+	// service fetch the JWKS on every call.
+	minJWKSReload = time.Minute
+)
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// keySet holds the RSA public keys of a JWKS document, keyed by key id.
+type keySet struct {
+	source string
+
+	mu       sync.RWMutex
+	keys     map[string]*rsa.PublicKey
+	loadedAt time.Time
+
+	// reloadMu serializes reloads for unknown key ids and guards
+	// reloadedAt, the time of the last such reload, failed or not.
+	reloadMu   sync.Mutex
+	reloadedAt time.Time
+}
+
+func newKeySet(source string) *keySet {
+	return &keySet{source: source, keys: make(map[string]*rsa.PublicKey)}
+}
+
+// lookup returns the key with the given id. An empty kid matches the only
+// key of a single-key set.
+func (s *keySet) lookup(kid string) (*rsa.PublicKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// load reads the JWKS document from a file path or an http(s) URL and
+// replaces the current keys.
+func (s *keySet) load(ctx context.Context) error {
+	data, err := readJWKS(ctx, s.source)
+	if err != nil {
+		return err
+	}
+
+	var set jsonWebKeySet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := parseRSAKey(jwk)
+		if err != nil {
+			return fmt.Errorf("parse JWKS key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS contains no RSA signing keys")
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// lookupOrReload returns the key with the given id, reloading the JWKS first
+// if the key is unknown, as it is right after the issuer rotated its keys,
+// and the JWKS was not loaded within minJWKSReload.
+func (s *keySet) lookupOrReload(ctx context.Context, kid string) (*rsa.PublicKey, bool) {
+	if key, ok := s.lookup(kid); ok {
+		return key, true
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	s.mu.RLock()
+	loadedAt := s.loadedAt
+	s.mu.RUnlock()
+	if loadedAt.Before(s.reloadedAt) {
+		loadedAt = s.reloadedAt
+	}
+	if time.Since(loadedAt) >= minJWKSReload {
+		s.reloadedAt = time.Now()
+		if err := s.load(ctx); err != nil {
+			slog.Error("Failed to reload JWKS", "error", err)
+		}
+	}
+	return s.lookup(kid)
+}
+
+func readJWKS(ctx context.Context, source string) ([]byte, error) {
+	if !isURL(source) {
+		return os.ReadFile(source)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, jwksFetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWKS: unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+func isURL(source string) bool {
+	return len(source) > 7 && (source[:7] == "http://" || (len(source) > 8 && source[:8] == "https://"))
+}
+
+func parseRSAKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("decode modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("decode exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("exponent is too large")
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
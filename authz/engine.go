@@ -0,0 +1,173 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"Booking/auth"
+)
+
+// FieldExtractor returns the Book fields a request changes. ok is false for
+// requests that do not change fields.
+type FieldExtractor func(req interface{}) (fields []string, ok bool, err error)
+
+// Engine evaluates the current policy. It is safe for concurrent use.
+type Engine struct {
+	path        string
+	knownFields []string
+	extract     FieldExtractor
+
+	policy atomic.Pointer[Policy]
+
+	mu      sync.Mutex
+	modTime time.Time
+}
+
+// NewEngine loads the policy file at path.
+func NewEngine(path string, knownFields []string, extract FieldExtractor) (*Engine, error) {
+	e := &Engine{path: path, knownFields: knownFields, extract: extract}
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Reload reads the policy file again. The current policy is kept if the
+// file is invalid.
+func (e *Engine) Reload() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	info, err := os.Stat(e.path)
+	if err != nil {
+		return fmt.Errorf("stat policy file: %w", err)
+	}
+	policy, err := LoadPolicy(e.path, e.knownFields)
+	if err != nil {
+		return err
+	}
+	e.policy.Store(policy)
+	e.modTime = info.ModTime()
+	return nil
+}
+
+func (e *Engine) changed() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	info, err := os.Stat(e.path)
+	return err == nil && !info.ModTime().Equal(e.modTime)
+}
+
+// Watch reloads the policy whenever the file changes, checking every
+// interval until ctx is cancelled.
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !e.changed() {
+				continue
+			}
+			if err := e.Reload(); err != nil {
+				slog.Error("Failed to reload authorization policy, keeping the previous one", "error", err)
+				continue
+			}
+			slog.Info("Authorization policy reloaded", "path", e.path)
+		}
+	}
+}
+
+func methodName(fullMethod string) string {
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[i+1:]
+	}
+	return fullMethod
+}
+
+// Authorize checks that principal may call fullMethod with req.
+func (e *Engine) Authorize(principal *auth.Principal, fullMethod string, req interface{}) error {
+	policy := e.policy.Load()
+	method := methodName(fullMethod)
+
+	if !policy.CanCall(principal.Roles, method) {
+		return status.Errorf(codes.PermissionDenied, "roles %v may not call %s", principal.Roles, method)
+	}
+
+	fields, ok, err := e.extract(req)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	denied := policy.DeniedFields(principal.Roles, fields)
+	if len(denied) == 0 {
+		return nil
+	}
+
+	st := status.Newf(codes.PermissionDenied, "roles %v may not change book fields: %s",
+		principal.Roles, strings.Join(denied, ", "))
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(denied))
+	for _, field := range denied {
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       "book." + field,
+			Description: "not permitted for the caller's roles",
+		})
+	}
+	if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// UnaryServerInterceptor enforces the policy for authenticated callers of
+// the methods that are not public. It must run after the auth interceptor.
+func (e *Engine) UnaryServerInterceptor(public auth.Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if public.IsPublic(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		principal, ok := auth.PrincipalFromContext(ctx)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "missing credentials")
+		}
+		if err := e.Authorize(principal, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor enforces the RPC part of the policy for streaming
+// calls. Field rules do not apply to streams.
+func (e *Engine) StreamServerInterceptor(public auth.Policy) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if public.IsPublic(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		principal, ok := auth.PrincipalFromContext(ss.Context())
+		if !ok {
+			return status.Error(codes.Unauthenticated, "missing credentials")
+		}
+		if !e.policy.Load().CanCall(principal.Roles, methodName(info.FullMethod)) {
+			return status.Errorf(codes.PermissionDenied, "roles %v may not call %s", principal.Roles, methodName(info.FullMethod))
+		}
+		return handler(srv, ss)
+	}
+}
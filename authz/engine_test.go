@@ -0,0 +1,168 @@
+package authz
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"Booking/auth"
+)
+
+// updateRequest stands in for an UpdateBook request that changes fields.
+type updateRequest struct {
+	fields []string
+}
+
+func extractFields(req interface{}) ([]string, bool, error) {
+	r, ok := req.(updateRequest)
+	if !ok {
+		return nil, false, nil
+	}
+	if len(r.fields) == 0 {
+		return nil, false, status.Error(codes.InvalidArgument, "empty update")
+	}
+	return r.fields, true, nil
+}
+
+func newTestEngine(t *testing.T, policy string) (*Engine, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	writePolicy(t, path, policy)
+	e, err := NewEngine(path, testFields, extractFields)
+	if err != nil {
+		t.Fatalf("NewEngine() error = %v", err)
+	}
+	return e, path
+}
+
+func TestAuthorize(t *testing.T) {
+	e, _ := newTestEngine(t, testPolicy)
+	manager := &auth.Principal{Subject: "m", Roles: []string{"store_manager"}}
+	customer := &auth.Principal{Subject: "c", Roles: []string{"customer"}}
+
+	tests := []struct {
+		name      string
+		principal *auth.Principal
+		method    string
+		req       interface{}
+		want      codes.Code
+	}{
+		{"allowed RPC", customer, "/booking.BookingService/ReadBook", nil, codes.OK},
+		{"denied RPC", customer, "/booking.BookingService/UpdateBook", updateRequest{[]string{"price"}}, codes.PermissionDenied},
+		{"allowed fields", manager, "/booking.BookingService/UpdateBook", updateRequest{[]string{"price", "quantity"}}, codes.OK},
+		{"denied fields", manager, "/booking.BookingService/UpdateBook", updateRequest{[]string{"title", "price"}}, codes.PermissionDenied},
+		{"extractor error", manager, "/booking.BookingService/UpdateBook", updateRequest{}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := e.Authorize(tt.principal, tt.method, tt.req)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("Authorize() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeFieldViolations(t *testing.T) {
+	e, _ := newTestEngine(t, testPolicy)
+	principal := &auth.Principal{Subject: "m", Roles: []string{"store_manager"}}
+
+	err := e.Authorize(principal, "/booking.BookingService/UpdateBook", updateRequest{[]string{"title", "price", "author"}})
+	st := status.Convert(err)
+	if st.Code() != codes.PermissionDenied {
+		t.Fatalf("Authorize() error = %v, want PermissionDenied", err)
+	}
+
+	var violations []string
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				violations = append(violations, v.GetField())
+			}
+		}
+	}
+	want := []string{"book.author", "book.title"}
+	if !slices.Equal(violations, want) {
+		t.Errorf("field violations = %v, want %v", violations, want)
+	}
+}
+
+func TestReloadKeepsPolicyOnInvalidFile(t *testing.T) {
+	e, path := newTestEngine(t, testPolicy)
+	customer := &auth.Principal{Subject: "c", Roles: []string{"customer"}}
+
+	writePolicy(t, path, "roles:\n  customer:\n    rpcs: [ReadBook, UpdateBook]\n    fields: [isbn]\n")
+	if err := e.Reload(); err == nil {
+		t.Fatal("Reload() of an invalid policy succeeded")
+	}
+	if err := e.Authorize(customer, "/booking.BookingService/UpdateBook", nil); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("Authorize() after a failed reload error = %v, want the previous policy's PermissionDenied", err)
+	}
+
+	writePolicy(t, path, "roles:\n  customer:\n    rpcs: [ReadBook, UpdateBook]\n    fields: [quantity]\n")
+	if err := e.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if err := e.Authorize(customer, "/booking.BookingService/UpdateBook", updateRequest{[]string{"quantity"}}); err != nil {
+		t.Errorf("Authorize() after reload error = %v", err)
+	}
+}
+
+func TestWatchReloadsChangedPolicy(t *testing.T) {
+	e, path := newTestEngine(t, testPolicy)
+	customer := &auth.Principal{Subject: "c", Roles: []string{"customer"}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Watch(ctx, 10*time.Millisecond)
+
+	writePolicy(t, path, "roles:\n  customer:\n    rpcs: [\"*\"]\n")
+	// Make the change visible on file systems with coarse timestamps.
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for e.Authorize(customer, "/booking.BookingService/DeleteBook", nil) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Watch() did not reload the changed policy")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	e, _ := newTestEngine(t, testPolicy)
+	intercept := e.UnaryServerInterceptor(auth.Policy{Public: map[string]bool{"/svc/ListBooks": true}})
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	customer := auth.ContextWithPrincipal(context.Background(), &auth.Principal{Subject: "c", Roles: []string{"customer"}})
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		want   codes.Code
+	}{
+		{"public method", context.Background(), "/svc/ListBooks", codes.OK},
+		{"anonymous", context.Background(), "/svc/ReadBook", codes.Unauthenticated},
+		{"allowed", customer, "/svc/ReadBook", codes.OK},
+		{"denied", customer, "/svc/DeleteBook", codes.PermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := intercept(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("interceptor error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
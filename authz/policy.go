@@ -0,0 +1,109 @@
+// Package authz enforces role-based access to RPCs and to the Book fields
+// they may change. Policies are read from a YAML file and can be reloaded
+// while the server is running.
+package authz
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// wildcard grants every RPC or every field.
+const wildcard = "*"
+
+// RoleRule is the policy file entry for a role.
+type RoleRule struct {
+	// RPCs lists method names, such as "UpdateBook", the role may call.
+	RPCs []string `yaml:"rpcs"`
+	// Fields lists the Book fields the role may change.
+	Fields []string `yaml:"fields"`
+}
+
+type policyFile struct {
+	Roles map[string]RoleRule `yaml:"roles"`
+}
+
+type role struct {
+	rpcs   map[string]bool
+	fields map[string]bool
+}
+
+// Policy is a parsed, validated policy file.
+type Policy struct {
+	roles map[string]role
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// LoadPolicy parses the policy file at path. Field names must be wildcards
+// or members of knownFields.
+func LoadPolicy(path string, knownFields []string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read policy file: %w", err)
+	}
+
+	var file policyFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse policy file %s: %w", path, err)
+	}
+	if len(file.Roles) == 0 {
+		return nil, fmt.Errorf("policy file %s defines no roles", path)
+	}
+
+	known := toSet(knownFields)
+	policy := &Policy{roles: make(map[string]role, len(file.Roles))}
+	for name, rule := range file.Roles {
+		for _, field := range rule.Fields {
+			if field != wildcard && !known[field] {
+				return nil, fmt.Errorf("policy file %s: role %s: unknown field %q", path, name, field)
+			}
+		}
+		policy.roles[name] = role{
+			rpcs:   toSet(rule.RPCs),
+			fields: toSet(rule.Fields),
+		}
+	}
+	return policy, nil
+}
+
+// CanCall reports whether any of roles may call the RPC with the given
+// method name.
+func (p *Policy) CanCall(roles []string, method string) bool {
+	for _, name := range roles {
+		r, ok := p.roles[name]
+		if ok && (r.rpcs[wildcard] || r.rpcs[method]) {
+			return true
+		}
+	}
+	return false
+}
+
+// DeniedFields returns the fields that none of roles may change, sorted.
+func (p *Policy) DeniedFields(roles []string, fields []string) []string {
+	var denied []string
+	for _, field := range fields {
+		allowed := false
+		for _, name := range roles {
+			r, ok := p.roles[name]
+			if ok && (r.fields[wildcard] || r.fields[field]) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			denied = append(denied, field)
+		}
+	}
+	sort.Strings(denied)
+	return denied
+}
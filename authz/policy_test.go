@@ -0,0 +1,117 @@
+package authz
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+var testFields = []string{"title", "author", "price", "quantity", "availability"}
+
+const testPolicy = `
+roles:
+  admin:
+    rpcs: ["*"]
+    fields: ["*"]
+  store_manager:
+    rpcs: [ReadBook, UpdateBook]
+    fields: [price, quantity]
+  content_editor:
+    rpcs: [ReadBook, CreateBook, UpdateBook]
+    fields: [title, author]
+  customer:
+    rpcs: [ReadBook]
+`
+
+func writePolicy(t *testing.T, path, policy string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(policy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func loadTestPolicy(t *testing.T, policy string) (*Policy, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	writePolicy(t, path, policy)
+	return LoadPolicy(path, testFields)
+}
+
+func TestCanCall(t *testing.T) {
+	p, err := loadTestPolicy(t, testPolicy)
+	if err != nil {
+		t.Fatalf("LoadPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		roles  []string
+		method string
+		want   bool
+	}{
+		{[]string{"admin"}, "DeleteBook", true},
+		{[]string{"store_manager"}, "UpdateBook", true},
+		{[]string{"store_manager"}, "CreateBook", false},
+		{[]string{"customer"}, "ReadBook", true},
+		{[]string{"customer"}, "UpdateBook", false},
+		{[]string{"customer", "content_editor"}, "CreateBook", true},
+		{[]string{"unknown"}, "ReadBook", false},
+		{nil, "ReadBook", false},
+	}
+	for _, tt := range tests {
+		if got := p.CanCall(tt.roles, tt.method); got != tt.want {
+			t.Errorf("CanCall(%v, %q) = %v, want %v", tt.roles, tt.method, got, tt.want)
+		}
+	}
+}
+
+func TestDeniedFields(t *testing.T) {
+	p, err := loadTestPolicy(t, testPolicy)
+	if err != nil {
+		t.Fatalf("LoadPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		roles  []string
+		fields []string
+		want   []string
+	}{
+		{[]string{"admin"}, testFields, nil},
+		{[]string{"store_manager"}, []string{"price", "quantity"}, nil},
+		{[]string{"store_manager"}, []string{"title", "price", "availability"}, []string{"availability", "title"}},
+		{[]string{"content_editor"}, []string{"price"}, []string{"price"}},
+		{[]string{"store_manager", "content_editor"}, []string{"title", "price"}, nil},
+		{[]string{"customer"}, []string{"quantity"}, []string{"quantity"}},
+		{[]string{"store_manager"}, nil, nil},
+	}
+	for _, tt := range tests {
+		if got := p.DeniedFields(tt.roles, tt.fields); !slices.Equal(got, tt.want) {
+			t.Errorf("DeniedFields(%v, %v) = %v, want %v", tt.roles, tt.fields, got, tt.want)
+		}
+	}
+}
+
+func TestLoadPolicyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		want   string
+	}{
+		{"unknown field", "roles:\n  editor:\n    rpcs: [UpdateBook]\n    fields: [title, isbn]\n", `unknown field "isbn"`},
+		{"no roles", "roles: {}\n", "defines no roles"},
+		{"invalid YAML", "roles: [\n", "parse policy file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTestPolicy(t, tt.policy)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadPolicy() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadPolicy(filepath.Join(t.TempDir(), "missing.yaml"), testFields); err == nil {
+		t.Error("LoadPolicy() of a missing file succeeded")
+	}
+}
@@ -19,10 +19,47 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	BookingService_CreateBook_FullMethodName = "/booking.BookingService/CreateBook"
-	BookingService_ReadBook_FullMethodName   = "/booking.BookingService/ReadBook"
-	BookingService_UpdateBook_FullMethodName = "/booking.BookingService/UpdateBook"
-	BookingService_DeleteBook_FullMethodName = "/booking.BookingService/DeleteBook"
+	BookingService_CreateBook_FullMethodName              = "/booking.BookingService/CreateBook"
+	BookingService_ReadBook_FullMethodName                = "/booking.BookingService/ReadBook"
+	BookingService_ListBooks_FullMethodName               = "/booking.BookingService/ListBooks"
+	BookingService_GetRelatedBooks_FullMethodName         = "/booking.BookingService/GetRelatedBooks"
+	BookingService_UpdateBook_FullMethodName              = "/booking.BookingService/UpdateBook"
+	BookingService_DeleteBook_FullMethodName              = "/booking.BookingService/DeleteBook"
+	BookingService_ListBookRevisions_FullMethodName       = "/booking.BookingService/ListBookRevisions"
+	BookingService_RevertBook_FullMethodName              = "/booking.BookingService/RevertBook"
+	BookingService_UploadCover_FullMethodName             = "/booking.BookingService/UploadCover"
+	BookingService_CreateReview_FullMethodName            = "/booking.BookingService/CreateReview"
+	BookingService_ListReviews_FullMethodName             = "/booking.BookingService/ListReviews"
+	BookingService_ListPendingReviews_FullMethodName      = "/booking.BookingService/ListPendingReviews"
+	BookingService_ModerateReview_FullMethodName          = "/booking.BookingService/ModerateReview"
+	BookingService_CreateSeries_FullMethodName            = "/booking.BookingService/CreateSeries"
+	BookingService_GetSeries_FullMethodName               = "/booking.BookingService/GetSeries"
+	BookingService_SetBookSeries_FullMethodName           = "/booking.BookingService/SetBookSeries"
+	BookingService_GetSeriesNeighbors_FullMethodName      = "/booking.BookingService/GetSeriesNeighbors"
+	BookingService_SetBookTranslation_FullMethodName      = "/booking.BookingService/SetBookTranslation"
+	BookingService_ListBookTranslations_FullMethodName    = "/booking.BookingService/ListBookTranslations"
+	BookingService_DeleteBookTranslation_FullMethodName   = "/booking.BookingService/DeleteBookTranslation"
+	BookingService_CreatePreOrder_FullMethodName          = "/booking.BookingService/CreatePreOrder"
+	BookingService_ListPreOrders_FullMethodName           = "/booking.BookingService/ListPreOrders"
+	BookingService_CancelReservation_FullMethodName       = "/booking.BookingService/CancelReservation"
+	BookingService_SubscribeBackInStock_FullMethodName    = "/booking.BookingService/SubscribeBackInStock"
+	BookingService_UnsubscribeBackInStock_FullMethodName  = "/booking.BookingService/UnsubscribeBackInStock"
+	BookingService_CreateGenre_FullMethodName             = "/booking.BookingService/CreateGenre"
+	BookingService_GetGenre_FullMethodName                = "/booking.BookingService/GetGenre"
+	BookingService_ListGenres_FullMethodName              = "/booking.BookingService/ListGenres"
+	BookingService_UpdateGenre_FullMethodName             = "/booking.BookingService/UpdateGenre"
+	BookingService_DeleteGenre_FullMethodName             = "/booking.BookingService/DeleteGenre"
+	BookingService_CreateWebhookEndpoint_FullMethodName   = "/booking.BookingService/CreateWebhookEndpoint"
+	BookingService_GetWebhookEndpoint_FullMethodName      = "/booking.BookingService/GetWebhookEndpoint"
+	BookingService_ListWebhookEndpoints_FullMethodName    = "/booking.BookingService/ListWebhookEndpoints"
+	BookingService_UpdateWebhookEndpoint_FullMethodName   = "/booking.BookingService/UpdateWebhookEndpoint"
+	BookingService_DeleteWebhookEndpoint_FullMethodName   = "/booking.BookingService/DeleteWebhookEndpoint"
+	BookingService_ListWebhookDeliveries_FullMethodName   = "/booking.BookingService/ListWebhookDeliveries"
+	BookingService_GetWebhookDelivery_FullMethodName      = "/booking.BookingService/GetWebhookDelivery"
+	BookingService_ReplayWebhookDeliveries_FullMethodName = "/booking.BookingService/ReplayWebhookDeliveries"
+	BookingService_CreateApiKey_FullMethodName            = "/booking.BookingService/CreateApiKey"
+	BookingService_RevokeApiKey_FullMethodName            = "/booking.BookingService/RevokeApiKey"
+	BookingService_ListApiKeys_FullMethodName             = "/booking.BookingService/ListApiKeys"
 )
 
 // BookingServiceClient is the client API for BookingService service.
@@ -31,8 +68,102 @@ const (
 type BookingServiceClient interface {
 	CreateBook(ctx context.Context, in *CreateBookRequest, opts ...grpc.CallOption) (*Book, error)
 	ReadBook(ctx context.Context, in *ReadBookRequest, opts ...grpc.CallOption) (*Book, error)
+	// ListBooks returns books ordered by their last change, oldest first, so
+	// that sync jobs can page through the changes since updated_since.
+	ListBooks(ctx context.Context, in *ListBooksRequest, opts ...grpc.CallOption) (*ListBooksResponse, error)
+	// GetRelatedBooks suggests books similar to a book, scored by shared
+	// genres, the same author, language, publication year and co-purchases:
+	// the number of customers with reservations, other than cancelled ones, of
+	// both books. Only books that share a genre, the author or a customer are
+	// candidates.
+	GetRelatedBooks(ctx context.Context, in *GetRelatedBooksRequest, opts ...grpc.CallOption) (*GetRelatedBooksResponse, error)
 	UpdateBook(ctx context.Context, in *UpdateBookRequest, opts ...grpc.CallOption) (*Book, error)
 	DeleteBook(ctx context.Context, in *DeleteBookRequest, opts ...grpc.CallOption) (*DeleteBookResponse, error)
+	// ListBookRevisions returns the change history of a book, newest first.
+	ListBookRevisions(ctx context.Context, in *ListBookRevisionsRequest, opts ...grpc.CallOption) (*ListBookRevisionsResponse, error)
+	// RevertBook restores the catalog fields of the book (title, author, year,
+	// language, genres, price and release_date) as they were after a revision.
+	// Stock fields keep their current values, unless the book was deleted and
+	// is recreated. The restore is a new change and gets a revision of its own.
+	RevertBook(ctx context.Context, in *RevertBookRequest, opts ...grpc.CallOption) (*Book, error)
+	// UploadCover stores a cover image and its thumbnails. The first message
+	// names the book, the following ones carry the image in chunks. Over HTTP,
+	// covers are uploaded as multipart/form-data to POST /books/{id}/cover.
+	UploadCover(ctx context.Context, opts ...grpc.CallOption) (BookingService_UploadCoverClient, error)
+	// CreateReview adds the caller's review of a book. Reviews start out
+	// pending and count towards the book's rating once approved. Each user may
+	// review a book once.
+	CreateReview(ctx context.Context, in *CreateReviewRequest, opts ...grpc.CallOption) (*Review, error)
+	// ListReviews returns the approved reviews of a book, newest first.
+	ListReviews(ctx context.Context, in *ListReviewsRequest, opts ...grpc.CallOption) (*ListReviewsResponse, error)
+	// ListPendingReviews returns the reviews awaiting moderation, oldest first.
+	// It requires the admin role or scope.
+	ListPendingReviews(ctx context.Context, in *ListPendingReviewsRequest, opts ...grpc.CallOption) (*ListReviewsResponse, error)
+	// ModerateReview approves or rejects a review. It requires the admin role
+	// or scope.
+	ModerateReview(ctx context.Context, in *ModerateReviewRequest, opts ...grpc.CallOption) (*Review, error)
+	CreateSeries(ctx context.Context, in *CreateSeriesRequest, opts ...grpc.CallOption) (*Series, error)
+	// GetSeries returns a series with its books in reading order.
+	GetSeries(ctx context.Context, in *GetSeriesRequest, opts ...grpc.CallOption) (*GetSeriesResponse, error)
+	// SetBookSeries places a book at a position in a series, or removes it
+	// from its series when series_id is 0.
+	SetBookSeries(ctx context.Context, in *SetBookSeriesRequest, opts ...grpc.CallOption) (*Book, error)
+	// GetSeriesNeighbors returns the books before and after a book in its
+	// series.
+	GetSeriesNeighbors(ctx context.Context, in *GetSeriesNeighborsRequest, opts ...grpc.CallOption) (*GetSeriesNeighborsResponse, error)
+	// SetBookTranslation creates or replaces the translation of a book into
+	// one locale.
+	SetBookTranslation(ctx context.Context, in *SetBookTranslationRequest, opts ...grpc.CallOption) (*BookTranslation, error)
+	ListBookTranslations(ctx context.Context, in *ListBookTranslationsRequest, opts ...grpc.CallOption) (*ListBookTranslationsResponse, error)
+	DeleteBookTranslation(ctx context.Context, in *DeleteBookTranslationRequest, opts ...grpc.CallOption) (*DeleteBookTranslationResponse, error)
+	// CreatePreOrder queues the caller's demand for a book open for
+	// pre-order. Stock is allocated to pre-orders in the order they were made.
+	CreatePreOrder(ctx context.Context, in *CreatePreOrderRequest, opts ...grpc.CallOption) (*Reservation, error)
+	// ListPreOrders returns the pre-orders of a book, oldest first. Callers
+	// other than admins only see their own pre-orders.
+	ListPreOrders(ctx context.Context, in *ListPreOrdersRequest, opts ...grpc.CallOption) (*ListReservationsResponse, error)
+	// CancelReservation cancels a queued reservation. Callers other than its
+	// customer need the admin role.
+	CancelReservation(ctx context.Context, in *CancelReservationRequest, opts ...grpc.CallOption) (*Reservation, error)
+	// SubscribeBackInStock asks for a notification event when the book is
+	// back in stock, that is when its quantity goes from zero to positive.
+	// Subscriptions are dropped once notified.
+	SubscribeBackInStock(ctx context.Context, in *SubscribeBackInStockRequest, opts ...grpc.CallOption) (*BackInStockSubscription, error)
+	UnsubscribeBackInStock(ctx context.Context, in *UnsubscribeBackInStockRequest, opts ...grpc.CallOption) (*UnsubscribeBackInStockResponse, error)
+	// Genre taxonomy. Genres form a tree; books reference them by id.
+	CreateGenre(ctx context.Context, in *CreateGenreRequest, opts ...grpc.CallOption) (*Genre, error)
+	GetGenre(ctx context.Context, in *GetGenreRequest, opts ...grpc.CallOption) (*Genre, error)
+	// ListGenres returns the whole taxonomy, parents before their children.
+	ListGenres(ctx context.Context, in *ListGenresRequest, opts ...grpc.CallOption) (*ListGenresResponse, error)
+	// UpdateGenre replaces a genre, including its aliases. Changing the slug
+	// also sets updated_at of the books of the genre, since they show it.
+	UpdateGenre(ctx context.Context, in *UpdateGenreRequest, opts ...grpc.CallOption) (*Genre, error)
+	// DeleteGenre deletes a genre that has no children and no books.
+	DeleteGenre(ctx context.Context, in *DeleteGenreRequest, opts ...grpc.CallOption) (*DeleteGenreResponse, error)
+	// Webhook administration. These RPCs require the admin role or scope.
+	// Events matching the filter of an endpoint are POSTed to it as JSON,
+	// signed with its secret, and retried with exponential backoff until
+	// they succeed or run out of attempts.
+	CreateWebhookEndpoint(ctx context.Context, in *CreateWebhookEndpointRequest, opts ...grpc.CallOption) (*CreateWebhookEndpointResponse, error)
+	GetWebhookEndpoint(ctx context.Context, in *GetWebhookEndpointRequest, opts ...grpc.CallOption) (*WebhookEndpoint, error)
+	ListWebhookEndpoints(ctx context.Context, in *ListWebhookEndpointsRequest, opts ...grpc.CallOption) (*ListWebhookEndpointsResponse, error)
+	// UpdateWebhookEndpoint replaces an endpoint. Its secret is kept.
+	UpdateWebhookEndpoint(ctx context.Context, in *UpdateWebhookEndpointRequest, opts ...grpc.CallOption) (*WebhookEndpoint, error)
+	// DeleteWebhookEndpoint deletes an endpoint with its deliveries.
+	DeleteWebhookEndpoint(ctx context.Context, in *DeleteWebhookEndpointRequest, opts ...grpc.CallOption) (*DeleteWebhookEndpointResponse, error)
+	// ListWebhookDeliveries returns the deliveries to an endpoint, newest
+	// first.
+	ListWebhookDeliveries(ctx context.Context, in *ListWebhookDeliveriesRequest, opts ...grpc.CallOption) (*ListWebhookDeliveriesResponse, error)
+	// GetWebhookDelivery returns a delivery with its payload and the log of
+	// its attempts.
+	GetWebhookDelivery(ctx context.Context, in *GetWebhookDeliveryRequest, opts ...grpc.CallOption) (*WebhookDelivery, error)
+	// ReplayWebhookDeliveries sends failed deliveries to an endpoint again,
+	// with a fresh set of attempts.
+	ReplayWebhookDeliveries(ctx context.Context, in *ReplayWebhookDeliveriesRequest, opts ...grpc.CallOption) (*ReplayWebhookDeliveriesResponse, error)
+	// API key administration. These RPCs require the admin role or scope.
+	CreateApiKey(ctx context.Context, in *CreateApiKeyRequest, opts ...grpc.CallOption) (*CreateApiKeyResponse, error)
+	RevokeApiKey(ctx context.Context, in *RevokeApiKeyRequest, opts ...grpc.CallOption) (*ApiKey, error)
+	ListApiKeys(ctx context.Context, in *ListApiKeysRequest, opts ...grpc.CallOption) (*ListApiKeysResponse, error)
 }
 
 type bookingServiceClient struct {
@@ -61,132 +192,1369 @@ func (c *bookingServiceClient) ReadBook(ctx context.Context, in *ReadBookRequest
 	return out, nil
 }
 
+func (c *bookingServiceClient) ListBooks(ctx context.Context, in *ListBooksRequest, opts ...grpc.CallOption) (*ListBooksResponse, error) {
+	out := new(ListBooksResponse)
+	err := c.cc.Invoke(ctx, BookingService_ListBooks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) GetRelatedBooks(ctx context.Context, in *GetRelatedBooksRequest, opts ...grpc.CallOption) (*GetRelatedBooksResponse, error) {
+	out := new(GetRelatedBooksResponse)
+	err := c.cc.Invoke(ctx, BookingService_GetRelatedBooks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *bookingServiceClient) UpdateBook(ctx context.Context, in *UpdateBookRequest, opts ...grpc.CallOption) (*Book, error) {
 	out := new(Book)
 	err := c.cc.Invoke(ctx, BookingService_UpdateBook_FullMethodName, in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
-	return out, nil
+	return out, nil
+}
+
+func (c *bookingServiceClient) DeleteBook(ctx context.Context, in *DeleteBookRequest, opts ...grpc.CallOption) (*DeleteBookResponse, error) {
+	out := new(DeleteBookResponse)
+	err := c.cc.Invoke(ctx, BookingService_DeleteBook_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) ListBookRevisions(ctx context.Context, in *ListBookRevisionsRequest, opts ...grpc.CallOption) (*ListBookRevisionsResponse, error) {
+	out := new(ListBookRevisionsResponse)
+	err := c.cc.Invoke(ctx, BookingService_ListBookRevisions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) RevertBook(ctx context.Context, in *RevertBookRequest, opts ...grpc.CallOption) (*Book, error) {
+	out := new(Book)
+	err := c.cc.Invoke(ctx, BookingService_RevertBook_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) UploadCover(ctx context.Context, opts ...grpc.CallOption) (BookingService_UploadCoverClient, error) {
+	stream, err := c.cc.NewStream(ctx, &BookingService_ServiceDesc.Streams[0], BookingService_UploadCover_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &bookingServiceUploadCoverClient{stream}
+	return x, nil
+}
+
+type BookingService_UploadCoverClient interface {
+	Send(*UploadCoverRequest) error
+	CloseAndRecv() (*Book, error)
+	grpc.ClientStream
+}
+
+type bookingServiceUploadCoverClient struct {
+	grpc.ClientStream
+}
+
+func (x *bookingServiceUploadCoverClient) Send(m *UploadCoverRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *bookingServiceUploadCoverClient) CloseAndRecv() (*Book, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(Book)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *bookingServiceClient) CreateReview(ctx context.Context, in *CreateReviewRequest, opts ...grpc.CallOption) (*Review, error) {
+	out := new(Review)
+	err := c.cc.Invoke(ctx, BookingService_CreateReview_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) ListReviews(ctx context.Context, in *ListReviewsRequest, opts ...grpc.CallOption) (*ListReviewsResponse, error) {
+	out := new(ListReviewsResponse)
+	err := c.cc.Invoke(ctx, BookingService_ListReviews_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) ListPendingReviews(ctx context.Context, in *ListPendingReviewsRequest, opts ...grpc.CallOption) (*ListReviewsResponse, error) {
+	out := new(ListReviewsResponse)
+	err := c.cc.Invoke(ctx, BookingService_ListPendingReviews_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) ModerateReview(ctx context.Context, in *ModerateReviewRequest, opts ...grpc.CallOption) (*Review, error) {
+	out := new(Review)
+	err := c.cc.Invoke(ctx, BookingService_ModerateReview_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) CreateSeries(ctx context.Context, in *CreateSeriesRequest, opts ...grpc.CallOption) (*Series, error) {
+	out := new(Series)
+	err := c.cc.Invoke(ctx, BookingService_CreateSeries_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) GetSeries(ctx context.Context, in *GetSeriesRequest, opts ...grpc.CallOption) (*GetSeriesResponse, error) {
+	out := new(GetSeriesResponse)
+	err := c.cc.Invoke(ctx, BookingService_GetSeries_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) SetBookSeries(ctx context.Context, in *SetBookSeriesRequest, opts ...grpc.CallOption) (*Book, error) {
+	out := new(Book)
+	err := c.cc.Invoke(ctx, BookingService_SetBookSeries_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) GetSeriesNeighbors(ctx context.Context, in *GetSeriesNeighborsRequest, opts ...grpc.CallOption) (*GetSeriesNeighborsResponse, error) {
+	out := new(GetSeriesNeighborsResponse)
+	err := c.cc.Invoke(ctx, BookingService_GetSeriesNeighbors_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) SetBookTranslation(ctx context.Context, in *SetBookTranslationRequest, opts ...grpc.CallOption) (*BookTranslation, error) {
+	out := new(BookTranslation)
+	err := c.cc.Invoke(ctx, BookingService_SetBookTranslation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) ListBookTranslations(ctx context.Context, in *ListBookTranslationsRequest, opts ...grpc.CallOption) (*ListBookTranslationsResponse, error) {
+	out := new(ListBookTranslationsResponse)
+	err := c.cc.Invoke(ctx, BookingService_ListBookTranslations_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) DeleteBookTranslation(ctx context.Context, in *DeleteBookTranslationRequest, opts ...grpc.CallOption) (*DeleteBookTranslationResponse, error) {
+	out := new(DeleteBookTranslationResponse)
+	err := c.cc.Invoke(ctx, BookingService_DeleteBookTranslation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) CreatePreOrder(ctx context.Context, in *CreatePreOrderRequest, opts ...grpc.CallOption) (*Reservation, error) {
+	out := new(Reservation)
+	err := c.cc.Invoke(ctx, BookingService_CreatePreOrder_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) ListPreOrders(ctx context.Context, in *ListPreOrdersRequest, opts ...grpc.CallOption) (*ListReservationsResponse, error) {
+	out := new(ListReservationsResponse)
+	err := c.cc.Invoke(ctx, BookingService_ListPreOrders_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) CancelReservation(ctx context.Context, in *CancelReservationRequest, opts ...grpc.CallOption) (*Reservation, error) {
+	out := new(Reservation)
+	err := c.cc.Invoke(ctx, BookingService_CancelReservation_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) SubscribeBackInStock(ctx context.Context, in *SubscribeBackInStockRequest, opts ...grpc.CallOption) (*BackInStockSubscription, error) {
+	out := new(BackInStockSubscription)
+	err := c.cc.Invoke(ctx, BookingService_SubscribeBackInStock_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) UnsubscribeBackInStock(ctx context.Context, in *UnsubscribeBackInStockRequest, opts ...grpc.CallOption) (*UnsubscribeBackInStockResponse, error) {
+	out := new(UnsubscribeBackInStockResponse)
+	err := c.cc.Invoke(ctx, BookingService_UnsubscribeBackInStock_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) CreateGenre(ctx context.Context, in *CreateGenreRequest, opts ...grpc.CallOption) (*Genre, error) {
+	out := new(Genre)
+	err := c.cc.Invoke(ctx, BookingService_CreateGenre_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) GetGenre(ctx context.Context, in *GetGenreRequest, opts ...grpc.CallOption) (*Genre, error) {
+	out := new(Genre)
+	err := c.cc.Invoke(ctx, BookingService_GetGenre_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) ListGenres(ctx context.Context, in *ListGenresRequest, opts ...grpc.CallOption) (*ListGenresResponse, error) {
+	out := new(ListGenresResponse)
+	err := c.cc.Invoke(ctx, BookingService_ListGenres_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) UpdateGenre(ctx context.Context, in *UpdateGenreRequest, opts ...grpc.CallOption) (*Genre, error) {
+	out := new(Genre)
+	err := c.cc.Invoke(ctx, BookingService_UpdateGenre_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) DeleteGenre(ctx context.Context, in *DeleteGenreRequest, opts ...grpc.CallOption) (*DeleteGenreResponse, error) {
+	out := new(DeleteGenreResponse)
+	err := c.cc.Invoke(ctx, BookingService_DeleteGenre_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) CreateWebhookEndpoint(ctx context.Context, in *CreateWebhookEndpointRequest, opts ...grpc.CallOption) (*CreateWebhookEndpointResponse, error) {
+	out := new(CreateWebhookEndpointResponse)
+	err := c.cc.Invoke(ctx, BookingService_CreateWebhookEndpoint_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) GetWebhookEndpoint(ctx context.Context, in *GetWebhookEndpointRequest, opts ...grpc.CallOption) (*WebhookEndpoint, error) {
+	out := new(WebhookEndpoint)
+	err := c.cc.Invoke(ctx, BookingService_GetWebhookEndpoint_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) ListWebhookEndpoints(ctx context.Context, in *ListWebhookEndpointsRequest, opts ...grpc.CallOption) (*ListWebhookEndpointsResponse, error) {
+	out := new(ListWebhookEndpointsResponse)
+	err := c.cc.Invoke(ctx, BookingService_ListWebhookEndpoints_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) UpdateWebhookEndpoint(ctx context.Context, in *UpdateWebhookEndpointRequest, opts ...grpc.CallOption) (*WebhookEndpoint, error) {
+	out := new(WebhookEndpoint)
+	err := c.cc.Invoke(ctx, BookingService_UpdateWebhookEndpoint_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) DeleteWebhookEndpoint(ctx context.Context, in *DeleteWebhookEndpointRequest, opts ...grpc.CallOption) (*DeleteWebhookEndpointResponse, error) {
+	out := new(DeleteWebhookEndpointResponse)
+	err := c.cc.Invoke(ctx, BookingService_DeleteWebhookEndpoint_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) ListWebhookDeliveries(ctx context.Context, in *ListWebhookDeliveriesRequest, opts ...grpc.CallOption) (*ListWebhookDeliveriesResponse, error) {
+	out := new(ListWebhookDeliveriesResponse)
+	err := c.cc.Invoke(ctx, BookingService_ListWebhookDeliveries_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) GetWebhookDelivery(ctx context.Context, in *GetWebhookDeliveryRequest, opts ...grpc.CallOption) (*WebhookDelivery, error) {
+	out := new(WebhookDelivery)
+	err := c.cc.Invoke(ctx, BookingService_GetWebhookDelivery_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) ReplayWebhookDeliveries(ctx context.Context, in *ReplayWebhookDeliveriesRequest, opts ...grpc.CallOption) (*ReplayWebhookDeliveriesResponse, error) {
+	out := new(ReplayWebhookDeliveriesResponse)
+	err := c.cc.Invoke(ctx, BookingService_ReplayWebhookDeliveries_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) CreateApiKey(ctx context.Context, in *CreateApiKeyRequest, opts ...grpc.CallOption) (*CreateApiKeyResponse, error) {
+	out := new(CreateApiKeyResponse)
+	err := c.cc.Invoke(ctx, BookingService_CreateApiKey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) RevokeApiKey(ctx context.Context, in *RevokeApiKeyRequest, opts ...grpc.CallOption) (*ApiKey, error) {
+	out := new(ApiKey)
+	err := c.cc.Invoke(ctx, BookingService_RevokeApiKey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) ListApiKeys(ctx context.Context, in *ListApiKeysRequest, opts ...grpc.CallOption) (*ListApiKeysResponse, error) {
+	out := new(ListApiKeysResponse)
+	err := c.cc.Invoke(ctx, BookingService_ListApiKeys_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BookingServiceServer is the server API for BookingService service.
+// All implementations must embed UnimplementedBookingServiceServer
+// for forward compatibility
+type BookingServiceServer interface {
+	CreateBook(context.Context, *CreateBookRequest) (*Book, error)
+	ReadBook(context.Context, *ReadBookRequest) (*Book, error)
+	// ListBooks returns books ordered by their last change, oldest first, so
+	// that sync jobs can page through the changes since updated_since.
+	ListBooks(context.Context, *ListBooksRequest) (*ListBooksResponse, error)
+	// GetRelatedBooks suggests books similar to a book, scored by shared
+	// genres, the same author, language, publication year and co-purchases:
+	// the number of customers with reservations, other than cancelled ones, of
+	// both books. Only books that share a genre, the author or a customer are
+	// candidates.
+	GetRelatedBooks(context.Context, *GetRelatedBooksRequest) (*GetRelatedBooksResponse, error)
+	UpdateBook(context.Context, *UpdateBookRequest) (*Book, error)
+	DeleteBook(context.Context, *DeleteBookRequest) (*DeleteBookResponse, error)
+	// ListBookRevisions returns the change history of a book, newest first.
+	ListBookRevisions(context.Context, *ListBookRevisionsRequest) (*ListBookRevisionsResponse, error)
+	// RevertBook restores the catalog fields of the book (title, author, year,
+	// language, genres, price and release_date) as they were after a revision.
+	// Stock fields keep their current values, unless the book was deleted and
+	// is recreated. The restore is a new change and gets a revision of its own.
+	RevertBook(context.Context, *RevertBookRequest) (*Book, error)
+	// UploadCover stores a cover image and its thumbnails. The first message
+	// names the book, the following ones carry the image in chunks. Over HTTP,
+	// covers are uploaded as multipart/form-data to POST /books/{id}/cover.
+	UploadCover(BookingService_UploadCoverServer) error
+	// CreateReview adds the caller's review of a book. Reviews start out
+	// pending and count towards the book's rating once approved. Each user may
+	// review a book once.
+	CreateReview(context.Context, *CreateReviewRequest) (*Review, error)
+	// ListReviews returns the approved reviews of a book, newest first.
+	ListReviews(context.Context, *ListReviewsRequest) (*ListReviewsResponse, error)
+	// ListPendingReviews returns the reviews awaiting moderation, oldest first.
+	// It requires the admin role or scope.
+	ListPendingReviews(context.Context, *ListPendingReviewsRequest) (*ListReviewsResponse, error)
+	// ModerateReview approves or rejects a review. It requires the admin role
+	// or scope.
+	ModerateReview(context.Context, *ModerateReviewRequest) (*Review, error)
+	CreateSeries(context.Context, *CreateSeriesRequest) (*Series, error)
+	// GetSeries returns a series with its books in reading order.
+	GetSeries(context.Context, *GetSeriesRequest) (*GetSeriesResponse, error)
+	// SetBookSeries places a book at a position in a series, or removes it
+	// from its series when series_id is 0.
+	SetBookSeries(context.Context, *SetBookSeriesRequest) (*Book, error)
+	// GetSeriesNeighbors returns the books before and after a book in its
+	// series.
+	GetSeriesNeighbors(context.Context, *GetSeriesNeighborsRequest) (*GetSeriesNeighborsResponse, error)
+	// SetBookTranslation creates or replaces the translation of a book into
+	// one locale.
+	SetBookTranslation(context.Context, *SetBookTranslationRequest) (*BookTranslation, error)
+	ListBookTranslations(context.Context, *ListBookTranslationsRequest) (*ListBookTranslationsResponse, error)
+	DeleteBookTranslation(context.Context, *DeleteBookTranslationRequest) (*DeleteBookTranslationResponse, error)
+	// CreatePreOrder queues the caller's demand for a book open for
+	// pre-order. Stock is allocated to pre-orders in the order they were made.
+	CreatePreOrder(context.Context, *CreatePreOrderRequest) (*Reservation, error)
+	// ListPreOrders returns the pre-orders of a book, oldest first. Callers
+	// other than admins only see their own pre-orders.
+	ListPreOrders(context.Context, *ListPreOrdersRequest) (*ListReservationsResponse, error)
+	// CancelReservation cancels a queued reservation. Callers other than its
+	// customer need the admin role.
+	CancelReservation(context.Context, *CancelReservationRequest) (*Reservation, error)
+	// SubscribeBackInStock asks for a notification event when the book is
+	// back in stock, that is when its quantity goes from zero to positive.
+	// Subscriptions are dropped once notified.
+	SubscribeBackInStock(context.Context, *SubscribeBackInStockRequest) (*BackInStockSubscription, error)
+	UnsubscribeBackInStock(context.Context, *UnsubscribeBackInStockRequest) (*UnsubscribeBackInStockResponse, error)
+	// Genre taxonomy. Genres form a tree; books reference them by id.
+	CreateGenre(context.Context, *CreateGenreRequest) (*Genre, error)
+	GetGenre(context.Context, *GetGenreRequest) (*Genre, error)
+	// ListGenres returns the whole taxonomy, parents before their children.
+	ListGenres(context.Context, *ListGenresRequest) (*ListGenresResponse, error)
+	// UpdateGenre replaces a genre, including its aliases. Changing the slug
+	// also sets updated_at of the books of the genre, since they show it.
+	UpdateGenre(context.Context, *UpdateGenreRequest) (*Genre, error)
+	// DeleteGenre deletes a genre that has no children and no books.
+	DeleteGenre(context.Context, *DeleteGenreRequest) (*DeleteGenreResponse, error)
+	// Webhook administration. These RPCs require the admin role or scope.
+	// Events matching the filter of an endpoint are POSTed to it as JSON,
+	// signed with its secret, and retried with exponential backoff until
+	// they succeed or run out of attempts.
+	CreateWebhookEndpoint(context.Context, *CreateWebhookEndpointRequest) (*CreateWebhookEndpointResponse, error)
+	GetWebhookEndpoint(context.Context, *GetWebhookEndpointRequest) (*WebhookEndpoint, error)
+	ListWebhookEndpoints(context.Context, *ListWebhookEndpointsRequest) (*ListWebhookEndpointsResponse, error)
+	// UpdateWebhookEndpoint replaces an endpoint. Its secret is kept.
+	UpdateWebhookEndpoint(context.Context, *UpdateWebhookEndpointRequest) (*WebhookEndpoint, error)
+	// DeleteWebhookEndpoint deletes an endpoint with its deliveries.
+	DeleteWebhookEndpoint(context.Context, *DeleteWebhookEndpointRequest) (*DeleteWebhookEndpointResponse, error)
+	// ListWebhookDeliveries returns the deliveries to an endpoint, newest
+	// first.
+	ListWebhookDeliveries(context.Context, *ListWebhookDeliveriesRequest) (*ListWebhookDeliveriesResponse, error)
+	// GetWebhookDelivery returns a delivery with its payload and the log of
+	// its attempts.
+	GetWebhookDelivery(context.Context, *GetWebhookDeliveryRequest) (*WebhookDelivery, error)
+	// ReplayWebhookDeliveries sends failed deliveries to an endpoint again,
+	// with a fresh set of attempts.
+	ReplayWebhookDeliveries(context.Context, *ReplayWebhookDeliveriesRequest) (*ReplayWebhookDeliveriesResponse, error)
+	// API key administration. These RPCs require the admin role or scope.
+	CreateApiKey(context.Context, *CreateApiKeyRequest) (*CreateApiKeyResponse, error)
+	RevokeApiKey(context.Context, *RevokeApiKeyRequest) (*ApiKey, error)
+	ListApiKeys(context.Context, *ListApiKeysRequest) (*ListApiKeysResponse, error)
+	mustEmbedUnimplementedBookingServiceServer()
+}
+
+// UnimplementedBookingServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedBookingServiceServer struct {
+}
+
+func (UnimplementedBookingServiceServer) CreateBook(context.Context, *CreateBookRequest) (*Book, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBook not implemented")
+}
+func (UnimplementedBookingServiceServer) ReadBook(context.Context, *ReadBookRequest) (*Book, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReadBook not implemented")
+}
+func (UnimplementedBookingServiceServer) ListBooks(context.Context, *ListBooksRequest) (*ListBooksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBooks not implemented")
+}
+func (UnimplementedBookingServiceServer) GetRelatedBooks(context.Context, *GetRelatedBooksRequest) (*GetRelatedBooksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRelatedBooks not implemented")
+}
+func (UnimplementedBookingServiceServer) UpdateBook(context.Context, *UpdateBookRequest) (*Book, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateBook not implemented")
+}
+func (UnimplementedBookingServiceServer) DeleteBook(context.Context, *DeleteBookRequest) (*DeleteBookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteBook not implemented")
+}
+func (UnimplementedBookingServiceServer) ListBookRevisions(context.Context, *ListBookRevisionsRequest) (*ListBookRevisionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBookRevisions not implemented")
+}
+func (UnimplementedBookingServiceServer) RevertBook(context.Context, *RevertBookRequest) (*Book, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevertBook not implemented")
+}
+func (UnimplementedBookingServiceServer) UploadCover(BookingService_UploadCoverServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadCover not implemented")
+}
+func (UnimplementedBookingServiceServer) CreateReview(context.Context, *CreateReviewRequest) (*Review, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateReview not implemented")
+}
+func (UnimplementedBookingServiceServer) ListReviews(context.Context, *ListReviewsRequest) (*ListReviewsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListReviews not implemented")
+}
+func (UnimplementedBookingServiceServer) ListPendingReviews(context.Context, *ListPendingReviewsRequest) (*ListReviewsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPendingReviews not implemented")
+}
+func (UnimplementedBookingServiceServer) ModerateReview(context.Context, *ModerateReviewRequest) (*Review, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ModerateReview not implemented")
+}
+func (UnimplementedBookingServiceServer) CreateSeries(context.Context, *CreateSeriesRequest) (*Series, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateSeries not implemented")
+}
+func (UnimplementedBookingServiceServer) GetSeries(context.Context, *GetSeriesRequest) (*GetSeriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSeries not implemented")
+}
+func (UnimplementedBookingServiceServer) SetBookSeries(context.Context, *SetBookSeriesRequest) (*Book, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetBookSeries not implemented")
+}
+func (UnimplementedBookingServiceServer) GetSeriesNeighbors(context.Context, *GetSeriesNeighborsRequest) (*GetSeriesNeighborsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSeriesNeighbors not implemented")
+}
+func (UnimplementedBookingServiceServer) SetBookTranslation(context.Context, *SetBookTranslationRequest) (*BookTranslation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetBookTranslation not implemented")
+}
+func (UnimplementedBookingServiceServer) ListBookTranslations(context.Context, *ListBookTranslationsRequest) (*ListBookTranslationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBookTranslations not implemented")
+}
+func (UnimplementedBookingServiceServer) DeleteBookTranslation(context.Context, *DeleteBookTranslationRequest) (*DeleteBookTranslationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteBookTranslation not implemented")
+}
+func (UnimplementedBookingServiceServer) CreatePreOrder(context.Context, *CreatePreOrderRequest) (*Reservation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePreOrder not implemented")
+}
+func (UnimplementedBookingServiceServer) ListPreOrders(context.Context, *ListPreOrdersRequest) (*ListReservationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPreOrders not implemented")
+}
+func (UnimplementedBookingServiceServer) CancelReservation(context.Context, *CancelReservationRequest) (*Reservation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelReservation not implemented")
+}
+func (UnimplementedBookingServiceServer) SubscribeBackInStock(context.Context, *SubscribeBackInStockRequest) (*BackInStockSubscription, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubscribeBackInStock not implemented")
+}
+func (UnimplementedBookingServiceServer) UnsubscribeBackInStock(context.Context, *UnsubscribeBackInStockRequest) (*UnsubscribeBackInStockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnsubscribeBackInStock not implemented")
+}
+func (UnimplementedBookingServiceServer) CreateGenre(context.Context, *CreateGenreRequest) (*Genre, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateGenre not implemented")
+}
+func (UnimplementedBookingServiceServer) GetGenre(context.Context, *GetGenreRequest) (*Genre, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGenre not implemented")
+}
+func (UnimplementedBookingServiceServer) ListGenres(context.Context, *ListGenresRequest) (*ListGenresResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGenres not implemented")
+}
+func (UnimplementedBookingServiceServer) UpdateGenre(context.Context, *UpdateGenreRequest) (*Genre, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateGenre not implemented")
+}
+func (UnimplementedBookingServiceServer) DeleteGenre(context.Context, *DeleteGenreRequest) (*DeleteGenreResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteGenre not implemented")
+}
+func (UnimplementedBookingServiceServer) CreateWebhookEndpoint(context.Context, *CreateWebhookEndpointRequest) (*CreateWebhookEndpointResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateWebhookEndpoint not implemented")
+}
+func (UnimplementedBookingServiceServer) GetWebhookEndpoint(context.Context, *GetWebhookEndpointRequest) (*WebhookEndpoint, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWebhookEndpoint not implemented")
+}
+func (UnimplementedBookingServiceServer) ListWebhookEndpoints(context.Context, *ListWebhookEndpointsRequest) (*ListWebhookEndpointsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWebhookEndpoints not implemented")
+}
+func (UnimplementedBookingServiceServer) UpdateWebhookEndpoint(context.Context, *UpdateWebhookEndpointRequest) (*WebhookEndpoint, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateWebhookEndpoint not implemented")
+}
+func (UnimplementedBookingServiceServer) DeleteWebhookEndpoint(context.Context, *DeleteWebhookEndpointRequest) (*DeleteWebhookEndpointResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWebhookEndpoint not implemented")
+}
+func (UnimplementedBookingServiceServer) ListWebhookDeliveries(context.Context, *ListWebhookDeliveriesRequest) (*ListWebhookDeliveriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWebhookDeliveries not implemented")
+}
+func (UnimplementedBookingServiceServer) GetWebhookDelivery(context.Context, *GetWebhookDeliveryRequest) (*WebhookDelivery, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetWebhookDelivery not implemented")
+}
+func (UnimplementedBookingServiceServer) ReplayWebhookDeliveries(context.Context, *ReplayWebhookDeliveriesRequest) (*ReplayWebhookDeliveriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplayWebhookDeliveries not implemented")
+}
+func (UnimplementedBookingServiceServer) CreateApiKey(context.Context, *CreateApiKeyRequest) (*CreateApiKeyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateApiKey not implemented")
+}
+func (UnimplementedBookingServiceServer) RevokeApiKey(context.Context, *RevokeApiKeyRequest) (*ApiKey, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeApiKey not implemented")
+}
+func (UnimplementedBookingServiceServer) ListApiKeys(context.Context, *ListApiKeysRequest) (*ListApiKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListApiKeys not implemented")
+}
+func (UnimplementedBookingServiceServer) mustEmbedUnimplementedBookingServiceServer() {}
+
+// UnsafeBookingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BookingServiceServer will
+// result in compilation errors.
+type UnsafeBookingServiceServer interface {
+	mustEmbedUnimplementedBookingServiceServer()
+}
+
+func RegisterBookingServiceServer(s grpc.ServiceRegistrar, srv BookingServiceServer) {
+	s.RegisterService(&BookingService_ServiceDesc, srv)
+}
+
+func _BookingService_CreateBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateBookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).CreateBook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_CreateBook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).CreateBook(ctx, req.(*CreateBookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_ReadBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadBookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).ReadBook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_ReadBook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).ReadBook(ctx, req.(*ReadBookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_ListBooks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBooksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).ListBooks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_ListBooks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).ListBooks(ctx, req.(*ListBooksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_GetRelatedBooks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRelatedBooksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).GetRelatedBooks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_GetRelatedBooks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).GetRelatedBooks(ctx, req.(*GetRelatedBooksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_UpdateBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateBookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).UpdateBook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_UpdateBook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).UpdateBook(ctx, req.(*UpdateBookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_DeleteBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteBookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).DeleteBook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_DeleteBook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).DeleteBook(ctx, req.(*DeleteBookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_ListBookRevisions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBookRevisionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).ListBookRevisions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_ListBookRevisions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).ListBookRevisions(ctx, req.(*ListBookRevisionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_RevertBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevertBookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).RevertBook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_RevertBook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).RevertBook(ctx, req.(*RevertBookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_UploadCover_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(BookingServiceServer).UploadCover(&bookingServiceUploadCoverServer{stream})
+}
+
+type BookingService_UploadCoverServer interface {
+	SendAndClose(*Book) error
+	Recv() (*UploadCoverRequest, error)
+	grpc.ServerStream
+}
+
+type bookingServiceUploadCoverServer struct {
+	grpc.ServerStream
+}
+
+func (x *bookingServiceUploadCoverServer) SendAndClose(m *Book) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *bookingServiceUploadCoverServer) Recv() (*UploadCoverRequest, error) {
+	m := new(UploadCoverRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _BookingService_CreateReview_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateReviewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).CreateReview(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_CreateReview_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).CreateReview(ctx, req.(*CreateReviewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_ListReviews_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListReviewsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).ListReviews(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_ListReviews_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).ListReviews(ctx, req.(*ListReviewsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_ListPendingReviews_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPendingReviewsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).ListPendingReviews(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_ListPendingReviews_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).ListPendingReviews(ctx, req.(*ListPendingReviewsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_ModerateReview_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerateReviewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).ModerateReview(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_ModerateReview_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).ModerateReview(ctx, req.(*ModerateReviewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_CreateSeries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateSeriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).CreateSeries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_CreateSeries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).CreateSeries(ctx, req.(*CreateSeriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_GetSeries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSeriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).GetSeries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_GetSeries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).GetSeries(ctx, req.(*GetSeriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_SetBookSeries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetBookSeriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).SetBookSeries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_SetBookSeries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).SetBookSeries(ctx, req.(*SetBookSeriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_GetSeriesNeighbors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSeriesNeighborsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).GetSeriesNeighbors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_GetSeriesNeighbors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).GetSeriesNeighbors(ctx, req.(*GetSeriesNeighborsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_SetBookTranslation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetBookTranslationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).SetBookTranslation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_SetBookTranslation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).SetBookTranslation(ctx, req.(*SetBookTranslationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_ListBookTranslations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBookTranslationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).ListBookTranslations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_ListBookTranslations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).ListBookTranslations(ctx, req.(*ListBookTranslationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_DeleteBookTranslation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteBookTranslationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).DeleteBookTranslation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_DeleteBookTranslation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).DeleteBookTranslation(ctx, req.(*DeleteBookTranslationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_CreatePreOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePreOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).CreatePreOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_CreatePreOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).CreatePreOrder(ctx, req.(*CreatePreOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_ListPreOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPreOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).ListPreOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_ListPreOrders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).ListPreOrders(ctx, req.(*ListPreOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_CancelReservation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelReservationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).CancelReservation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_CancelReservation_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).CancelReservation(ctx, req.(*CancelReservationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_SubscribeBackInStock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubscribeBackInStockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).SubscribeBackInStock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_SubscribeBackInStock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).SubscribeBackInStock(ctx, req.(*SubscribeBackInStockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_UnsubscribeBackInStock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnsubscribeBackInStockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).UnsubscribeBackInStock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_UnsubscribeBackInStock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).UnsubscribeBackInStock(ctx, req.(*UnsubscribeBackInStockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_CreateGenre_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateGenreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).CreateGenre(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_CreateGenre_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).CreateGenre(ctx, req.(*CreateGenreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_GetGenre_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGenreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).GetGenre(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_GetGenre_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).GetGenre(ctx, req.(*GetGenreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *bookingServiceClient) DeleteBook(ctx context.Context, in *DeleteBookRequest, opts ...grpc.CallOption) (*DeleteBookResponse, error) {
-	out := new(DeleteBookResponse)
-	err := c.cc.Invoke(ctx, BookingService_DeleteBook_FullMethodName, in, out, opts...)
-	if err != nil {
+func _BookingService_ListGenres_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGenresRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(BookingServiceServer).ListGenres(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_ListGenres_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).ListGenres(ctx, req.(*ListGenresRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// BookingServiceServer is the server API for BookingService service.
-// All implementations must embed UnimplementedBookingServiceServer
-// for forward compatibility
-type BookingServiceServer interface {
-	CreateBook(context.Context, *CreateBookRequest) (*Book, error)
-	ReadBook(context.Context, *ReadBookRequest) (*Book, error)
-	UpdateBook(context.Context, *UpdateBookRequest) (*Book, error)
-	DeleteBook(context.Context, *DeleteBookRequest) (*DeleteBookResponse, error)
-	mustEmbedUnimplementedBookingServiceServer()
+func _BookingService_UpdateGenre_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateGenreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).UpdateGenre(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_UpdateGenre_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).UpdateGenre(ctx, req.(*UpdateGenreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// UnimplementedBookingServiceServer must be embedded to have forward compatible implementations.
-type UnimplementedBookingServiceServer struct {
+func _BookingService_DeleteGenre_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteGenreRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).DeleteGenre(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_DeleteGenre_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).DeleteGenre(ctx, req.(*DeleteGenreRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (UnimplementedBookingServiceServer) CreateBook(context.Context, *CreateBookRequest) (*Book, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateBook not implemented")
+func _BookingService_CreateWebhookEndpoint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateWebhookEndpointRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).CreateWebhookEndpoint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_CreateWebhookEndpoint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).CreateWebhookEndpoint(ctx, req.(*CreateWebhookEndpointRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedBookingServiceServer) ReadBook(context.Context, *ReadBookRequest) (*Book, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ReadBook not implemented")
+
+func _BookingService_GetWebhookEndpoint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWebhookEndpointRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).GetWebhookEndpoint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_GetWebhookEndpoint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).GetWebhookEndpoint(ctx, req.(*GetWebhookEndpointRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedBookingServiceServer) UpdateBook(context.Context, *UpdateBookRequest) (*Book, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateBook not implemented")
+
+func _BookingService_ListWebhookEndpoints_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWebhookEndpointsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).ListWebhookEndpoints(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_ListWebhookEndpoints_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).ListWebhookEndpoints(ctx, req.(*ListWebhookEndpointsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedBookingServiceServer) DeleteBook(context.Context, *DeleteBookRequest) (*DeleteBookResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteBook not implemented")
+
+func _BookingService_UpdateWebhookEndpoint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateWebhookEndpointRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).UpdateWebhookEndpoint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_UpdateWebhookEndpoint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).UpdateWebhookEndpoint(ctx, req.(*UpdateWebhookEndpointRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedBookingServiceServer) mustEmbedUnimplementedBookingServiceServer() {}
 
-// UnsafeBookingServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to BookingServiceServer will
-// result in compilation errors.
-type UnsafeBookingServiceServer interface {
-	mustEmbedUnimplementedBookingServiceServer()
+func _BookingService_DeleteWebhookEndpoint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteWebhookEndpointRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).DeleteWebhookEndpoint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_DeleteWebhookEndpoint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).DeleteWebhookEndpoint(ctx, req.(*DeleteWebhookEndpointRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterBookingServiceServer(s grpc.ServiceRegistrar, srv BookingServiceServer) {
-	s.RegisterService(&BookingService_ServiceDesc, srv)
+func _BookingService_ListWebhookDeliveries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListWebhookDeliveriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).ListWebhookDeliveries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_ListWebhookDeliveries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).ListWebhookDeliveries(ctx, req.(*ListWebhookDeliveriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _BookingService_CreateBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateBookRequest)
+func _BookingService_GetWebhookDelivery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetWebhookDeliveryRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(BookingServiceServer).CreateBook(ctx, in)
+		return srv.(BookingServiceServer).GetWebhookDelivery(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: BookingService_CreateBook_FullMethodName,
+		FullMethod: BookingService_GetWebhookDelivery_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(BookingServiceServer).CreateBook(ctx, req.(*CreateBookRequest))
+		return srv.(BookingServiceServer).GetWebhookDelivery(ctx, req.(*GetWebhookDeliveryRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _BookingService_ReadBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ReadBookRequest)
+func _BookingService_ReplayWebhookDeliveries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReplayWebhookDeliveriesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(BookingServiceServer).ReadBook(ctx, in)
+		return srv.(BookingServiceServer).ReplayWebhookDeliveries(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: BookingService_ReadBook_FullMethodName,
+		FullMethod: BookingService_ReplayWebhookDeliveries_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(BookingServiceServer).ReadBook(ctx, req.(*ReadBookRequest))
+		return srv.(BookingServiceServer).ReplayWebhookDeliveries(ctx, req.(*ReplayWebhookDeliveriesRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _BookingService_UpdateBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdateBookRequest)
+func _BookingService_CreateApiKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateApiKeyRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(BookingServiceServer).UpdateBook(ctx, in)
+		return srv.(BookingServiceServer).CreateApiKey(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: BookingService_UpdateBook_FullMethodName,
+		FullMethod: BookingService_CreateApiKey_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(BookingServiceServer).UpdateBook(ctx, req.(*UpdateBookRequest))
+		return srv.(BookingServiceServer).CreateApiKey(ctx, req.(*CreateApiKeyRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _BookingService_DeleteBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteBookRequest)
+func _BookingService_RevokeApiKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeApiKeyRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(BookingServiceServer).DeleteBook(ctx, in)
+		return srv.(BookingServiceServer).RevokeApiKey(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: BookingService_DeleteBook_FullMethodName,
+		FullMethod: BookingService_RevokeApiKey_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(BookingServiceServer).DeleteBook(ctx, req.(*DeleteBookRequest))
+		return srv.(BookingServiceServer).RevokeApiKey(ctx, req.(*RevokeApiKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_ListApiKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListApiKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).ListApiKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_ListApiKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).ListApiKeys(ctx, req.(*ListApiKeysRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -206,6 +1574,14 @@ var BookingService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ReadBook",
 			Handler:    _BookingService_ReadBook_Handler,
 		},
+		{
+			MethodName: "ListBooks",
+			Handler:    _BookingService_ListBooks_Handler,
+		},
+		{
+			MethodName: "GetRelatedBooks",
+			Handler:    _BookingService_GetRelatedBooks_Handler,
+		},
 		{
 			MethodName: "UpdateBook",
 			Handler:    _BookingService_UpdateBook_Handler,
@@ -214,7 +1590,149 @@ var BookingService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteBook",
 			Handler:    _BookingService_DeleteBook_Handler,
 		},
+		{
+			MethodName: "ListBookRevisions",
+			Handler:    _BookingService_ListBookRevisions_Handler,
+		},
+		{
+			MethodName: "RevertBook",
+			Handler:    _BookingService_RevertBook_Handler,
+		},
+		{
+			MethodName: "CreateReview",
+			Handler:    _BookingService_CreateReview_Handler,
+		},
+		{
+			MethodName: "ListReviews",
+			Handler:    _BookingService_ListReviews_Handler,
+		},
+		{
+			MethodName: "ListPendingReviews",
+			Handler:    _BookingService_ListPendingReviews_Handler,
+		},
+		{
+			MethodName: "ModerateReview",
+			Handler:    _BookingService_ModerateReview_Handler,
+		},
+		{
+			MethodName: "CreateSeries",
+			Handler:    _BookingService_CreateSeries_Handler,
+		},
+		{
+			MethodName: "GetSeries",
+			Handler:    _BookingService_GetSeries_Handler,
+		},
+		{
+			MethodName: "SetBookSeries",
+			Handler:    _BookingService_SetBookSeries_Handler,
+		},
+		{
+			MethodName: "GetSeriesNeighbors",
+			Handler:    _BookingService_GetSeriesNeighbors_Handler,
+		},
+		{
+			MethodName: "SetBookTranslation",
+			Handler:    _BookingService_SetBookTranslation_Handler,
+		},
+		{
+			MethodName: "ListBookTranslations",
+			Handler:    _BookingService_ListBookTranslations_Handler,
+		},
+		{
+			MethodName: "DeleteBookTranslation",
+			Handler:    _BookingService_DeleteBookTranslation_Handler,
+		},
+		{
+			MethodName: "CreatePreOrder",
+			Handler:    _BookingService_CreatePreOrder_Handler,
+		},
+		{
+			MethodName: "ListPreOrders",
+			Handler:    _BookingService_ListPreOrders_Handler,
+		},
+		{
+			MethodName: "CancelReservation",
+			Handler:    _BookingService_CancelReservation_Handler,
+		},
+		{
+			MethodName: "SubscribeBackInStock",
+			Handler:    _BookingService_SubscribeBackInStock_Handler,
+		},
+		{
+			MethodName: "UnsubscribeBackInStock",
+			Handler:    _BookingService_UnsubscribeBackInStock_Handler,
+		},
+		{
+			MethodName: "CreateGenre",
+			Handler:    _BookingService_CreateGenre_Handler,
+		},
+		{
+			MethodName: "GetGenre",
+			Handler:    _BookingService_GetGenre_Handler,
+		},
+		{
+			MethodName: "ListGenres",
+			Handler:    _BookingService_ListGenres_Handler,
+		},
+		{
+			MethodName: "UpdateGenre",
+			Handler:    _BookingService_UpdateGenre_Handler,
+		},
+		{
+			MethodName: "DeleteGenre",
+			Handler:    _BookingService_DeleteGenre_Handler,
+		},
+		{
+			MethodName: "CreateWebhookEndpoint",
+			Handler:    _BookingService_CreateWebhookEndpoint_Handler,
+		},
+		{
+			MethodName: "GetWebhookEndpoint",
+			Handler:    _BookingService_GetWebhookEndpoint_Handler,
+		},
+		{
+			MethodName: "ListWebhookEndpoints",
+			Handler:    _BookingService_ListWebhookEndpoints_Handler,
+		},
+		{
+			MethodName: "UpdateWebhookEndpoint",
+			Handler:    _BookingService_UpdateWebhookEndpoint_Handler,
+		},
+		{
+			MethodName: "DeleteWebhookEndpoint",
+			Handler:    _BookingService_DeleteWebhookEndpoint_Handler,
+		},
+		{
+			MethodName: "ListWebhookDeliveries",
+			Handler:    _BookingService_ListWebhookDeliveries_Handler,
+		},
+		{
+			MethodName: "GetWebhookDelivery",
+			Handler:    _BookingService_GetWebhookDelivery_Handler,
+		},
+		{
+			MethodName: "ReplayWebhookDeliveries",
+			Handler:    _BookingService_ReplayWebhookDeliveries_Handler,
+		},
+		{
+			MethodName: "CreateApiKey",
+			Handler:    _BookingService_CreateApiKey_Handler,
+		},
+		{
+			MethodName: "RevokeApiKey",
+			Handler:    _BookingService_RevokeApiKey_Handler,
+		},
+		{
+			MethodName: "ListApiKeys",
+			Handler:    _BookingService_ListApiKeys_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadCover",
+			Handler:       _BookingService_UploadCover_Handler,
+			ClientStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "booking.proto",
 }
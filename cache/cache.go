@@ -0,0 +1,163 @@
+// Package cache is a read-through cache with an in-process LRU tier and an
+// optional external tier shared between replicas. Concurrent misses for the
+// same key are coalesced into a single load.
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+)
+
+// Backend stores cached values. LRU is the in-process implementation;
+// external caches such as Redis or memcached can be plugged in as the
+// shared tier of a Cache.
+type Backend interface {
+	// Get returns the value for key. ok is false when it is not cached.
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// LoadFunc loads a value that is not cached.
+type LoadFunc func(ctx context.Context) ([]byte, error)
+
+// Cache looks values up in the local tier, then the shared tier, and loads
+// them on a miss. A nil *Cache is valid and caches nothing.
+type Cache struct {
+	local  *LRU
+	shared Backend
+	ttl    time.Duration
+
+	group singleflight.Group
+	// generation is incremented by every invalidation. A load that started
+	// before an invalidation does not store its possibly stale result.
+	generation atomic.Uint64
+
+	hits    *prometheus.CounterVec
+	misses  *prometheus.CounterVec
+	entries prometheus.GaugeFunc
+}
+
+// New returns a cache of size entries in process, backed by shared if it is
+// not nil. Entries expire after ttl in both tiers.
+func New(size int, ttl time.Duration, shared Backend) *Cache {
+	c := &Cache{
+		local:  NewLRU(size),
+		shared: shared,
+		ttl:    ttl,
+		hits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "bookstore",
+			Subsystem: "cache",
+			Name:      "hits_total",
+			Help:      "Total number of cache hits, by tier.",
+		}, []string{"tier"}),
+		misses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "bookstore",
+			Subsystem: "cache",
+			Name:      "misses_total",
+			Help:      "Total number of cache misses, by tier.",
+		}, []string{"tier"}),
+	}
+	c.entries = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "bookstore",
+		Subsystem: "cache",
+		Name:      "entries",
+		Help:      "Number of entries in the in-process cache.",
+	}, func() float64 { return float64(c.local.Len()) })
+	return c
+}
+
+// Collectors returns the cache metrics, to be registered with
+// metrics.Metrics.Register.
+func (c *Cache) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{c.hits, c.misses, c.entries}
+}
+
+// Get returns the cached value for key, or loads and caches it.
+func (c *Cache) Get(ctx context.Context, key string, load LoadFunc) ([]byte, error) {
+	if c == nil {
+		return load(ctx)
+	}
+
+	if value, ok, _ := c.local.Get(ctx, key); ok {
+		c.hits.WithLabelValues("local").Inc()
+		return value, nil
+	}
+	c.misses.WithLabelValues("local").Inc()
+
+	// The load runs on behalf of every caller waiting for it, so it must not
+	// be cancelled with the first one.
+	loadCtx := context.WithoutCancel(ctx)
+	value, err, _ := c.group.Do(key, func() (interface{}, error) {
+		return c.fill(loadCtx, key, load)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]byte), nil
+}
+
+func (c *Cache) fill(ctx context.Context, key string, load LoadFunc) ([]byte, error) {
+	generation := c.generation.Load()
+
+	if c.shared != nil {
+		value, ok, err := c.shared.Get(ctx, key)
+		if err != nil {
+			slog.Warn("Failed to read from shared cache", "key", key, "error", err)
+		}
+		if ok {
+			c.hits.WithLabelValues("shared").Inc()
+			c.store(ctx, key, value, generation, false)
+			return value, nil
+		}
+		c.misses.WithLabelValues("shared").Inc()
+	}
+
+	value, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.store(ctx, key, value, generation, c.shared != nil)
+	return value, nil
+}
+
+func (c *Cache) store(ctx context.Context, key string, value []byte, generation uint64, shared bool) {
+	if c.generation.Load() != generation {
+		return
+	}
+	if shared {
+		if err := c.shared.Set(ctx, key, value, c.ttl); err != nil {
+			slog.Warn("Failed to write to shared cache", "key", key, "error", err)
+		}
+	}
+	_ = c.local.Set(ctx, key, value, c.ttl)
+}
+
+// Invalidate removes key from both tiers.
+func (c *Cache) Invalidate(ctx context.Context, key string) {
+	if c == nil {
+		return
+	}
+	c.generation.Add(1)
+	c.group.Forget(key)
+	_ = c.local.Delete(ctx, key)
+	if c.shared != nil {
+		if err := c.shared.Delete(ctx, key); err != nil {
+			slog.Warn("Failed to delete from shared cache", "key", key, "error", err)
+		}
+	}
+}
+
+// Purge removes every entry from the local tier.
+func (c *Cache) Purge() {
+	if c == nil {
+		return
+	}
+	c.generation.Add(1)
+	c.local.Purge()
+}
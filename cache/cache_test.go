@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	_ = c.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = c.Set(ctx, "b", []byte("2"), time.Minute)
+	// Reading a makes b the least recently used.
+	if _, ok, _ := c.Get(ctx, "a"); !ok {
+		t.Fatal("a is not cached")
+	}
+	_ = c.Set(ctx, "c", []byte("3"), time.Minute)
+
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Error("b was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok, _ := c.Get(ctx, key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+}
+
+func TestLRUOverwriteDoesNotGrow(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	_ = c.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = c.Set(ctx, "a", []byte("2"), time.Minute)
+
+	value, ok, _ := c.Get(ctx, "a")
+	if !ok || string(value) != "2" {
+		t.Errorf("Get(a) = %q, %v, want %q, true", value, ok, "2")
+	}
+	if c.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", c.Len())
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+	_ = c.Set(ctx, "a", []byte("1"), -time.Second)
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Error("expired entry returned")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Len() = %d after reading an expired entry, want 0", c.Len())
+	}
+}
+
+func TestLRUDeleteAndPurge(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(3)
+	for _, key := range []string{"a", "b", "c"} {
+		_ = c.Set(ctx, key, []byte(key), time.Minute)
+	}
+	_ = c.Delete(ctx, "a")
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Error("deleted entry returned")
+	}
+	c.Purge()
+	if c.Len() != 0 {
+		t.Errorf("Len() = %d after Purge, want 0", c.Len())
+	}
+}
+
+func TestCacheLoadsOnce(t *testing.T) {
+	ctx := context.Background()
+	c := New(10, time.Minute, nil)
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(context.Context) ([]byte, error) {
+		loads.Add(1)
+		<-release
+		return []byte("book"), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if value, err := c.Get(ctx, "k", load); err != nil || string(value) != "book" {
+				t.Errorf("Get() = %q, %v", value, err)
+			}
+		}()
+	}
+	// Let the callers pile up on the first load.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if _, err := c.Get(ctx, "k", load); err != nil {
+		t.Fatal(err)
+	}
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loaded %d times, want 1", n)
+	}
+}
+
+func TestCacheDoesNotStoreLoadRacingInvalidation(t *testing.T) {
+	ctx := context.Background()
+	c := New(10, time.Minute, nil)
+	started, release := make(chan struct{}), make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = c.Get(ctx, "k", func(context.Context) ([]byte, error) {
+			close(started)
+			<-release
+			return []byte("stale"), nil
+		})
+	}()
+	<-started
+	c.Invalidate(ctx, "k")
+	close(release)
+	<-done
+
+	value, err := c.Get(ctx, "k", func(context.Context) ([]byte, error) {
+		return []byte("fresh"), nil
+	})
+	if err != nil || string(value) != "fresh" {
+		t.Errorf("Get() after invalidation = %q, %v, want %q", value, err, "fresh")
+	}
+}
+
+func TestCacheInvalidateAndPurge(t *testing.T) {
+	ctx := context.Background()
+	c := New(10, time.Minute, nil)
+	version := "1"
+	load := func(context.Context) ([]byte, error) { return []byte(version), nil }
+
+	get := func() string {
+		t.Helper()
+		value, err := c.Get(ctx, "k", load)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(value)
+	}
+
+	get()
+	version = "2"
+	if got := get(); got != "1" {
+		t.Errorf("cached Get() = %q, want %q", got, "1")
+	}
+	c.Invalidate(ctx, "k")
+	if got := get(); got != "2" {
+		t.Errorf("Get() after Invalidate = %q, want %q", got, "2")
+	}
+	version = "3"
+	c.Purge()
+	if got := get(); got != "3" {
+		t.Errorf("Get() after Purge = %q, want %q", got, "3")
+	}
+}
+
+func TestCacheDoesNotStoreErrors(t *testing.T) {
+	ctx := context.Background()
+	c := New(10, time.Minute, nil)
+	errLoad := errors.New("database down")
+
+	if _, err := c.Get(ctx, "k", func(context.Context) ([]byte, error) { return nil, errLoad }); !errors.Is(err, errLoad) {
+		t.Fatalf("Get() error = %v, want %v", err, errLoad)
+	}
+	value, err := c.Get(ctx, "k", func(context.Context) ([]byte, error) { return []byte("ok"), nil })
+	if err != nil || string(value) != "ok" {
+		t.Errorf("Get() after a failed load = %q, %v, want %q", value, err, "ok")
+	}
+}
+
+func TestCacheSharedTier(t *testing.T) {
+	ctx := context.Background()
+	shared := NewLRU(10)
+	a, b := New(10, time.Minute, shared), New(10, time.Minute, shared)
+
+	if _, err := a.Get(ctx, "k", func(context.Context) ([]byte, error) { return []byte("v"), nil }); err != nil {
+		t.Fatal(err)
+	}
+	value, err := b.Get(ctx, "k", func(context.Context) ([]byte, error) {
+		t.Error("b loaded a value cached in the shared tier")
+		return nil, nil
+	})
+	if err != nil || string(value) != "v" {
+		t.Errorf("b.Get() = %q, %v, want %q", value, err, "v")
+	}
+
+	a.Invalidate(ctx, "k")
+	if _, ok, _ := shared.Get(ctx, "k"); ok {
+		t.Error("Invalidate left the key in the shared tier")
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	value, err := c.Get(context.Background(), "k", func(context.Context) ([]byte, error) { return []byte("v"), nil })
+	if err != nil || string(value) != "v" {
+		t.Errorf("Get() on a nil cache = %q, %v", value, err)
+	}
+	c.Invalidate(context.Background(), "k")
+	c.Purge()
+}
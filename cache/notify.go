@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	listenerMinReconnect = time.Second
+	listenerMaxReconnect = time.Minute
+)
+
+// Listen invalidates the keys received on a Postgres notification channel
+// until ctx is cancelled. key maps a notification payload to a cache key.
+// The local tier is purged whenever the connection is re-established,
+// since notifications sent in the meantime are lost.
+func (c *Cache) Listen(ctx context.Context, dsn, channel string, key func(payload string) string) {
+	if c == nil {
+		return
+	}
+
+	listener := pq.NewListener(dsn, listenerMinReconnect, listenerMaxReconnect, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			slog.Warn("Cache invalidation listener disconnected", "channel", channel, "error", err)
+		case pq.ListenerEventReconnected:
+			slog.Info("Cache invalidation listener reconnected, purging cache", "channel", channel)
+			c.Purge()
+		case pq.ListenerEventConnectionAttemptFailed:
+			slog.Warn("Cache invalidation listener failed to connect", "channel", channel, "error", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(channel); err != nil {
+		slog.Error("Failed to listen for cache invalidations", "channel", channel, "error", err)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-listener.Notify:
+			// A nil notification follows a reconnect, which already purged
+			// the cache.
+			if n != nil {
+				c.Invalidate(ctx, key(n.Extra))
+			}
+		}
+	}
+}
@@ -0,0 +1,977 @@
+// Package config loads the book service configuration from a YAML or TOML
+// file, environment variables and command-line flags, in that order of
+// precedence (flags win).
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// Secret holds a sensitive value. It is redacted when printed or marshaled,
+// use Value to get the plain text.
+type Secret string
+
+// Value returns the plain text secret.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+type Config struct {
+	GRPC        GRPCConfig        `yaml:"grpc" toml:"grpc"`
+	HTTP        HTTPConfig        `yaml:"http" toml:"http"`
+	Database    DatabaseConfig    `yaml:"database" toml:"database"`
+	AMQP        AMQPConfig        `yaml:"amqp" toml:"amqp"`
+	Health      HealthConfig      `yaml:"health" toml:"health"`
+	Tracing     TracingConfig     `yaml:"tracing" toml:"tracing"`
+	Logging     LoggingConfig     `yaml:"logging" toml:"logging"`
+	Auth        AuthConfig        `yaml:"auth" toml:"auth"`
+	Authz       AuthzConfig       `yaml:"authz" toml:"authz"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit" toml:"rate_limit"`
+	Cache       CacheConfig       `yaml:"cache" toml:"cache"`
+	Idempotency IdempotencyConfig `yaml:"idempotency" toml:"idempotency"`
+	Covers      CoversConfig      `yaml:"covers" toml:"covers"`
+	Locales     LocalesConfig     `yaml:"locales" toml:"locales"`
+	PreOrders   PreOrdersConfig   `yaml:"pre_orders" toml:"pre_orders"`
+	Events      EventsConfig      `yaml:"events" toml:"events"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks" toml:"webhooks"`
+	// TLSReloadInterval is how often certificate files are checked for
+	// changes.
+	TLSReloadInterval time.Duration `yaml:"tls_reload_interval" toml:"tls_reload_interval"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+}
+
+type GRPCConfig struct {
+	Addr string    `yaml:"addr" toml:"addr"`
+	TLS  TLSConfig `yaml:"tls" toml:"tls"`
+}
+
+// HTTPConfig configures the gateway. GRPCClient secures the gateway's
+// connection to the gRPC server when gRPC TLS is enabled. CacheControl is
+// the Cache-Control header of successful responses, by RPC name.
+type HTTPConfig struct {
+	Addr         string            `yaml:"addr" toml:"addr"`
+	TLS          TLSConfig         `yaml:"tls" toml:"tls"`
+	GRPCClient   TLSClientConfig   `yaml:"grpc_client" toml:"grpc_client"`
+	CacheControl map[string]string `yaml:"cache_control" toml:"cache_control"`
+}
+
+// TLSConfig configures TLS on a listener. ClientAuth is "none", "optional"
+// (verify client certificates when presented) or "require"; client
+// certificates are verified against ClientCAFile.
+type TLSConfig struct {
+	Enabled      bool   `yaml:"enabled" toml:"enabled"`
+	CertFile     string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile      string `yaml:"key_file" toml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file" toml:"client_ca_file"`
+	ClientAuth   string `yaml:"client_auth" toml:"client_auth"`
+}
+
+// TLSClientConfig configures a TLS client. The system roots are trusted
+// when CAFile is empty, and CertFile and KeyFile are the client certificate
+// presented to servers that require one.
+type TLSClientConfig struct {
+	CAFile     string `yaml:"ca_file" toml:"ca_file"`
+	CertFile   string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile    string `yaml:"key_file" toml:"key_file"`
+	ServerName string `yaml:"server_name" toml:"server_name"`
+}
+
+type DatabaseConfig struct {
+	Host         string `yaml:"host" toml:"host"`
+	Port         int    `yaml:"port" toml:"port"`
+	User         string `yaml:"user" toml:"user"`
+	Password     Secret `yaml:"password" toml:"password"`
+	PasswordFile string `yaml:"password_file" toml:"password_file"`
+	Name         string `yaml:"name" toml:"name"`
+	SSLMode      string `yaml:"sslmode" toml:"sslmode"`
+	SSLRootCert  string `yaml:"sslrootcert" toml:"sslrootcert"`
+	SSLCert      string `yaml:"sslcert" toml:"sslcert"`
+	SSLKey       string `yaml:"sslkey" toml:"sslkey"`
+	AutoMigrate  bool   `yaml:"auto_migrate" toml:"auto_migrate"`
+}
+
+// AMQPConfig configures the optional RabbitMQ connection. It is disabled
+// when URL is empty. The broker must be reachable at startup; a connection
+// lost later is re-established in the background.
+type AMQPConfig struct {
+	URL Secret `yaml:"url" toml:"url"`
+}
+
+type HealthConfig struct {
+	CheckInterval time.Duration `yaml:"check_interval" toml:"check_interval"`
+}
+
+// TracingConfig selects the OpenTelemetry span exporter: "none", "stdout"
+// or "otlp".
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter" toml:"exporter"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" toml:"otlp_endpoint"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" toml:"otlp_insecure"`
+	SampleRatio  float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// LoggingConfig configures the default slog logger. Format is "json" or
+// "text".
+type LoggingConfig struct {
+	Level  string `yaml:"level" toml:"level"`
+	Format string `yaml:"format" toml:"format"`
+}
+
+// AuthConfig configures JWT authentication. Tokens are verified with the
+// HS256 secret, the RS256 keys of the JWKS (a file path or an http(s) URL),
+// or both. It is enabled by default, and the service refuses to start
+// without a key. Disabling it, for local development only, leaves every
+// RPC and admin endpoint open to any caller.
+type AuthConfig struct {
+	Enabled             bool          `yaml:"enabled" toml:"enabled"`
+	HMACSecret          Secret        `yaml:"hmac_secret" toml:"hmac_secret"`
+	HMACSecretFile      string        `yaml:"hmac_secret_file" toml:"hmac_secret_file"`
+	JWKS                string        `yaml:"jwks" toml:"jwks"`
+	JWKSRefreshInterval time.Duration `yaml:"jwks_refresh_interval" toml:"jwks_refresh_interval"`
+	Issuer              string        `yaml:"issuer" toml:"issuer"`
+	Audience            string        `yaml:"audience" toml:"audience"`
+	Leeway              time.Duration `yaml:"leeway" toml:"leeway"`
+	RequiredScope       string        `yaml:"required_scope" toml:"required_scope"`
+}
+
+// AuthzConfig configures role-based authorization. It is disabled when
+// PolicyFile is empty.
+type AuthzConfig struct {
+	PolicyFile     string        `yaml:"policy_file" toml:"policy_file"`
+	ReloadInterval time.Duration `yaml:"reload_interval" toml:"reload_interval"`
+}
+
+// RateLimit is a token bucket: Rate tokens per second are added up to
+// Burst tokens, and every request takes one.
+type RateLimit struct {
+	Rate  float64 `yaml:"rate" toml:"rate"`
+	Burst int     `yaml:"burst" toml:"burst"`
+}
+
+// RateLimitConfig configures per-client rate limiting. Methods overrides
+// the default limit for RPCs by name, such as "ReadBook". Backend is
+// "memory" or "postgres". IP limits every IP address over all RPCs before
+// credentials are checked, so that API keys and tokens cannot be guessed
+// faster. TrustedProxies lists the addresses or CIDR ranges of the proxies
+// in front of the gateway whose X-Forwarded-For entries are believed.
+type RateLimitConfig struct {
+	Enabled        bool                 `yaml:"enabled" toml:"enabled"`
+	Backend        string               `yaml:"backend" toml:"backend"`
+	Default        RateLimit            `yaml:"default" toml:"default"`
+	Methods        map[string]RateLimit `yaml:"methods" toml:"methods"`
+	IP             RateLimit            `yaml:"ip" toml:"ip"`
+	TrustedProxies []string             `yaml:"trusted_proxies" toml:"trusted_proxies"`
+}
+
+// CacheConfig configures the ReadBook cache. With Notify, books changed by
+// other replicas are invalidated through Postgres notifications; otherwise
+// they may be served stale for up to TTL.
+type CacheConfig struct {
+	Enabled bool          `yaml:"enabled" toml:"enabled"`
+	Size    int           `yaml:"size" toml:"size"`
+	TTL     time.Duration `yaml:"ttl" toml:"ttl"`
+	Notify  bool          `yaml:"notify" toml:"notify"`
+}
+
+// IdempotencyConfig configures idempotency keys for write RPCs. Responses
+// are kept for TTL. A key whose first attempt has not finished after
+// ClaimTimeout, say because its replica crashed, may be claimed again.
+type IdempotencyConfig struct {
+	Enabled      bool          `yaml:"enabled" toml:"enabled"`
+	TTL          time.Duration `yaml:"ttl" toml:"ttl"`
+	ClaimTimeout time.Duration `yaml:"claim_timeout" toml:"claim_timeout"`
+}
+
+// CoversConfig configures cover image uploads. Covers are stored below Dir.
+// Thumbnails maps thumbnail names to their widths in pixels. BaseURL is the
+// external URL of the gateway that cover URLs are built on; they are paths
+// when it is empty.
+type CoversConfig struct {
+	Dir        string         `yaml:"dir" toml:"dir"`
+	MaxBytes   int            `yaml:"max_bytes" toml:"max_bytes"`
+	Thumbnails map[string]int `yaml:"thumbnails" toml:"thumbnails"`
+	BaseURL    string         `yaml:"base_url" toml:"base_url"`
+}
+
+// LocalesConfig configures localized book metadata. Fallback lists the
+// locales tried, in order, after those the caller prefers.
+type LocalesConfig struct {
+	Fallback []string `yaml:"fallback" toml:"fallback"`
+}
+
+// PreOrdersConfig configures the job that allocates incoming stock to
+// pre-orders and releases books on their release date.
+type PreOrdersConfig struct {
+	AllocationInterval time.Duration `yaml:"allocation_interval" toml:"allocation_interval"`
+}
+
+// EventsConfig configures catalog events. They are published to the topic
+// exchange Exchange every RelayInterval when AMQP is configured, and kept
+// for Retention once published.
+type EventsConfig struct {
+	Exchange      string        `yaml:"exchange" toml:"exchange"`
+	RelayInterval time.Duration `yaml:"relay_interval" toml:"relay_interval"`
+	Retention     time.Duration `yaml:"retention" toml:"retention"`
+}
+
+// WebhooksConfig configures the delivery of events to webhook endpoints.
+// Due deliveries are checked for every PollInterval and up to Concurrency
+// are sent at a time. A failed attempt is retried after InitialBackoff,
+// doubling up to MaxBackoff, until MaxAttempts were made. Deliveries are
+// kept for Retention.
+type WebhooksConfig struct {
+	PollInterval   time.Duration `yaml:"poll_interval" toml:"poll_interval"`
+	Timeout        time.Duration `yaml:"timeout" toml:"timeout"`
+	Concurrency    int           `yaml:"concurrency" toml:"concurrency"`
+	MaxAttempts    int           `yaml:"max_attempts" toml:"max_attempts"`
+	InitialBackoff time.Duration `yaml:"initial_backoff" toml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff" toml:"max_backoff"`
+	Retention      time.Duration `yaml:"retention" toml:"retention"`
+}
+
+// DSN returns the connection string for the lib/pq driver.
+func (c DatabaseConfig) DSN() string {
+	params := []struct{ key, value string }{
+		{"host", c.Host},
+		{"port", strconv.Itoa(c.Port)},
+		{"user", c.User},
+		{"password", c.Password.Value()},
+		{"dbname", c.Name},
+		{"sslmode", c.SSLMode},
+		{"sslrootcert", c.SSLRootCert},
+		{"sslcert", c.SSLCert},
+		{"sslkey", c.SSLKey},
+	}
+
+	var parts []string
+	for _, p := range params {
+		if p.value == "" {
+			continue
+		}
+		parts = append(parts, p.key+"="+quoteDSNValue(p.value))
+	}
+	return strings.Join(parts, " ")
+}
+
+func quoteDSNValue(v string) string {
+	if v != "" && !strings.ContainsAny(v, ` '\`) {
+		return v
+	}
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `'`, `\'`)
+	return "'" + v + "'"
+}
+
+// Default returns the configuration used when nothing else is specified.
+func Default() *Config {
+	return &Config{
+		GRPC: GRPCConfig{
+			Addr: ":50052",
+			TLS:  TLSConfig{ClientAuth: "none"},
+		},
+		HTTP: HTTPConfig{
+			Addr: ":8081",
+			TLS:  TLSConfig{ClientAuth: "none"},
+			CacheControl: map[string]string{
+				"ReadBook":        "public, max-age=60",
+				"GetRelatedBooks": "public, max-age=300",
+			},
+		},
+		Database: DatabaseConfig{
+			Host:        "localhost",
+			Port:        5432,
+			User:        "postgres",
+			Name:        "bookstore",
+			SSLMode:     "disable",
+			AutoMigrate: true,
+		},
+		Health: HealthConfig{
+			CheckInterval: 10 * time.Second,
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OTLPEndpoint: "localhost:4317",
+			SampleRatio:  1,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Auth: AuthConfig{
+			Enabled:             true,
+			JWKSRefreshInterval: 15 * time.Minute,
+			Leeway:              30 * time.Second,
+		},
+		Authz: AuthzConfig{
+			ReloadInterval: 30 * time.Second,
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: true,
+			Backend: "memory",
+			Default: RateLimit{Rate: 20, Burst: 40},
+			IP:      RateLimit{Rate: 100, Burst: 200},
+		},
+		Cache: CacheConfig{
+			Enabled: true,
+			Size:    10000,
+			TTL:     5 * time.Minute,
+			Notify:  true,
+		},
+		Idempotency: IdempotencyConfig{
+			Enabled:      true,
+			TTL:          24 * time.Hour,
+			ClaimTimeout: time.Minute,
+		},
+		Covers: CoversConfig{
+			Dir:      "data/blobs",
+			MaxBytes: 5 << 20,
+			Thumbnails: map[string]int{
+				"small":  160,
+				"medium": 480,
+			},
+		},
+		Locales: LocalesConfig{
+			Fallback: []string{"en"},
+		},
+		PreOrders: PreOrdersConfig{
+			AllocationInterval: time.Minute,
+		},
+		Events: EventsConfig{
+			Exchange:      "booking.events",
+			RelayInterval: time.Second,
+			Retention:     7 * 24 * time.Hour,
+		},
+		Webhooks: WebhooksConfig{
+			PollInterval:   time.Second,
+			Timeout:        10 * time.Second,
+			Concurrency:    8,
+			MaxAttempts:    10,
+			InitialBackoff: 30 * time.Second,
+			MaxBackoff:     6 * time.Hour,
+			Retention:      30 * 24 * time.Hour,
+		},
+		TLSReloadInterval: time.Minute,
+		ShutdownTimeout:   15 * time.Second,
+	}
+}
+
+// Load builds the configuration from defaults, the config file, environment
+// variables and the given command-line arguments. The config file is taken
+// from the -config flag or the CONFIG_FILE environment variable.
+func Load(args []string) (*Config, error) {
+	// Flags are parsed twice: first to find the config file, then again on
+	// top of the file and environment so that flags take precedence.
+	fs := flag.NewFlagSet("bookservice", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	Default().registerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	fs = flag.NewFlagSet("bookservice", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.String("config", "", "")
+	cfg.registerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.resolveSecrets(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) registerFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.GRPC.Addr, "grpc-addr", c.GRPC.Addr, "gRPC listen address")
+	c.GRPC.TLS.registerFlags(fs, "grpc", "gRPC")
+	fs.StringVar(&c.HTTP.Addr, "http-addr", c.HTTP.Addr, "gRPC-Gateway listen address")
+	c.HTTP.TLS.registerFlags(fs, "http", "gRPC-Gateway")
+	fs.StringVar(&c.Database.Host, "db-host", c.Database.Host, "database host")
+	fs.IntVar(&c.Database.Port, "db-port", c.Database.Port, "database port")
+	fs.StringVar(&c.Database.User, "db-user", c.Database.User, "database user")
+	fs.StringVar(&c.Database.PasswordFile, "db-password-file", c.Database.PasswordFile, "file containing the database password")
+	fs.StringVar(&c.Database.Name, "db-name", c.Database.Name, "database name")
+	fs.StringVar(&c.Database.SSLMode, "db-sslmode", c.Database.SSLMode, "database SSL mode")
+	fs.StringVar(&c.Database.SSLRootCert, "db-sslrootcert", c.Database.SSLRootCert, "CA certificate file to verify the database server")
+	fs.StringVar(&c.Database.SSLCert, "db-sslcert", c.Database.SSLCert, "client certificate file for the database")
+	fs.StringVar(&c.Database.SSLKey, "db-sslkey", c.Database.SSLKey, "client key file for the database")
+	fs.BoolVar(&c.Database.AutoMigrate, "db-auto-migrate", c.Database.AutoMigrate, "apply pending schema migrations on startup")
+	fs.DurationVar(&c.Health.CheckInterval, "health-check-interval", c.Health.CheckInterval, "interval between readiness checks")
+	fs.StringVar(&c.Tracing.Exporter, "tracing-exporter", c.Tracing.Exporter, "span exporter: none, stdout or otlp")
+	fs.StringVar(&c.Tracing.OTLPEndpoint, "otlp-endpoint", c.Tracing.OTLPEndpoint, "OTLP gRPC collector endpoint")
+	fs.BoolVar(&c.Tracing.OTLPInsecure, "otlp-insecure", c.Tracing.OTLPInsecure, "disable TLS for the OTLP exporter")
+	fs.Float64Var(&c.Tracing.SampleRatio, "tracing-sample-ratio", c.Tracing.SampleRatio, "fraction of traces to sample")
+	fs.StringVar(&c.Logging.Level, "log-level", c.Logging.Level, "log level: debug, info, warn or error")
+	fs.StringVar(&c.Logging.Format, "log-format", c.Logging.Format, "log format: json or text")
+	fs.BoolVar(&c.Auth.Enabled, "auth-enabled", c.Auth.Enabled, "require JWT bearer tokens for write RPCs")
+	fs.StringVar(&c.Auth.HMACSecretFile, "auth-hmac-secret-file", c.Auth.HMACSecretFile, "file containing the HS256 JWT secret")
+	fs.StringVar(&c.Auth.JWKS, "auth-jwks", c.Auth.JWKS, "JWKS file path or URL with RS256 verification keys")
+	fs.StringVar(&c.Auth.Issuer, "auth-issuer", c.Auth.Issuer, "expected JWT issuer")
+	fs.StringVar(&c.Auth.Audience, "auth-audience", c.Auth.Audience, "expected JWT audience")
+	fs.StringVar(&c.Authz.PolicyFile, "authz-policy-file", c.Authz.PolicyFile, "role-based authorization policy file")
+	fs.BoolVar(&c.RateLimit.Enabled, "rate-limit-enabled", c.RateLimit.Enabled, "enable per-client rate limiting")
+	fs.StringVar(&c.RateLimit.Backend, "rate-limit-backend", c.RateLimit.Backend, "rate limit state: memory or postgres")
+	fs.Func("rate-limit-trusted-proxies", "comma-separated addresses or CIDR ranges of proxies whose X-Forwarded-For is trusted", func(v string) error {
+		c.RateLimit.TrustedProxies = splitList(v)
+		return nil
+	})
+	fs.BoolVar(&c.Cache.Enabled, "cache-enabled", c.Cache.Enabled, "cache ReadBook results in memory")
+	fs.IntVar(&c.Cache.Size, "cache-size", c.Cache.Size, "maximum number of cached books")
+	fs.DurationVar(&c.Cache.TTL, "cache-ttl", c.Cache.TTL, "time a cached book is served for")
+	fs.BoolVar(&c.Idempotency.Enabled, "idempotency-enabled", c.Idempotency.Enabled, "honour idempotency keys on write RPCs")
+	fs.DurationVar(&c.Idempotency.TTL, "idempotency-ttl", c.Idempotency.TTL, "time responses are kept for retries with the same idempotency key")
+	fs.DurationVar(&c.Idempotency.ClaimTimeout, "idempotency-claim-timeout", c.Idempotency.ClaimTimeout, "time after which an unfinished first attempt no longer holds its idempotency key")
+	fs.StringVar(&c.Covers.Dir, "covers-dir", c.Covers.Dir, "directory cover images are stored in")
+	fs.IntVar(&c.Covers.MaxBytes, "covers-max-bytes", c.Covers.MaxBytes, "largest accepted cover image in bytes")
+	fs.StringVar(&c.Covers.BaseURL, "covers-base-url", c.Covers.BaseURL, "external gateway URL that cover URLs are built on")
+	fs.Func("locales-fallback", "comma-separated locales tried after the caller's preferences", func(v string) error {
+		c.Locales.Fallback = splitList(v)
+		return nil
+	})
+	fs.DurationVar(&c.PreOrders.AllocationInterval, "pre-orders-allocation-interval", c.PreOrders.AllocationInterval, "interval between pre-order allocation runs")
+	fs.StringVar(&c.Events.Exchange, "events-exchange", c.Events.Exchange, "AMQP topic exchange catalog events are published to")
+	fs.DurationVar(&c.Events.RelayInterval, "events-relay-interval", c.Events.RelayInterval, "interval between checks for unpublished events")
+	fs.DurationVar(&c.Events.Retention, "events-retention", c.Events.Retention, "time events are kept for")
+	fs.DurationVar(&c.Webhooks.PollInterval, "webhooks-poll-interval", c.Webhooks.PollInterval, "interval between checks for due webhook deliveries")
+	fs.DurationVar(&c.Webhooks.Timeout, "webhooks-timeout", c.Webhooks.Timeout, "deadline of a webhook request")
+	fs.IntVar(&c.Webhooks.Concurrency, "webhooks-concurrency", c.Webhooks.Concurrency, "maximum number of webhook requests in flight")
+	fs.IntVar(&c.Webhooks.MaxAttempts, "webhooks-max-attempts", c.Webhooks.MaxAttempts, "attempts before a webhook delivery fails")
+	fs.DurationVar(&c.Webhooks.InitialBackoff, "webhooks-initial-backoff", c.Webhooks.InitialBackoff, "delay before the first retry of a webhook delivery")
+	fs.DurationVar(&c.Webhooks.MaxBackoff, "webhooks-max-backoff", c.Webhooks.MaxBackoff, "longest delay between retries of a webhook delivery")
+	fs.DurationVar(&c.Webhooks.Retention, "webhooks-retention", c.Webhooks.Retention, "time webhook deliveries and their logs are kept for")
+	fs.DurationVar(&c.TLSReloadInterval, "tls-reload-interval", c.TLSReloadInterval, "interval between checks for changed certificate files")
+	fs.DurationVar(&c.ShutdownTimeout, "shutdown-timeout", c.ShutdownTimeout, "graceful shutdown deadline")
+}
+
+func (t *TLSConfig) registerFlags(fs *flag.FlagSet, prefix, server string) {
+	fs.BoolVar(&t.Enabled, prefix+"-tls-enabled", t.Enabled, "serve "+server+" over TLS")
+	fs.StringVar(&t.CertFile, prefix+"-tls-cert-file", t.CertFile, server+" TLS certificate file")
+	fs.StringVar(&t.KeyFile, prefix+"-tls-key-file", t.KeyFile, server+" TLS key file")
+	fs.StringVar(&t.ClientCAFile, prefix+"-tls-client-ca-file", t.ClientCAFile, "CA file to verify "+server+" client certificates")
+	fs.StringVar(&t.ClientAuth, prefix+"-tls-client-auth", t.ClientAuth, server+" client certificates: none, optional or require")
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, c)
+	case ".toml":
+		_, err = toml.Decode(string(data), c)
+	default:
+		return fmt.Errorf("unsupported config file format %q", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) applyEnv() error {
+	envString("GRPC_ADDR", &c.GRPC.Addr)
+	if err := c.GRPC.TLS.applyEnv("GRPC"); err != nil {
+		return err
+	}
+	envString("HTTP_ADDR", &c.HTTP.Addr)
+	if err := c.HTTP.TLS.applyEnv("HTTP"); err != nil {
+		return err
+	}
+	envString("GATEWAY_GRPC_CA_FILE", &c.HTTP.GRPCClient.CAFile)
+	envString("GATEWAY_GRPC_CERT_FILE", &c.HTTP.GRPCClient.CertFile)
+	envString("GATEWAY_GRPC_KEY_FILE", &c.HTTP.GRPCClient.KeyFile)
+	envString("GATEWAY_GRPC_SERVER_NAME", &c.HTTP.GRPCClient.ServerName)
+	envString("DB_HOST", &c.Database.Host)
+	if err := envInt("DB_PORT", &c.Database.Port); err != nil {
+		return err
+	}
+	envString("DB_USER", &c.Database.User)
+	if v, ok := os.LookupEnv("DB_PASSWORD"); ok {
+		c.Database.Password = Secret(v)
+	}
+	envString("DB_PASSWORD_FILE", &c.Database.PasswordFile)
+	envString("DB_NAME", &c.Database.Name)
+	envString("DB_SSLMODE", &c.Database.SSLMode)
+	envString("DB_SSLROOTCERT", &c.Database.SSLRootCert)
+	envString("DB_SSLCERT", &c.Database.SSLCert)
+	envString("DB_SSLKEY", &c.Database.SSLKey)
+	if err := envBool("DB_AUTO_MIGRATE", &c.Database.AutoMigrate); err != nil {
+		return err
+	}
+	if v, ok := os.LookupEnv("AMQP_URL"); ok {
+		c.AMQP.URL = Secret(v)
+	}
+	if err := envDuration("HEALTH_CHECK_INTERVAL", &c.Health.CheckInterval); err != nil {
+		return err
+	}
+	envString("TRACING_EXPORTER", &c.Tracing.Exporter)
+	envString("OTEL_EXPORTER_OTLP_ENDPOINT", &c.Tracing.OTLPEndpoint)
+	if err := envBool("OTEL_EXPORTER_OTLP_INSECURE", &c.Tracing.OTLPInsecure); err != nil {
+		return err
+	}
+	if err := envFloat("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio); err != nil {
+		return err
+	}
+	envString("LOG_LEVEL", &c.Logging.Level)
+	envString("LOG_FORMAT", &c.Logging.Format)
+	if err := envBool("AUTH_ENABLED", &c.Auth.Enabled); err != nil {
+		return err
+	}
+	if v, ok := os.LookupEnv("AUTH_HMAC_SECRET"); ok {
+		c.Auth.HMACSecret = Secret(v)
+	}
+	envString("AUTH_HMAC_SECRET_FILE", &c.Auth.HMACSecretFile)
+	envString("AUTH_JWKS", &c.Auth.JWKS)
+	if err := envDuration("AUTH_JWKS_REFRESH_INTERVAL", &c.Auth.JWKSRefreshInterval); err != nil {
+		return err
+	}
+	envString("AUTH_ISSUER", &c.Auth.Issuer)
+	envString("AUTH_AUDIENCE", &c.Auth.Audience)
+	envString("AUTH_REQUIRED_SCOPE", &c.Auth.RequiredScope)
+	envString("AUTHZ_POLICY_FILE", &c.Authz.PolicyFile)
+	if err := envDuration("AUTHZ_RELOAD_INTERVAL", &c.Authz.ReloadInterval); err != nil {
+		return err
+	}
+	if err := envBool("RATE_LIMIT_ENABLED", &c.RateLimit.Enabled); err != nil {
+		return err
+	}
+	envString("RATE_LIMIT_BACKEND", &c.RateLimit.Backend)
+	if err := envFloat("RATE_LIMIT_RATE", &c.RateLimit.Default.Rate); err != nil {
+		return err
+	}
+	if err := envInt("RATE_LIMIT_BURST", &c.RateLimit.Default.Burst); err != nil {
+		return err
+	}
+	if err := envFloat("RATE_LIMIT_IP_RATE", &c.RateLimit.IP.Rate); err != nil {
+		return err
+	}
+	if err := envInt("RATE_LIMIT_IP_BURST", &c.RateLimit.IP.Burst); err != nil {
+		return err
+	}
+	if v, ok := os.LookupEnv("RATE_LIMIT_TRUSTED_PROXIES"); ok {
+		c.RateLimit.TrustedProxies = splitList(v)
+	}
+	if err := envBool("CACHE_ENABLED", &c.Cache.Enabled); err != nil {
+		return err
+	}
+	if err := envInt("CACHE_SIZE", &c.Cache.Size); err != nil {
+		return err
+	}
+	if err := envDuration("CACHE_TTL", &c.Cache.TTL); err != nil {
+		return err
+	}
+	if err := envBool("CACHE_NOTIFY", &c.Cache.Notify); err != nil {
+		return err
+	}
+	if err := envBool("IDEMPOTENCY_ENABLED", &c.Idempotency.Enabled); err != nil {
+		return err
+	}
+	if err := envDuration("IDEMPOTENCY_TTL", &c.Idempotency.TTL); err != nil {
+		return err
+	}
+	if err := envDuration("IDEMPOTENCY_CLAIM_TIMEOUT", &c.Idempotency.ClaimTimeout); err != nil {
+		return err
+	}
+	envString("COVERS_DIR", &c.Covers.Dir)
+	if err := envInt("COVERS_MAX_BYTES", &c.Covers.MaxBytes); err != nil {
+		return err
+	}
+	envString("COVERS_BASE_URL", &c.Covers.BaseURL)
+	if v, ok := os.LookupEnv("LOCALES_FALLBACK"); ok {
+		c.Locales.Fallback = splitList(v)
+	}
+	if err := envDuration("PRE_ORDERS_ALLOCATION_INTERVAL", &c.PreOrders.AllocationInterval); err != nil {
+		return err
+	}
+	envString("EVENTS_EXCHANGE", &c.Events.Exchange)
+	if err := envDuration("EVENTS_RELAY_INTERVAL", &c.Events.RelayInterval); err != nil {
+		return err
+	}
+	if err := envDuration("EVENTS_RETENTION", &c.Events.Retention); err != nil {
+		return err
+	}
+	if err := envDuration("WEBHOOKS_POLL_INTERVAL", &c.Webhooks.PollInterval); err != nil {
+		return err
+	}
+	if err := envDuration("WEBHOOKS_TIMEOUT", &c.Webhooks.Timeout); err != nil {
+		return err
+	}
+	if err := envInt("WEBHOOKS_CONCURRENCY", &c.Webhooks.Concurrency); err != nil {
+		return err
+	}
+	if err := envInt("WEBHOOKS_MAX_ATTEMPTS", &c.Webhooks.MaxAttempts); err != nil {
+		return err
+	}
+	if err := envDuration("WEBHOOKS_INITIAL_BACKOFF", &c.Webhooks.InitialBackoff); err != nil {
+		return err
+	}
+	if err := envDuration("WEBHOOKS_MAX_BACKOFF", &c.Webhooks.MaxBackoff); err != nil {
+		return err
+	}
+	if err := envDuration("WEBHOOKS_RETENTION", &c.Webhooks.Retention); err != nil {
+		return err
+	}
+	if err := envDuration("TLS_RELOAD_INTERVAL", &c.TLSReloadInterval); err != nil {
+		return err
+	}
+	return envDuration("SHUTDOWN_TIMEOUT", &c.ShutdownTimeout)
+}
+
+func (t *TLSConfig) applyEnv(prefix string) error {
+	if err := envBool(prefix+"_TLS_ENABLED", &t.Enabled); err != nil {
+		return err
+	}
+	envString(prefix+"_TLS_CERT_FILE", &t.CertFile)
+	envString(prefix+"_TLS_KEY_FILE", &t.KeyFile)
+	envString(prefix+"_TLS_CLIENT_CA_FILE", &t.ClientCAFile)
+	envString(prefix+"_TLS_CLIENT_AUTH", &t.ClientAuth)
+	return nil
+}
+
+func envString(key string, dst *string) {
+	if v, ok := os.LookupEnv(key); ok {
+		*dst = v
+	}
+}
+
+// splitList splits a comma-separated list, dropping empty elements.
+func splitList(v string) []string {
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func envInt(key string, dst *int) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*dst = n
+	return nil
+}
+
+func envDuration(key string, dst *time.Duration) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*dst = d
+	return nil
+}
+
+func envBool(key string, dst *bool) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*dst = b
+	return nil
+}
+
+func envFloat(key string, dst *float64) error {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*dst = f
+	return nil
+}
+
+// resolveSecrets replaces *_file references with the contents of the
+// referenced files.
+func (c *Config) resolveSecrets() error {
+	refs := []struct {
+		name string
+		file string
+		dst  *Secret
+	}{
+		{"database password", c.Database.PasswordFile, &c.Database.Password},
+		{"auth HMAC secret", c.Auth.HMACSecretFile, &c.Auth.HMACSecret},
+	}
+	for _, ref := range refs {
+		if ref.file == "" {
+			continue
+		}
+		data, err := os.ReadFile(ref.file)
+		if err != nil {
+			return fmt.Errorf("read %s file: %w", ref.name, err)
+		}
+		*ref.dst = Secret(strings.TrimRight(string(data), "\r\n"))
+	}
+	return nil
+}
+
+// validSSLModes lists the modes supported by lib/pq.
+var validSSLModes = map[string]bool{
+	"disable":     true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+// validThumbnailName restricts thumbnail names to what can appear in a URL
+// path and a blob key unescaped.
+var validThumbnailName = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// Validate reports all problems found in the configuration.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.GRPC.Addr == "" {
+		errs = append(errs, errors.New("grpc.addr is required"))
+	}
+	if c.HTTP.Addr == "" {
+		errs = append(errs, errors.New("http.addr is required"))
+	}
+	errs = append(errs, c.GRPC.TLS.validate("grpc.tls")...)
+	errs = append(errs, c.HTTP.TLS.validate("http.tls")...)
+	if (c.HTTP.GRPCClient.CertFile == "") != (c.HTTP.GRPCClient.KeyFile == "") {
+		errs = append(errs, errors.New("http.grpc_client needs both cert_file and key_file"))
+	}
+	if c.GRPC.TLS.Enabled && c.GRPC.TLS.ClientAuth == "require" && c.HTTP.GRPCClient.CertFile == "" {
+		errs = append(errs, errors.New("grpc.tls.client_auth require needs a gateway client certificate in http.grpc_client"))
+	}
+	if c.Database.Host == "" {
+		errs = append(errs, errors.New("database.host is required"))
+	}
+	if c.Database.Port <= 0 || c.Database.Port > 65535 {
+		errs = append(errs, fmt.Errorf("database.port %d is out of range", c.Database.Port))
+	}
+	if c.Database.Name == "" {
+		errs = append(errs, errors.New("database.name is required"))
+	}
+	if !validSSLModes[c.Database.SSLMode] {
+		errs = append(errs, fmt.Errorf("database.sslmode %q is not supported", c.Database.SSLMode))
+	}
+	if (c.Database.SSLCert == "") != (c.Database.SSLKey == "") {
+		errs = append(errs, errors.New("database needs both sslcert and sslkey"))
+	}
+	if c.Health.CheckInterval <= 0 {
+		errs = append(errs, errors.New("health.check_interval must be positive"))
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.OTLPEndpoint == "" {
+			errs = append(errs, errors.New("tracing.otlp_endpoint is required for the otlp exporter"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter %q is not supported", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio must be between 0 and 1"))
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Logging.Level)); err != nil {
+		errs = append(errs, fmt.Errorf("logging.level %q is not supported", c.Logging.Level))
+	}
+	if c.Logging.Format != "json" && c.Logging.Format != "text" {
+		errs = append(errs, fmt.Errorf("logging.format %q is not supported", c.Logging.Format))
+	}
+	if c.Auth.Enabled {
+		if c.Auth.HMACSecret == "" && c.Auth.JWKS == "" {
+			errs = append(errs, errors.New("auth requires hmac_secret, hmac_secret_file or jwks when enabled"))
+		}
+		if c.Auth.JWKSRefreshInterval <= 0 {
+			errs = append(errs, errors.New("auth.jwks_refresh_interval must be positive"))
+		}
+	}
+	if c.Authz.PolicyFile != "" {
+		if !c.Auth.Enabled {
+			errs = append(errs, errors.New("authz.policy_file requires auth to be enabled"))
+		}
+		if c.Authz.ReloadInterval <= 0 {
+			errs = append(errs, errors.New("authz.reload_interval must be positive"))
+		}
+	}
+	if c.RateLimit.Enabled {
+		if c.RateLimit.Backend != "memory" && c.RateLimit.Backend != "postgres" {
+			errs = append(errs, fmt.Errorf("rate_limit.backend %q is not supported", c.RateLimit.Backend))
+		}
+		limits := map[string]RateLimit{"default": c.RateLimit.Default, "ip": c.RateLimit.IP}
+		for method, limit := range c.RateLimit.Methods {
+			limits["methods."+method] = limit
+		}
+		for name, limit := range limits {
+			// A zero rate disables limiting for the method.
+			if limit.Rate < 0 || (limit.Rate > 0 && limit.Burst < 1) {
+				errs = append(errs, fmt.Errorf("rate_limit.%s needs a non-negative rate and a burst of at least 1", name))
+			}
+		}
+		for _, proxy := range c.RateLimit.TrustedProxies {
+			if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+				errs = append(errs, fmt.Errorf("rate_limit.trusted_proxies: %q is not an address or CIDR range", proxy))
+			}
+		}
+	}
+	if c.Cache.Enabled {
+		if c.Cache.Size <= 0 {
+			errs = append(errs, errors.New("cache.size must be positive"))
+		}
+		if c.Cache.TTL <= 0 {
+			errs = append(errs, errors.New("cache.ttl must be positive"))
+		}
+	}
+	if c.Idempotency.Enabled {
+		if c.Idempotency.TTL <= 0 {
+			errs = append(errs, errors.New("idempotency.ttl must be positive"))
+		}
+		if c.Idempotency.ClaimTimeout <= 0 || c.Idempotency.ClaimTimeout > c.Idempotency.TTL {
+			errs = append(errs, errors.New("idempotency.claim_timeout must be positive and at most idempotency.ttl"))
+		}
+	}
+	if c.Covers.Dir == "" {
+		errs = append(errs, errors.New("covers.dir is required"))
+	}
+	if c.Covers.MaxBytes <= 0 {
+		errs = append(errs, errors.New("covers.max_bytes must be positive"))
+	}
+	for name, width := range c.Covers.Thumbnails {
+		if !validThumbnailName.MatchString(name) || name == "original" {
+			errs = append(errs, fmt.Errorf("covers.thumbnails: invalid name %q", name))
+		}
+		if width <= 0 {
+			errs = append(errs, fmt.Errorf("covers.thumbnails.%s must be a positive width", name))
+		}
+	}
+	for _, locale := range c.Locales.Fallback {
+		if tag, err := language.Parse(locale); err != nil || tag.String() != locale {
+			errs = append(errs, fmt.Errorf("locales.fallback: %q is not a canonical BCP 47 tag", locale))
+		}
+	}
+	if c.PreOrders.AllocationInterval <= 0 {
+		errs = append(errs, errors.New("pre_orders.allocation_interval must be positive"))
+	}
+	if c.Events.Exchange == "" {
+		errs = append(errs, errors.New("events.exchange is required"))
+	}
+	if c.Events.RelayInterval <= 0 {
+		errs = append(errs, errors.New("events.relay_interval must be positive"))
+	}
+	if c.Events.Retention <= 0 {
+		errs = append(errs, errors.New("events.retention must be positive"))
+	}
+	if c.Webhooks.PollInterval <= 0 {
+		errs = append(errs, errors.New("webhooks.poll_interval must be positive"))
+	}
+	if c.Webhooks.Timeout <= 0 {
+		errs = append(errs, errors.New("webhooks.timeout must be positive"))
+	}
+	if c.Webhooks.Concurrency <= 0 {
+		errs = append(errs, errors.New("webhooks.concurrency must be positive"))
+	}
+	if c.Webhooks.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("webhooks.max_attempts must be positive"))
+	}
+	if c.Webhooks.InitialBackoff <= 0 || c.Webhooks.MaxBackoff < c.Webhooks.InitialBackoff {
+		errs = append(errs, errors.New("webhooks.initial_backoff must be positive and at most webhooks.max_backoff"))
+	}
+	if c.Webhooks.Retention <= 0 {
+		errs = append(errs, errors.New("webhooks.retention must be positive"))
+	}
+	if (c.GRPC.TLS.Enabled || c.HTTP.TLS.Enabled) && c.TLSReloadInterval <= 0 {
+		errs = append(errs, errors.New("tls_reload_interval must be positive"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+func (t TLSConfig) validate(name string) []error {
+	if !t.Enabled {
+		return nil
+	}
+	var errs []error
+	if t.CertFile == "" || t.KeyFile == "" {
+		errs = append(errs, fmt.Errorf("%s needs cert_file and key_file when enabled", name))
+	}
+	switch t.ClientAuth {
+	case "none":
+	case "optional", "require":
+		if t.ClientCAFile == "" {
+			errs = append(errs, fmt.Errorf("%s.client_auth %q needs client_ca_file", name, t.ClientAuth))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("%s.client_auth %q is not supported", name, t.ClientAuth))
+	}
+	return errs
+}
+
+// Print writes the configuration as YAML with secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDefaultIsValid(t *testing.T) {
+	cfg := Default()
+	// Authentication needs a key to verify tokens with.
+	cfg.Auth.HMACSecret = "test-secret"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Default().Validate() = %v", err)
+	}
+}
+
+func TestDefaultRequiresAuthKey(t *testing.T) {
+	err := Default().Validate()
+	if err == nil || !strings.Contains(err.Error(), "auth requires") {
+		t.Errorf("Default().Validate() = %v, want it to require an auth key", err)
+	}
+	cfg := Default()
+	cfg.Auth.Enabled = false
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() with auth explicitly disabled = %v", err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+grpc:
+  addr: ":1000"
+http:
+  addr: ":2000"
+database:
+  host: file-host
+  port: 6000
+shutdown_timeout: 1m
+`)
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("HTTP_ADDR", ":3000")
+	t.Setenv("DB_HOST", "env-host")
+	t.Setenv("DB_PORT", "7000")
+	t.Setenv("AUTH_HMAC_SECRET", "test-secret")
+
+	cfg, err := Load([]string{"-config", path, "-db-port", "8000"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  any
+		want any
+	}{
+		{"default", cfg.Database.Name, "bookstore"},
+		{"file over default", cfg.GRPC.Addr, ":1000"},
+		{"file over default (duration)", cfg.ShutdownTimeout, time.Minute},
+		{"env over file", cfg.HTTP.Addr, ":3000"},
+		{"env over file (string)", cfg.Database.Host, "env-host"},
+		{"flag over env and file", cfg.Database.Port, 8000},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+[database]
+host = "toml-host"
+`)
+	t.Setenv("CONFIG_FILE", path)
+	// Setenv restores DB_HOST after the test; it must be unset, not empty.
+	t.Setenv("DB_HOST", "")
+	os.Unsetenv("DB_HOST")
+	t.Setenv("AUTH_HMAC_SECRET", "test-secret")
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Database.Host != "toml-host" {
+		t.Errorf("database.host = %q, want %q", cfg.Database.Host, "toml-host")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		wantErr string
+	}{
+		{"unsupported file format", []string{"-config", writeConfigFile(t, "config.json", "{}")}, nil, "unsupported config file format"},
+		{"malformed file", []string{"-config", writeConfigFile(t, "config.yaml", "grpc: [")}, nil, "parse config file"},
+		{"invalid env int", nil, map[string]string{"DB_PORT": "many"}, "invalid DB_PORT"},
+		{"invalid env duration", nil, map[string]string{"SHUTDOWN_TIMEOUT": "soon"}, "invalid SHUTDOWN_TIMEOUT"},
+		{"invalid flag", []string{"-db-port", "many"}, nil, "invalid value"},
+		{"missing secret file", []string{"-db-password-file", filepath.Join(t.TempDir(), "missing")}, nil, "read database password file"},
+		{"invalid value", []string{"-log-level", "loud"}, nil, `logging.level "loud" is not supported`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := Load(tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadResolvesSecretFiles(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	path := writeConfigFile(t, "password", "s3cret\n")
+	t.Setenv("AUTH_HMAC_SECRET", "test-secret")
+
+	cfg, err := Load([]string{"-db-password-file", path})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := cfg.Database.Password.Value(); got != "s3cret" {
+		t.Errorf("password = %q, want %q", got, "s3cret")
+	}
+	if got := cfg.Database.Password.String(); got != redacted {
+		t.Errorf("password.String() = %q, want it redacted", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{"missing grpc addr", func(c *Config) { c.GRPC.Addr = "" }, "grpc.addr is required"},
+		{"port out of range", func(c *Config) { c.Database.Port = 70000 }, "database.port 70000 is out of range"},
+		{"unknown sslmode", func(c *Config) { c.Database.SSLMode = "prefer" }, `database.sslmode "prefer" is not supported`},
+		{"ssl cert without key", func(c *Config) { c.Database.SSLCert = "client.crt" }, "both sslcert and sslkey"},
+		{"tls without cert", func(c *Config) { c.GRPC.TLS.Enabled = true }, "grpc.tls needs cert_file and key_file"},
+		{"client auth without CA", func(c *Config) {
+			c.HTTP.TLS = TLSConfig{Enabled: true, CertFile: "a", KeyFile: "b", ClientAuth: "require"}
+		}, `http.tls.client_auth "require" needs client_ca_file`},
+		{"auth without key", func(c *Config) { c.Auth.Enabled = true }, "auth requires hmac_secret"},
+		{"policy without auth", func(c *Config) {
+			c.Auth.Enabled = false
+			c.Authz.PolicyFile = "policy.yaml"
+		}, "authz.policy_file requires auth"},
+		{"rate without burst", func(c *Config) { c.RateLimit.Default = RateLimit{Rate: 1} }, "rate_limit.default needs"},
+		{"unknown rate limit backend", func(c *Config) { c.RateLimit.Backend = "redis" }, `rate_limit.backend "redis"`},
+		{"non-canonical locale", func(c *Config) { c.Locales.Fallback = []string{"EN"} }, `"EN" is not a canonical`},
+		{"backoff above max", func(c *Config) { c.Webhooks.InitialBackoff = 2 * c.Webhooks.MaxBackoff }, "webhooks.initial_backoff"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	cfg := Default()
+	cfg.GRPC.Addr = ""
+	cfg.HTTP.Addr = ""
+	cfg.Database.Name = ""
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil")
+	}
+	for _, want := range []string{"grpc.addr", "http.addr", "database.name"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want it to mention %s", err, want)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+// Package covers validates uploaded book cover images, stores them with
+// resized thumbnails in a blob.Store and serves them over HTTP.
+package covers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"Booking/blob"
+)
+
+// Original is the size name of the image as uploaded.
+const Original = "original"
+
+const (
+	// maxPixels bounds the decoded size of an image, which can be far
+	// larger than the upload for compressed formats: 4096x4096 pixels take
+	// 64 MiB decoded.
+	maxPixels = 4096 * 4096
+
+	thumbnailQuality = 85
+
+	// A version is stored once and never overwritten, so it can be cached
+	// for good.
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	// Covers stored before versions existed are replaced in place.
+	legacyCacheControl = "public, max-age=300"
+)
+
+var (
+	ErrTooLarge        = errors.New("cover image is too large")
+	ErrUnsupportedType = errors.New("cover image must be a JPEG, PNG or GIF")
+	ErrInvalidImage    = errors.New("cover image cannot be decoded")
+)
+
+// allowedTypes are the accepted content types, as detected from the
+// uploaded bytes.
+var allowedTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// Service stores every upload of a cover under a new version, as
+// "covers/<book id>/<version>/<size>", where size is Original or the name of
+// a thumbnail. Covers uploaded before versions existed are stored under
+// "covers/<book id>/<size>", which is version 0.
+type Service struct {
+	store      blob.Store
+	maxBytes   int
+	thumbnails map[string]int
+	baseURL    string
+}
+
+// New returns a Service. thumbnails maps size names, such as "small", to
+// thumbnail widths in pixels. URLs are built relative to baseURL, which may
+// be empty to return paths.
+func New(store blob.Store, maxBytes int, thumbnails map[string]int, baseURL string) *Service {
+	return &Service{
+		store:      store,
+		maxBytes:   maxBytes,
+		thumbnails: thumbnails,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// MaxBytes is the largest accepted upload.
+func (s *Service) MaxBytes() int {
+	return s.maxBytes
+}
+
+func key(bookID, version int64, size string) string {
+	prefix := "covers/" + strconv.FormatInt(bookID, 10) + "/"
+	if version == 0 {
+		return prefix + size
+	}
+	return prefix + strconv.FormatInt(version, 10) + "/" + size
+}
+
+// Save validates a cover image and stores it with its thumbnails under a new
+// version, which it returns. It returns ErrTooLarge, ErrUnsupportedType or
+// ErrInvalidImage for bad uploads. The objects of a failed upload are
+// deleted again.
+func (s *Service) Save(ctx context.Context, bookID int64, data []byte) (int64, error) {
+	if len(data) > s.maxBytes {
+		return 0, ErrTooLarge
+	}
+	if !allowedTypes[http.DetectContentType(data)] {
+		return 0, ErrUnsupportedType
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return 0, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, ErrInvalidImage
+	}
+
+	version := time.Now().UnixNano()
+	if err := s.put(ctx, bookID, version, data, img); err != nil {
+		// The upload may have been cancelled, the cleanup must still run.
+		s.Delete(context.WithoutCancel(ctx), bookID, version)
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *Service) put(ctx context.Context, bookID, version int64, data []byte, img image.Image) error {
+	if err := s.store.Put(ctx, key(bookID, version, Original), bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("store cover: %w", err)
+	}
+	for name, width := range s.thumbnails {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, thumbnail(img, width), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+			return fmt.Errorf("encode %s thumbnail: %w", name, err)
+		}
+		if err := s.store.Put(ctx, key(bookID, version, name), &buf); err != nil {
+			return fmt.Errorf("store %s thumbnail: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Delete removes a version of the cover of a book with its thumbnails.
+// Failures are logged: at worst they leave unreferenced objects behind.
+func (s *Service) Delete(ctx context.Context, bookID, version int64) {
+	for _, size := range s.sizes() {
+		if err := s.store.Delete(ctx, key(bookID, version, size)); err != nil {
+			slog.Error("Failed to delete cover", "book_id", bookID, "version", version, "size", size, "error", err)
+		}
+	}
+}
+
+// sizes returns Original and the names of the thumbnails.
+func (s *Service) sizes() []string {
+	sizes := []string{Original}
+	for name := range s.thumbnails {
+		sizes = append(sizes, name)
+	}
+	return sizes
+}
+
+// URLs returns the URL of the original cover of a book and of its
+// thumbnails by size name. version, as returned by Save, is part of the
+// URLs so that caches pick up a new cover.
+func (s *Service) URLs(bookID, version int64) (string, map[string]string) {
+	query := "?v=" + strconv.FormatInt(version, 10)
+	base := s.baseURL + "/covers/" + strconv.FormatInt(bookID, 10) + "/"
+
+	thumbnails := make(map[string]string, len(s.thumbnails))
+	for name := range s.thumbnails {
+		thumbnails[name] = base + url.PathEscape(name) + query
+	}
+	return base + Original + query, thumbnails
+}
+
+// ServeHTTP serves GET /covers/{id}/{size}?v={version}. It is meant for
+// runtime.ServeMux.HandlePath. A cover without the version, which includes
+// the URLs of covers uploaded before versions existed, is looked up under
+// version 0 and only cached briefly.
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	bookID, err := strconv.ParseInt(pathParams["id"], 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	size := pathParams["size"]
+	if _, ok := s.thumbnails[size]; !ok && size != Original {
+		http.NotFound(w, r)
+		return
+	}
+
+	cacheControl := immutableCacheControl
+	version, err := strconv.ParseInt(r.URL.Query().Get("v"), 10, 64)
+	if err != nil || version < 0 {
+		version = 0
+	}
+	obj, info, err := s.store.Open(r.Context(), key(bookID, version, size))
+	if errors.Is(err, blob.ErrNotFound) && version != 0 {
+		version = 0
+		obj, info, err = s.store.Open(r.Context(), key(bookID, version, size))
+	}
+	if version == 0 {
+		cacheControl = legacyCacheControl
+	}
+	if errors.Is(err, blob.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to read cover", http.StatusInternalServerError)
+		return
+	}
+	defer obj.Close()
+
+	content, ok := obj.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(obj)
+		if err != nil {
+			http.Error(w, "failed to read cover", http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	h := w.Header()
+	h.Set("Cache-Control", cacheControl)
+	h.Set("ETag", `"`+strconv.FormatInt(info.ModTime.UnixNano(), 36)+"-"+strconv.FormatInt(info.Size, 36)+`"`)
+	h.Set("X-Content-Type-Options", "nosniff")
+	// ServeContent detects the content type and answers conditional and
+	// range requests.
+	http.ServeContent(w, r, "", info.ModTime, content)
+}
@@ -0,0 +1,175 @@
+package covers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"Booking/blob"
+)
+
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, uniform(image.Rect(0, 0, width, height), color.NRGBA{R: 255, A: 255})); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func newTestService(t *testing.T, store blob.Store) *Service {
+	t.Helper()
+	if store == nil {
+		files, err := blob.NewFileStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		store = files
+	}
+	return New(store, 1<<20, map[string]int{"small": 16}, "")
+}
+
+func TestSaveValidates(t *testing.T) {
+	s := newTestService(t, nil)
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"text", []byte("not an image at all"), ErrUnsupportedType},
+		{"too many bytes", append(pngImage(t, 1, 1), make([]byte, 1<<20)...), ErrTooLarge},
+		{"too many pixels", pngImage(t, 4097, 4096), ErrTooLarge},
+		{"truncated", pngImage(t, 64, 64)[:60], ErrInvalidImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Save(context.Background(), 1, tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("Save() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func get(s *Service, bookID, size, query string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/covers/"+bookID+"/"+size+query, nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r, map[string]string{"id": bookID, "size": size})
+	return w
+}
+
+func TestServeVersions(t *testing.T) {
+	store, err := blob.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestService(t, store)
+	ctx := context.Background()
+
+	// A cover stored before versions existed.
+	if err := store.Put(ctx, "covers/1/original", bytes.NewReader(pngImage(t, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	version, err := s.Save(ctx, 2, pngImage(t, 64, 32))
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	url, thumbnails := s.URLs(2, version)
+	v := "?v=" + strconv.FormatInt(version, 10)
+	if url != "/covers/2/original"+v || thumbnails["small"] != "/covers/2/small"+v {
+		t.Errorf("URLs() = %q, %v", url, thumbnails)
+	}
+
+	tests := []struct {
+		name         string
+		bookID, size string
+		query        string
+		status       int
+		cacheControl string
+	}{
+		{"current version", "2", Original, v, http.StatusOK, immutableCacheControl},
+		{"thumbnail", "2", "small", v, http.StatusOK, immutableCacheControl},
+		{"other version", "2", Original, "?v=12345", http.StatusNotFound, ""},
+		{"no version", "2", Original, "", http.StatusNotFound, ""},
+		{"legacy cover", "1", Original, "?v=1700000000", http.StatusOK, legacyCacheControl},
+		{"legacy cover without version", "1", Original, "", http.StatusOK, legacyCacheControl},
+		{"unknown size", "2", "huge", v, http.StatusNotFound, ""},
+		{"invalid id", "x", Original, v, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := get(s, tt.bookID, tt.size, tt.query)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Cache-Control"); tt.status == http.StatusOK && got != tt.cacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.cacheControl)
+			}
+		})
+	}
+
+	// A new upload does not change what the old URLs serve.
+	before := get(s, "2", Original, v).Body.String()
+	next, err := s.Save(ctx, 2, pngImage(t, 16, 16))
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if next == version {
+		t.Fatal("Save() reused a version")
+	}
+	if after := get(s, "2", Original, v).Body.String(); after != before {
+		t.Error("the cover of an old version changed")
+	}
+
+	s.Delete(ctx, 2, version)
+	if w := get(s, "2", "small", v); w.Code != http.StatusNotFound {
+		t.Errorf("status of a deleted version = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+// failingStore fails to store thumbnails.
+type failingStore struct {
+	blob.Store
+}
+
+func (s failingStore) Put(ctx context.Context, key string, r io.Reader) error {
+	if !strings.HasSuffix(key, "/"+Original) {
+		return errors.New("disk full")
+	}
+	return s.Store.Put(ctx, key, r)
+}
+
+func TestSaveCleansUpOnError(t *testing.T) {
+	dir := t.TempDir()
+	files, err := blob.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestService(t, failingStore{files})
+	if _, err := s.Save(context.Background(), 3, pngImage(t, 64, 64)); err == nil {
+		t.Fatal("Save() succeeded without storing the thumbnails")
+	}
+	// The original was stored first and deleted again.
+	var left []string
+	if err := fs.WalkDir(os.DirFS(dir), ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			left = append(left, path)
+		}
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 0 {
+		t.Errorf("objects left after a failed upload: %v", left)
+	}
+}
@@ -0,0 +1,61 @@
+package covers
+
+import "image"
+
+// thumbnail scales img down to width, keeping its aspect ratio, and flattens
+// it onto a white background, as JPEG has no alpha channel. Images narrower
+// than width keep their size.
+//
+// Only the thumbnail is allocated: each of its pixels averages the source
+// pixels it covers, which avoids the aliasing of nearest-neighbour sampling,
+// and is then flattened.
+func thumbnail(img image.Image, width int) *image.RGBA {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	height := srcH
+	if width >= srcW {
+		width = srcW
+	} else {
+		height = srcH * width / srcW
+		if height < 1 {
+			height = 1
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := y * srcH / height
+		y1 := (y + 1) * srcH / height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := x * srcW / width
+			x1 := (x + 1) * srcW / width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var sum [4]uint64
+			for sy := b.Min.Y + y0; sy < b.Min.Y+y1; sy++ {
+				for sx := b.Min.X + x0; sx < b.Min.X+x1; sx++ {
+					r, g, bl, a := img.At(sx, sy).RGBA()
+					sum[0] += uint64(r)
+					sum[1] += uint64(g)
+					sum[2] += uint64(bl)
+					sum[3] += uint64(a)
+				}
+			}
+			n := uint64((y1 - y0) * (x1 - x0))
+			// The colors are alpha-premultiplied, so flattening adds the
+			// white showing through the transparent part.
+			white := 0xffff*n - sum[3]
+			i := y*dst.Stride + x*4
+			for c := 0; c < 3; c++ {
+				dst.Pix[i+c] = uint8(((sum[c] + white + n/2) / n) >> 8)
+			}
+			dst.Pix[i+3] = 0xff
+		}
+	}
+	return dst
+}
@@ -0,0 +1,98 @@
+package covers
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name       string
+		img        image.Image
+		width      int
+		wantBounds image.Rectangle
+		want       color.RGBA
+	}{
+		{
+			name:       "scaled down",
+			img:        uniform(image.Rect(0, 0, 400, 200), color.NRGBA{R: 200, G: 100, B: 50, A: 255}),
+			width:      100,
+			wantBounds: image.Rect(0, 0, 100, 50),
+			want:       color.RGBA{R: 200, G: 100, B: 50, A: 255},
+		},
+		{
+			name:       "narrower than width",
+			img:        uniform(image.Rect(0, 0, 40, 80), color.NRGBA{R: 10, G: 20, B: 30, A: 255}),
+			width:      100,
+			wantBounds: image.Rect(0, 0, 40, 80),
+			want:       color.RGBA{R: 10, G: 20, B: 30, A: 255},
+		},
+		{
+			name:       "offset bounds",
+			img:        uniform(image.Rect(10, 20, 210, 120), color.NRGBA{R: 1, G: 2, B: 3, A: 255}),
+			width:      50,
+			wantBounds: image.Rect(0, 0, 50, 25),
+			want:       color.RGBA{R: 1, G: 2, B: 3, A: 255},
+		},
+		{
+			name:       "transparent",
+			img:        uniform(image.Rect(0, 0, 20, 20), color.NRGBA{}),
+			width:      10,
+			wantBounds: image.Rect(0, 0, 10, 10),
+			want:       color.RGBA{R: 255, G: 255, B: 255, A: 255},
+		},
+		{
+			name:       "half transparent",
+			img:        uniform(image.Rect(0, 0, 20, 20), color.NRGBA{A: 128}),
+			width:      10,
+			wantBounds: image.Rect(0, 0, 10, 10),
+			want:       color.RGBA{R: 127, G: 127, B: 127, A: 255},
+		},
+		{
+			name:       "very wide",
+			img:        uniform(image.Rect(0, 0, 1000, 2), color.NRGBA{A: 255}),
+			width:      10,
+			wantBounds: image.Rect(0, 0, 10, 1),
+			want:       color.RGBA{A: 255},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := thumbnail(tt.img, tt.width)
+			if got.Bounds() != tt.wantBounds {
+				t.Fatalf("bounds = %v, want %v", got.Bounds(), tt.wantBounds)
+			}
+			for _, p := range []image.Point{got.Bounds().Min, got.Bounds().Max.Sub(image.Pt(1, 1))} {
+				if c := got.RGBAAt(p.X, p.Y); c != tt.want {
+					t.Errorf("pixel %v = %v, want %v", p, c, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestThumbnailAverages(t *testing.T) {
+	// Alternating black and white columns average to grey instead of
+	// aliasing to either.
+	img := image.NewGray(image.Rect(0, 0, 100, 10))
+	for x := 0; x < 100; x += 2 {
+		for y := 0; y < 10; y++ {
+			img.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+	got := thumbnail(img, 10)
+	if c := got.RGBAAt(5, 0); c.R < 126 || c.R > 128 {
+		t.Errorf("pixel = %v, want grey", c)
+	}
+}
+
+func uniform(r image.Rectangle, c color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(r)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetNRGBA(x, y, c)
+		}
+	}
+	return img
+}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	// minRedialDelay and maxRedialDelay bound the backoff between attempts
+	// to reconnect to the broker.
+	minRedialDelay = time.Second
+	maxRedialDelay = 30 * time.Second
+)
+
+// Conn is an AMQP connection that is dialled again whenever the broker
+// closes it, for example on a restart. Channels opened on a closed
+// connection fail until Run has reconnected.
+type Conn struct {
+	url string
+
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	stopped bool
+}
+
+// Dial connects to the broker at url.
+func Dial(url string) (*Conn, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{url: url, conn: conn}, nil
+}
+
+// Run reconnects with backoff each time the connection is closed, until ctx
+// is cancelled or Close is called.
+func (c *Conn) Run(ctx context.Context) {
+	for {
+		c.mu.Lock()
+		closed := c.conn.NotifyClose(make(chan *amqp.Error, 1))
+		c.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-closed:
+			if c.isStopped() {
+				return
+			}
+			slog.Warn("AMQP connection closed; reconnecting", "error", err)
+		}
+
+		conn, ok := c.redial(ctx)
+		if !ok {
+			return
+		}
+		c.mu.Lock()
+		if c.stopped {
+			c.mu.Unlock()
+			conn.Close()
+			return
+		}
+		c.conn = conn
+		c.mu.Unlock()
+		slog.Info("Reconnected to AMQP")
+	}
+}
+
+// redial dials the broker until it succeeds or ctx is cancelled.
+func (c *Conn) redial(ctx context.Context) (*amqp.Connection, bool) {
+	delay := minRedialDelay
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-time.After(delay):
+		}
+		if c.isStopped() {
+			return nil, false
+		}
+		conn, err := amqp.Dial(c.url)
+		if err == nil {
+			return conn, true
+		}
+		slog.Error("Failed to reconnect to AMQP", "error", err, "retry_in", delay)
+		delay = min(2*delay, maxRedialDelay)
+	}
+}
+
+// Channel opens a channel on the current connection.
+func (c *Conn) Channel() (*amqp.Channel, error) {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	return conn.Channel()
+}
+
+// IsClosed reports whether the current connection is closed, that is
+// whether Run has yet to reconnect.
+func (c *Conn) IsClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.IsClosed()
+}
+
+// Close closes the connection and stops Run from reconnecting.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+	return c.conn.Close()
+}
+
+func (c *Conn) isStopped() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopped
+}
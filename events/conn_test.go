@@ -0,0 +1,196 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeBroker accepts AMQP connections, completes the connection handshake
+// and then leaves them idle. It only speaks the parts of AMQP 0-9-1 that
+// Dial and Close use.
+type fakeBroker struct {
+	listener net.Listener
+	conns    chan net.Conn
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{listener: listener, conns: make(chan net.Conn, 4)}
+	t.Cleanup(func() { listener.Close() })
+	go b.serve()
+	return b
+}
+
+func (b *fakeBroker) url() string {
+	return "amqp://guest:guest@" + b.listener.Addr().String() + "/"
+}
+
+func (b *fakeBroker) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn)
+	}
+}
+
+func (b *fakeBroker) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	// Protocol header.
+	if _, err := io.ReadFull(r, make([]byte, 8)); err != nil {
+		return
+	}
+
+	var start []byte
+	start = append(start, 0, 9)                     // version
+	start = binary.BigEndian.AppendUint32(start, 0) // server properties
+	start = appendLongString(start, "PLAIN")
+	start = appendLongString(start, "en_US")
+	writeMethod(conn, 10, 10, start) // connection.start
+
+	if _, _, err := readMethod(r); err != nil { // connection.start-ok
+		return
+	}
+	var tune []byte
+	tune = binary.BigEndian.AppendUint16(tune, 0)      // channel max
+	tune = binary.BigEndian.AppendUint32(tune, 131072) // frame max
+	tune = binary.BigEndian.AppendUint16(tune, 0)      // heartbeat
+
+	writeMethod(conn, 10, 30, tune) // connection.tune
+
+	for {
+		class, method, err := readMethod(r)
+		if err != nil {
+			return
+		}
+		switch {
+		case class == 10 && method == 40: // connection.open
+			writeMethod(conn, 10, 41, []byte{0})
+			b.conns <- conn
+		case class == 10 && method == 50: // connection.close
+			writeMethod(conn, 10, 51, nil)
+			return
+		}
+	}
+}
+
+func appendLongString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func writeMethod(w io.Writer, class, method uint16, args []byte) {
+	payload := binary.BigEndian.AppendUint16(nil, class)
+	payload = binary.BigEndian.AppendUint16(payload, method)
+	payload = append(payload, args...)
+
+	frame := []byte{1, 0, 0} // method frame on channel 0
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(payload)))
+	frame = append(frame, payload...)
+	frame = append(frame, 0xCE)
+	w.Write(frame)
+}
+
+// readMethod reads frames until a method frame and returns its class and
+// method ids.
+func readMethod(r io.Reader) (class, method uint16, err error) {
+	for {
+		header := make([]byte, 7)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return 0, 0, err
+		}
+		body := make([]byte, binary.BigEndian.Uint32(header[3:])+1)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return 0, 0, err
+		}
+		if header[0] == 1 && len(body) >= 5 {
+			return binary.BigEndian.Uint16(body), binary.BigEndian.Uint16(body[2:]), nil
+		}
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConnReconnects(t *testing.T) {
+	broker := newFakeBroker(t)
+	c, err := Dial(broker.url())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	first := <-broker.conns
+
+	done := make(chan struct{})
+	go func() {
+		c.Run(context.Background())
+		close(done)
+	}()
+
+	// The broker drops the connection, as it does on a restart.
+	first.Close()
+	waitFor(t, "the connection to close", c.IsClosed)
+
+	select {
+	case <-broker.conns:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run() did not reconnect")
+	}
+	waitFor(t, "the new connection", func() bool { return !c.IsClosed() })
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run() did not return after Close()")
+	}
+}
+
+func TestConnRunStopsOnCancel(t *testing.T) {
+	broker := newFakeBroker(t)
+	c, err := Dial(broker.url())
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer c.Close()
+	first := <-broker.conns
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+
+	// Cancelling while Run waits to redial stops it without reconnecting.
+	broker.listener.Close()
+	first.Close()
+	waitFor(t, "the connection to close", c.IsClosed)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run() did not return after ctx was cancelled")
+	}
+}
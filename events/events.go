@@ -0,0 +1,253 @@
+// Package events relays catalog events to RabbitMQ. Events are written to
+// the events table in the transaction of the change they describe, so that
+// none is lost and none is sent for a change that was rolled back. The Relay
+// publishes them afterwards, at least once.
+//
+// Events of one book are published in the order of its changes, which the
+// book's row lock serializes. Events of different books may be published out
+// of id order: ids are taken before commit, so an event with a lower id can
+// commit after one with a higher id has already been published. Consumers
+// must not rely on the order across books.
+package events
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/streadway/amqp"
+
+	"Booking/tracing"
+)
+
+const (
+	// batchSize is the number of events published per transaction.
+	batchSize = 100
+
+	// relayLockID is the advisory lock held by the one replica publishing,
+	// so that replicas do not publish the same events concurrently.
+	relayLockID = 0x6576656e7473
+)
+
+// Event is an event recorded in the events table.
+type Event struct {
+	ID        int64
+	Type      string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// Relay publishes recorded events to a topic exchange with their type as
+// the routing key.
+type Relay struct {
+	db       *tracing.DB
+	conn     *Conn
+	exchange string
+
+	ch       *amqp.Channel
+	confirms chan amqp.Confirmation
+}
+
+// NewRelay returns a Relay publishing to exchange over conn.
+func NewRelay(db *tracing.DB, conn *Conn, exchange string) *Relay {
+	return &Relay{db: db, conn: conn, exchange: exchange}
+}
+
+// Run publishes pending events every interval until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer r.closeChannel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.publishPending(ctx); err != nil && ctx.Err() == nil {
+				slog.Error("Failed to publish events", "error", err)
+				// The channel may be unusable after a failed publish.
+				r.closeChannel()
+			}
+		}
+	}
+}
+
+func (r *Relay) publishPending(ctx context.Context) error {
+	for {
+		n, err := r.publishBatch(ctx)
+		if err != nil || n < batchSize {
+			return err
+		}
+	}
+}
+
+// publishBatch publishes the oldest unpublished events and marks them
+// published. Events published before a failure are published again.
+func (r *Relay) publishBatch(ctx context.Context) (int, error) {
+	ch, err := r.channel()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, relayLockID).Scan(&locked); err != nil {
+		return 0, err
+	}
+	if !locked {
+		// Another replica is publishing.
+		return 0, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, type, payload, created_at
+		FROM events
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	`, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	var batch []Event
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Type, &e.Payload, &e.CreatedAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		batch = append(batch, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int64, 0, len(batch))
+	for _, e := range batch {
+		if err := r.publish(ctx, ch, e); err != nil {
+			return 0, fmt.Errorf("publish event %d: %w", e.ID, err)
+		}
+		ids = append(ids, e.ID)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE events SET published_at = now() WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+		return 0, err
+	}
+	return len(batch), tx.Commit()
+}
+
+// publish sends an event and waits for the broker to confirm it.
+func (r *Relay) publish(ctx context.Context, ch *amqp.Channel, e Event) error {
+	err := ch.Publish(r.exchange, e.Type, false, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    strconv.FormatInt(e.ID, 10),
+		Type:         e.Type,
+		Timestamp:    e.CreatedAt,
+		Body:         e.Payload,
+	})
+	if err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case confirm, ok := <-r.confirms:
+		if !ok {
+			return errors.New("channel closed")
+		}
+		if !confirm.Ack {
+			return errors.New("rejected by the broker")
+		}
+		return nil
+	}
+}
+
+// channel returns the publishing channel, opening it in confirm mode and
+// declaring the exchange first if needed.
+func (r *Relay) channel() (*amqp.Channel, error) {
+	if r.ch != nil {
+		return r.ch, nil
+	}
+	ch, err := r.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if err := ch.ExchangeDeclare(r.exchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		ch.Close()
+		return nil, err
+	}
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		return nil, err
+	}
+	r.confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	r.ch = ch
+	return ch, nil
+}
+
+func (r *Relay) closeChannel() {
+	if r.ch != nil {
+		r.ch.Close()
+		r.ch, r.confirms = nil, nil
+	}
+}
+
+// Cleanup deletes events older than retention every interval until ctx is
+// cancelled. When relayed, only published events are deleted: unpublished
+// ones are kept for the relay and logged as an error once they are older
+// than retention. Otherwise nothing publishes events and all are deleted.
+func Cleanup(ctx context.Context, db *tracing.DB, interval, retention time.Duration, relayed bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := cleanup(ctx, db, now.Add(-retention), relayed); err != nil {
+				slog.Error("Failed to delete old events", "error", err)
+			}
+		}
+	}
+}
+
+func cleanup(ctx context.Context, db *tracing.DB, before time.Time, relayed bool) error {
+	if !relayed {
+		_, err := db.ExecContext(ctx, `DELETE FROM events WHERE created_at < $1`, before)
+		return err
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM events WHERE created_at < $1 AND published_at IS NOT NULL`, before); err != nil {
+		return err
+	}
+
+	var stale int64
+	var oldest sql.NullTime
+	err := db.QueryRowContext(ctx, `
+		SELECT count(*), min(created_at)
+		FROM events
+		WHERE created_at < $1 AND published_at IS NULL
+	`, before).Scan(&stale, &oldest)
+	if err != nil {
+		return err
+	}
+	if stale > 0 {
+		slog.Error("Unpublished events are older than the retention", "count", stale, "oldest", oldest.Time)
+	}
+	return nil
+}
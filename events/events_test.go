@@ -0,0 +1,92 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"Booking/migrations"
+	"Booking/tracing"
+)
+
+// newTestDB returns the scratch database named by TEST_DATABASE_DSN, migrated
+// and without events, or skips the test when it is unset.
+func newTestDB(t *testing.T) *tracing.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+	if err := migrations.Apply(ctx, db); err != nil {
+		t.Fatalf("migrations.Apply() error = %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `TRUNCATE events RESTART IDENTITY`); err != nil {
+		t.Fatal(err)
+	}
+	return tracing.WrapDB(db)
+}
+
+func TestCleanup(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+	old := now.Add(-2 * time.Hour)
+
+	insert := `INSERT INTO events (type, payload, created_at, published_at) VALUES ('book.updated', '{}', $1, $2)`
+	for _, e := range []struct {
+		createdAt   time.Time
+		publishedAt interface{}
+	}{
+		{old, old}, // 1: old and published
+		{old, nil}, // 2: old, not published yet
+		{now, now}, // 3: recent and published
+		{now, nil}, // 4: recent, not published yet
+	} {
+		if _, err := db.ExecContext(ctx, insert, e.createdAt, e.publishedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		relayed bool
+		want    []int64
+	}{
+		{"relayed", true, []int64{2, 3, 4}},
+		{"not relayed", false, []int64{3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := cleanup(ctx, db, now.Add(-time.Hour), tt.relayed); err != nil {
+				t.Fatalf("cleanup() error = %v", err)
+			}
+			rows, err := db.QueryContext(ctx, `SELECT id FROM events ORDER BY id`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			var got []int64
+			for rows.Next() {
+				var id int64
+				if err := rows.Scan(&id); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, id)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("events left = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
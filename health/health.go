@@ -0,0 +1,225 @@
+// Package health tracks the readiness of the book service and exposes it
+// through the grpc.health.v1 Health service and the /healthz and /readyz
+// gateway endpoints.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"Booking/migrations"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// checkTimeout bounds a single round of readiness checks.
+const checkTimeout = 3 * time.Second
+
+// Check reports whether a dependency is usable. A nil error means healthy.
+type Check func(ctx context.Context) error
+
+// Checker runs the registered readiness checks and publishes the result.
+type Checker struct {
+	grpc     *health.Server
+	services []string
+
+	mu     sync.RWMutex
+	checks map[string]Check
+
+	shuttingDown atomic.Bool
+}
+
+// NewChecker returns a Checker that reports the status of the given gRPC
+// services, as well as the overall server status (the empty service name).
+func NewChecker(services ...string) *Checker {
+	c := &Checker{
+		grpc:     health.NewServer(),
+		services: append([]string{""}, services...),
+		checks:   make(map[string]Check),
+	}
+	c.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	return c
+}
+
+// Server returns the grpc.health.v1 Health implementation to register on
+// the gRPC server.
+func (c *Checker) Server() healthpb.HealthServer {
+	return c.grpc
+}
+
+// Add registers a named readiness check.
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks[name] = check
+}
+
+// Evaluate runs all checks and returns the failures keyed by check name.
+func (c *Checker) Evaluate(ctx context.Context) map[string]error {
+	c.mu.RLock()
+	checks := make(map[string]Check, len(c.checks))
+	for name, check := range c.checks {
+		checks[name] = check
+	}
+	c.mu.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		failures = make(map[string]error)
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			if err := check(ctx); err != nil {
+				mu.Lock()
+				failures[name] = err
+				mu.Unlock()
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return failures
+}
+
+// Run re-evaluates the checks every interval and updates the gRPC serving
+// status until ctx is cancelled.
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Checker) refresh(ctx context.Context) {
+	if c.shuttingDown.Load() {
+		return
+	}
+	failures := c.Evaluate(ctx)
+	if len(failures) == 0 {
+		c.setServingStatus(healthpb.HealthCheckResponse_SERVING)
+		return
+	}
+	for name, err := range failures {
+		slog.Warn("Readiness check failed", "check", name, "error", err)
+	}
+	c.setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+func (c *Checker) setServingStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	for _, service := range c.services {
+		c.grpc.SetServingStatus(service, status)
+	}
+}
+
+// Shutdown marks the service as not serving for the rest of its lifetime,
+// so that load balancers stop routing traffic to it while it drains.
+func (c *Checker) Shutdown() {
+	c.shuttingDown.Store(true)
+	c.grpc.Shutdown()
+}
+
+// LivenessHandler serves /healthz. It only reports that the process is up.
+func (c *Checker) LivenessHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+type readinessResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// ReadinessHandler serves /readyz. It runs every check and responds with 503
+// if any of them fails or the server is shutting down.
+func (c *Checker) ReadinessHandler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	if c.shuttingDown.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, readinessResponse{Status: "shutting down"})
+		return
+	}
+
+	failures := c.Evaluate(r.Context())
+
+	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	c.mu.RUnlock()
+	sort.Strings(names)
+
+	resp := readinessResponse{Status: "ok", Checks: make(map[string]string, len(names))}
+	code := http.StatusOK
+	for _, name := range names {
+		if err, failed := failures[name]; failed {
+			resp.Checks[name] = err.Error()
+			resp.Status = "unavailable"
+			code = http.StatusServiceUnavailable
+			continue
+		}
+		resp.Checks[name] = "ok"
+	}
+	writeJSON(w, code, resp)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to write health response", "error", err)
+	}
+}
+
+// DBCheck reports whether the database accepts connections.
+func DBCheck(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// MigrationCheck reports whether all schema migrations have been applied.
+func MigrationCheck(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		pending, err := migrations.Pending(ctx, db)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("pending migrations: %s", strings.Join(pending, ", "))
+		}
+		return nil
+	}
+}
+
+// AMQPCheck reports whether the AMQP connection is open. It fails while a
+// reconnecting connection is down.
+func AMQPCheck(conn interface{ IsClosed() bool }) Check {
+	return func(ctx context.Context) error {
+		if conn.IsClosed() {
+			return errors.New("amqp connection is closed")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,115 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const testService = "booking.BookingService"
+
+func servingStatus(t *testing.T, c *Checker, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	resp, err := c.Server().Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		t.Fatalf("Check(%q) error = %v", service, err)
+	}
+	return resp.GetStatus()
+}
+
+func TestRefresh(t *testing.T) {
+	c := NewChecker(testService)
+	if got := servingStatus(t, c, ""); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("status before the first check = %v, want NOT_SERVING", got)
+	}
+
+	var dbErr error
+	c.Add("db", func(ctx context.Context) error { return dbErr })
+	c.Add("amqp", func(ctx context.Context) error { return nil })
+
+	c.refresh(context.Background())
+	for _, service := range []string{"", testService} {
+		if got := servingStatus(t, c, service); got != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("status of %q = %v, want SERVING", service, got)
+		}
+	}
+
+	dbErr = errors.New("connection refused")
+	c.refresh(context.Background())
+	if got := servingStatus(t, c, testService); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("status with a failing check = %v, want NOT_SERVING", got)
+	}
+	failures := c.Evaluate(context.Background())
+	if len(failures) != 1 || failures["db"] != dbErr {
+		t.Errorf("Evaluate() = %v, want only the db failure", failures)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	c := NewChecker(testService)
+	c.refresh(context.Background())
+	c.Shutdown()
+
+	// Checks passing after Shutdown must not bring the service back.
+	c.refresh(context.Background())
+	if got := servingStatus(t, c, testService); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("status after Shutdown() = %v, want NOT_SERVING", got)
+	}
+
+	rec := httptest.NewRecorder()
+	c.ReadinessHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil), nil)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz after Shutdown() = %d, want 503", rec.Code)
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	c := NewChecker()
+	c.Add("db", func(ctx context.Context) error { return nil })
+
+	get := func() (int, readinessResponse) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		c.ReadinessHandler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil), nil)
+		if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+			t.Errorf("Cache-Control = %q, want no-store", got)
+		}
+		var resp readinessResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid /readyz body %q: %v", rec.Body, err)
+		}
+		return rec.Code, resp
+	}
+
+	code, resp := get()
+	if code != http.StatusOK || resp.Status != "ok" || resp.Checks["db"] != "ok" {
+		t.Errorf("/readyz = %d %+v, want 200 with db ok", code, resp)
+	}
+
+	c.Add("amqp", AMQPCheck(closedConn(true)))
+	code, resp = get()
+	if code != http.StatusServiceUnavailable || resp.Status != "unavailable" ||
+		resp.Checks["db"] != "ok" || resp.Checks["amqp"] != "amqp connection is closed" {
+		t.Errorf("/readyz = %d %+v, want 503 naming the amqp check", code, resp)
+	}
+}
+
+func TestLivenessHandler(t *testing.T) {
+	c := NewChecker()
+	c.Add("db", func(ctx context.Context) error { return errors.New("down") })
+
+	rec := httptest.NewRecorder()
+	c.LivenessHandler(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil), nil)
+	if rec.Code != http.StatusOK {
+		t.Errorf("/healthz = %d, want 200 regardless of readiness", rec.Code)
+	}
+}
+
+type closedConn bool
+
+func (c closedConn) IsClosed() bool { return bool(c) }
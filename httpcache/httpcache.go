@@ -0,0 +1,176 @@
+// Package httpcache adds HTTP caching semantics to the gRPC-Gateway:
+// validators (ETag and Last-Modified) derived from the update time of
+// returned resources, conditional GET with 304 responses, and Cache-Control
+// headers per RPC. Responses vary with Accept-Language, since books are
+// returned in the caller's locale.
+package httpcache
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// noStore is sent on responses to writes so that no cache keeps them.
+const noStore = "no-store"
+
+// versioned is implemented by resources that record their last change.
+type versioned interface {
+	GetUpdatedAt() *timestamppb.Timestamp
+}
+
+type idGetter interface {
+	GetId() int64
+}
+
+// localized is implemented by resources returned in one of several locales.
+type localized interface {
+	GetLocale() string
+}
+
+// ETag returns the entity tag of the version of resource id last changed at
+// updatedAt.
+func ETag(id int64, updatedAt time.Time) string {
+	return `"` + strconv.FormatInt(id, 36) + "-" + strconv.FormatInt(updatedAt.UnixNano(), 36) + `"`
+}
+
+// Policy sets the caching headers of gateway responses.
+type Policy struct {
+	cacheControl map[string]string
+}
+
+// NewPolicy returns a Policy that sends cacheControl[name] as the
+// Cache-Control header of successful responses of the RPC name, such as
+// "ReadBook".
+func NewPolicy(cacheControl map[string]string) *Policy {
+	return &Policy{cacheControl: cacheControl}
+}
+
+func methodName(fullMethod string) string {
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[i+1:]
+	}
+	return fullMethod
+}
+
+// ForwardResponse sets the validators and Cache-Control header of a
+// successful response. It is meant for runtime.WithForwardResponseOption.
+func (p *Policy) ForwardResponse(ctx context.Context, w http.ResponseWriter, resp proto.Message) error {
+	if v, ok := resp.(versioned); ok && v.GetUpdatedAt() != nil {
+		updatedAt := v.GetUpdatedAt().AsTime()
+		id := int64(0)
+		if r, ok := resp.(idGetter); ok {
+			id = r.GetId()
+		}
+		etag := ETag(id, updatedAt)
+		if l, ok := resp.(localized); ok && l.GetLocale() != "" {
+			etag = strings.TrimSuffix(etag, `"`) + "-" + l.GetLocale() + `"`
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+	}
+
+	w.Header().Add("Vary", "Accept-Language")
+	if fullMethod, ok := runtime.RPCMethod(ctx); ok {
+		if cc := p.cacheControl[methodName(fullMethod)]; cc != "" {
+			w.Header().Set("Cache-Control", cc)
+		}
+	}
+	return nil
+}
+
+// Middleware answers conditional GET requests whose validators match the
+// response with 304 Not Modified, and marks responses to writes as not
+// storable.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Cache-Control", noStore)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Header.Get("If-None-Match") == "" && r.Header.Get("If-Modified-Since") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(&conditionalWriter{ResponseWriter: w, req: r}, r)
+	})
+}
+
+// conditionalWriter replaces a 200 response with 304 Not Modified when the
+// request preconditions say the client already has it.
+type conditionalWriter struct {
+	http.ResponseWriter
+	req *http.Request
+
+	decided     bool
+	notModified bool
+}
+
+func (w *conditionalWriter) decide(code int) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	if code != http.StatusOK || !notModified(w.req, w.Header()) {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	w.notModified = true
+	h := w.Header()
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	h.Del("Transfer-Encoding")
+	w.ResponseWriter.WriteHeader(http.StatusNotModified)
+}
+
+func (w *conditionalWriter) WriteHeader(code int) {
+	w.decide(code)
+}
+
+func (w *conditionalWriter) Write(b []byte) (int, error) {
+	w.decide(http.StatusOK)
+	if w.notModified {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *conditionalWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// notModified evaluates If-None-Match and, in its absence,
+// If-Modified-Since against the response validators, as in RFC 9110.
+func notModified(r *http.Request, h http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := h.Get("ETag")
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(h.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(ims)
+}
@@ -0,0 +1,129 @@
+package httpcache
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	lastModified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	etag := ETag(42, lastModified)
+	response := http.Header{}
+	response.Set("ETag", etag)
+	response.Set("Last-Modified", lastModified.Format(http.TimeFormat))
+
+	tests := []struct {
+		name    string
+		request map[string]string
+		want    bool
+	}{
+		{"matching etag", map[string]string{"If-None-Match": etag}, true},
+		{"weak matching etag", map[string]string{"If-None-Match": "W/" + etag}, true},
+		{"etag in a list", map[string]string{"If-None-Match": `"other", ` + etag}, true},
+		{"any etag", map[string]string{"If-None-Match": "*"}, true},
+		{"other etag", map[string]string{"If-None-Match": `"other"`}, false},
+		{"not modified since", map[string]string{"If-Modified-Since": lastModified.Format(http.TimeFormat)}, true},
+		{"modified since", map[string]string{"If-Modified-Since": lastModified.Add(-time.Second).Format(http.TimeFormat)}, false},
+		{"invalid date", map[string]string{"If-Modified-Since": "yesterday"}, false},
+		{"etag takes precedence over date", map[string]string{
+			"If-None-Match":     `"other"`,
+			"If-Modified-Since": lastModified.Format(http.TimeFormat),
+		}, false},
+		{"no preconditions", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/books/42", nil)
+			for k, v := range tt.request {
+				r.Header.Set(k, v)
+			}
+			if got := notModified(r, response); got != tt.want {
+				t.Errorf("notModified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotModifiedWithoutValidators(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/books/42", nil)
+	r.Header.Set("If-None-Match", "*")
+	if notModified(r, http.Header{}) {
+		t.Error("notModified() = true for a response without an ETag")
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/books/42", nil)
+	r.Header.Set("If-Modified-Since", time.Now().Format(http.TimeFormat))
+	if notModified(r, http.Header{}) {
+		t.Error("notModified() = true for a response without Last-Modified")
+	}
+}
+
+func TestETagChangesWithUpdateTime(t *testing.T) {
+	updatedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	if ETag(1, updatedAt) == ETag(1, updatedAt.Add(time.Nanosecond)) {
+		t.Error("ETag() is the same for different update times")
+	}
+	if ETag(1, updatedAt) == ETag(2, updatedAt) {
+		t.Error("ETag() is the same for different resources")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	lastModified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	etag := ETag(42, lastModified)
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", etag)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = io.WriteString(w, `{"id":"42"}`)
+	}))
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		ifNoneMatch  string
+		wantCode     int
+		wantBody     string
+		cacheControl string
+	}{
+		{"unconditional", http.MethodGet, "/books/42", "", http.StatusOK, `{"id":"42"}`, ""},
+		{"matching", http.MethodGet, "/books/42", etag, http.StatusNotModified, "", ""},
+		{"stale", http.MethodGet, "/books/42", `"old"`, http.StatusOK, `{"id":"42"}`, ""},
+		{"error", http.MethodGet, "/missing", etag, http.StatusNotFound, `{"id":"42"}`, ""},
+		{"write", http.MethodPost, "/books", etag, http.StatusOK, `{"id":"42"}`, noStore},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantCode)
+			}
+			if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+			if got := w.Header().Get("Cache-Control"); got != tt.cacheControl {
+				t.Errorf("Cache-Control = %q, want %q", got, tt.cacheControl)
+			}
+			if tt.wantCode == http.StatusNotModified {
+				if got := w.Header().Get("Content-Type"); got != "" {
+					t.Errorf("Content-Type = %q on a 304 response", got)
+				}
+				if got := w.Header().Get("ETag"); got != etag {
+					t.Errorf("ETag = %q on a 304 response, want %q", got, etag)
+				}
+			}
+		})
+	}
+}
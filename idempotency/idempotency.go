@@ -0,0 +1,258 @@
+// Package idempotency makes retried write RPCs safe. A client sends the same
+// idempotency key with every attempt of a request; the first attempt runs
+// and its response is stored, later attempts get the stored response.
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"Booking/auth"
+	"Booking/tracing"
+)
+
+const (
+	// MetadataKey is the gRPC metadata key, and HTTP header, carrying the
+	// idempotency key.
+	MetadataKey = "idempotency-key"
+	// ReplayedMetadataKey is set on responses that were replayed from an
+	// earlier attempt.
+	ReplayedMetadataKey = "idempotent-replayed"
+
+	maxKeyLength = 255
+)
+
+// Store keeps the outcome of requests in the idempotency_keys table.
+type Store struct {
+	db           *tracing.DB
+	ttl          time.Duration
+	claimTimeout time.Duration
+}
+
+// NewStore returns a Store that remembers requests for ttl. A key whose
+// first attempt has not finished after claimTimeout can be claimed again,
+// so that a crashed replica does not block retries until the key expires.
+func NewStore(db *tracing.DB, ttl, claimTimeout time.Duration) *Store {
+	return &Store{db: db, ttl: ttl, claimTimeout: claimTimeout}
+}
+
+// record is a stored request. response is nil while the first attempt is
+// still running.
+type record struct {
+	method       string
+	fingerprint  []byte
+	responseType string
+	response     []byte
+}
+
+func fingerprint(method string, req interface{}) ([]byte, error) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("request of %s is not a proto message", method)
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(append([]byte(method+"\x00"), data...))
+	return sum[:], nil
+}
+
+// claim reserves key for a new request and returns the time of the claim,
+// which tells it apart from a later claim of the same key. It returns the
+// stored record instead when the key is already in use.
+func (s *Store) claim(ctx context.Context, scope, key string, rec *record) (claimedAt time.Time, existing *record, err error) {
+	// Expired keys, and claims whose attempt has not finished in time, are
+	// released first so that they can be claimed again.
+	_, err = s.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE scope = $1 AND key = $2
+		  AND (expires_at < now() OR (response IS NULL AND claimed_at < now() - make_interval(secs => $3)))
+	`, scope, key, s.claimTimeout.Seconds())
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (scope, key, method, fingerprint, expires_at, claimed_at)
+		VALUES ($1, $2, $3, $4, now() + make_interval(secs => $5), now())
+		ON CONFLICT (scope, key) DO NOTHING
+		RETURNING claimed_at
+	`, scope, key, rec.method, rec.fingerprint, s.ttl.Seconds()).Scan(&claimedAt)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return claimedAt, nil, err
+	}
+
+	existing = &record{}
+	var responseType sql.NullString
+	err = s.db.QueryRowContext(ctx, `
+		SELECT method, fingerprint, response_type, response
+		FROM idempotency_keys
+		WHERE scope = $1 AND key = $2
+	`, scope, key).Scan(&existing.method, &existing.fingerprint, &responseType, &existing.response)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Released in the meantime by a failed first attempt.
+		return s.claim(ctx, scope, key, rec)
+	}
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+	existing.responseType = responseType.String
+	return time.Time{}, existing, nil
+}
+
+// complete stores the response of the attempt that claimed key at
+// claimedAt. Nothing is stored if the claim was taken over in the meantime.
+func (s *Store) complete(ctx context.Context, scope, key string, claimedAt time.Time, resp proto.Message) error {
+	data, err := proto.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET response_type = $4, response = $5
+		WHERE scope = $1 AND key = $2 AND claimed_at = $3
+	`, scope, key, claimedAt, string(resp.ProtoReflect().Descriptor().FullName()), data)
+	return err
+}
+
+// release forgets the claim of key made at claimedAt after a failed
+// attempt, which had no effect, so that the request can be retried.
+func (s *Store) release(ctx context.Context, scope, key string, claimedAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND claimed_at = $3
+	`, scope, key, claimedAt)
+	return err
+}
+
+// Cleanup deletes expired keys every interval until ctx is cancelled.
+func (s *Store) Cleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at < now()`); err != nil {
+				slog.Error("Failed to delete expired idempotency keys", "error", err)
+			}
+		}
+	}
+}
+
+func keyFromContext(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", nil
+	}
+	keys := md.Get(MetadataKey)
+	if len(keys) == 0 {
+		return "", nil
+	}
+	key := strings.TrimSpace(keys[0])
+	if key == "" || len(key) > maxKeyLength {
+		return "", status.Errorf(codes.InvalidArgument, "%s must be 1 to %d characters", MetadataKey, maxKeyLength)
+	}
+	return key, nil
+}
+
+// scope keeps the keys of different callers apart.
+func scope(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		return principal.Subject
+	}
+	return ""
+}
+
+func replay(rec *record) (proto.Message, error) {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(rec.responseType))
+	if err != nil {
+		return nil, err
+	}
+	resp := mt.New().Interface()
+	if err := proto.Unmarshal(rec.response, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UnaryServerInterceptor applies idempotency keys to the given methods,
+// identified by full method name. It must run after the auth interceptor
+// so that keys are scoped to the caller.
+func (s *Store) UnaryServerInterceptor(methods map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !methods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		key, err := keyFromContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if key == "" {
+			return handler(ctx, req)
+		}
+
+		fp, err := fingerprint(info.FullMethod, req)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		sc := scope(ctx)
+		claimedAt, existing, err := s.claim(ctx, sc, key, &record{method: info.FullMethod, fingerprint: fp})
+		if err != nil {
+			slog.Error("Failed to claim idempotency key", "error", err)
+			return nil, status.Error(codes.Internal, "failed to check idempotency key")
+		}
+
+		if existing != nil {
+			if existing.method != info.FullMethod || string(existing.fingerprint) != string(fp) {
+				return nil, status.Errorf(codes.InvalidArgument, "%s was already used for a different request", MetadataKey)
+			}
+			if existing.response == nil {
+				return nil, status.Errorf(codes.Aborted, "a request with this %s is still in progress", MetadataKey)
+			}
+			resp, err := replay(existing)
+			if err != nil {
+				slog.Error("Failed to replay idempotent response", "error", err)
+				return nil, status.Error(codes.Internal, "failed to replay stored response")
+			}
+			if err := grpc.SetHeader(ctx, metadata.Pairs(ReplayedMetadataKey, "true")); err != nil {
+				slog.Debug("Failed to set idempotent replay header", "error", err)
+			}
+			return resp, nil
+		}
+
+		resp, err := handler(ctx, req)
+		// The outcome is stored even if the caller has gone away, since that
+		// is exactly when it will retry.
+		storeCtx := context.WithoutCancel(ctx)
+		if err != nil {
+			if releaseErr := s.release(storeCtx, sc, key, claimedAt); releaseErr != nil {
+				slog.Error("Failed to release idempotency key", "error", releaseErr)
+			}
+			return nil, err
+		}
+		if msg, ok := resp.(proto.Message); ok {
+			if err := s.complete(storeCtx, sc, key, claimedAt, msg); err != nil {
+				slog.Error("Failed to store idempotent response", "error", err)
+			}
+		}
+		return resp, nil
+	}
+}
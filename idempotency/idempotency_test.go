@@ -0,0 +1,143 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"Booking/auth"
+)
+
+func TestFingerprint(t *testing.T) {
+	const method = "/booking.BookingService/CreateBook"
+	req := func(fields map[string]interface{}) proto.Message {
+		s, err := structpb.NewStruct(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	base := map[string]interface{}{"title": "Dune", "author": "Herbert", "year": 1965}
+
+	fp := func(method string, msg interface{}) []byte {
+		t.Helper()
+		sum, err := fingerprint(method, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sum
+	}
+
+	first := fp(method, req(base))
+	// Maps are marshalled in a stable order, so equal requests always
+	// match.
+	for i := 0; i < 20; i++ {
+		if !bytes.Equal(fp(method, req(base)), first) {
+			t.Fatal("fingerprint() differs for equal requests")
+		}
+	}
+	if bytes.Equal(fp("/booking.BookingService/UpdateBook", req(base)), first) {
+		t.Error("fingerprint() is the same for another method")
+	}
+	if bytes.Equal(fp(method, req(map[string]interface{}{"title": "Dune", "author": "Herbert", "year": 1966})), first) {
+		t.Error("fingerprint() is the same for another request")
+	}
+	if _, err := fingerprint(method, "not a message"); err == nil {
+		t.Error("fingerprint() accepted a request that is not a proto message")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	original := wrapperspb.String("created")
+	data, err := proto.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := replay(&record{
+		responseType: string(original.ProtoReflect().Descriptor().FullName()),
+		response:     data,
+	})
+	if err != nil {
+		t.Fatalf("replay() error = %v", err)
+	}
+	if !proto.Equal(resp, original) {
+		t.Errorf("replay() = %v, want %v", resp, original)
+	}
+
+	if _, err := replay(&record{responseType: "booking.NoSuchMessage", response: data}); err == nil {
+		t.Error("replay() of an unknown type succeeded")
+	}
+}
+
+func TestKeyFromContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    string
+		invalid bool
+	}{
+		{"absent", nil, "", false},
+		{"present", []string{"abc"}, "abc", false},
+		{"trimmed", []string{"  abc "}, "abc", false},
+		{"first of several", []string{"abc", "def"}, "abc", false},
+		{"blank", []string{"   "}, "", true},
+		{"too long", []string{strings.Repeat("k", maxKeyLength+1)}, "", true},
+		{"longest", []string{strings.Repeat("k", maxKeyLength)}, strings.Repeat("k", maxKeyLength), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := metadata.MD{}
+			md.Append(MetadataKey, tt.values...)
+			got, err := keyFromContext(metadata.NewIncomingContext(context.Background(), md))
+			if tt.invalid {
+				if status.Code(err) != codes.InvalidArgument {
+					t.Errorf("keyFromContext() error = %v, want InvalidArgument", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("keyFromContext() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScope(t *testing.T) {
+	if got := scope(context.Background()); got != "" {
+		t.Errorf("scope() of an anonymous caller = %q, want empty", got)
+	}
+	ctx := auth.ContextWithPrincipal(context.Background(), &auth.Principal{Subject: "user-1"})
+	if got := scope(ctx); got != "user-1" {
+		t.Errorf("scope() = %q, want %q", got, "user-1")
+	}
+}
+
+func TestInterceptorSkipsRequestsWithoutKey(t *testing.T) {
+	// No key means no store access, so a Store without a database works.
+	s := &Store{}
+	interceptor := s.UnaryServerInterceptor(map[string]bool{"/svc/Write": true})
+	calls := 0
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		calls++
+		return wrapperspb.String("ok"), nil
+	}
+
+	for _, method := range []string{"/svc/Write", "/svc/Read"} {
+		if _, err := interceptor(context.Background(), wrapperspb.String("req"), &grpc.UnaryServerInfo{FullMethod: method}, handler); err != nil {
+			t.Fatalf("%s: %v", method, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,81 @@
+// Package locale picks the locale of localized content from the language
+// preferences of a caller.
+package locale
+
+import (
+	"context"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc/metadata"
+)
+
+// MetadataKey is the gRPC metadata key carrying language preferences in
+// Accept-Language syntax. The gRPC-Gateway forwards the Accept-Language
+// header under its own prefix.
+const MetadataKey = "accept-language"
+
+var gatewayMetadataKey = runtime.MetadataPrefix + MetadataKey
+
+var wildcard = language.MustParse("mul")
+
+// Canonical returns the canonical form of a BCP 47 tag, such as "kk" or
+// "en-US".
+func Canonical(tag string) (string, error) {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return "", err
+	}
+	return t.String(), nil
+}
+
+// Preferences returns the locales a caller asked for, most preferred first.
+// requested, in Accept-Language syntax, takes precedence over the
+// Accept-Language metadata of ctx. Malformed preferences are ignored.
+func Preferences(ctx context.Context, requested string) []language.Tag {
+	if requested == "" {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			for _, key := range []string{MetadataKey, gatewayMetadataKey} {
+				if values := md.Get(key); len(values) > 0 {
+					requested = values[0]
+					break
+				}
+			}
+		}
+	}
+	if requested == "" {
+		return nil
+	}
+	tags, _, err := language.ParseAcceptLanguage(requested)
+	if err != nil {
+		return nil
+	}
+	return tags
+}
+
+// Chain returns the locales to try in order: each preference followed by
+// its parents, such as "kk" after "kk-KZ", and then fallback.
+func Chain(preferences []language.Tag, fallback []string) []string {
+	seen := make(map[string]bool)
+	var chain []string
+	add := func(locale string) {
+		if locale != "" && !seen[locale] {
+			seen[locale] = true
+			chain = append(chain, locale)
+		}
+	}
+
+	for _, tag := range preferences {
+		// The "*" wildcard, parsed as "mul", matches nothing in particular.
+		if tag == wildcard {
+			continue
+		}
+		for t := tag; t != language.Und; t = t.Parent() {
+			add(t.String())
+		}
+	}
+	for _, locale := range fallback {
+		add(locale)
+	}
+	return chain
+}
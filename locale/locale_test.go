@@ -0,0 +1,80 @@
+package locale
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/language"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestChain(t *testing.T) {
+	tests := []struct {
+		name        string
+		preferences string
+		fallback    []string
+		want        []string
+	}{
+		{"no preferences", "", []string{"en"}, []string{"en"}},
+		{"language", "kk", []string{"en"}, []string{"kk", "en"}},
+		{"region falls back to its language", "kk-KZ", []string{"en"}, []string{"kk-KZ", "kk", "en"}},
+		{"order of preference", "ru;q=0.5, kk-KZ", []string{"en"}, []string{"kk-KZ", "kk", "ru", "en"}},
+		{"duplicates dropped", "en-US, en", []string{"en"}, []string{"en-US", "en"}},
+		{"wildcard ignored", "*, kk", []string{"en"}, []string{"kk", "en"}},
+		{"fallback list", "de", []string{"ru", "en"}, []string{"de", "ru", "en"}},
+		{"no fallback", "de", nil, []string{"de"}},
+		{"malformed preferences ignored", "not a locale!!", []string{"en"}, []string{"en"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Chain(Preferences(context.Background(), tt.preferences), tt.fallback)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Chain(%q, %q) = %q, want %q", tt.preferences, tt.fallback, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreferences(t *testing.T) {
+	md := metadata.Pairs(MetadataKey, "kk")
+	gatewayMD := metadata.Pairs(gatewayMetadataKey, "ru")
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		requested string
+		want      []language.Tag
+	}{
+		{"none", context.Background(), "", nil},
+		{"metadata", metadata.NewIncomingContext(context.Background(), md), "", []language.Tag{language.Kazakh}},
+		{"gateway header", metadata.NewIncomingContext(context.Background(), gatewayMD), "", []language.Tag{language.Russian}},
+		{"request over metadata", metadata.NewIncomingContext(context.Background(), md), "de", []language.Tag{language.German}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Preferences(tt.ctx, tt.requested); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Preferences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    string
+		wantErr bool
+	}{
+		{"kk", "kk", false},
+		{"en-us", "en-US", false},
+		{"EN_us", "en-US", false},
+		{"!!", "", true},
+	}
+	for _, tt := range tests {
+		got, err := Canonical(tt.tag)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("Canonical(%q) = %q, %v, want %q", tt.tag, got, err, tt.want)
+		}
+	}
+}
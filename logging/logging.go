@@ -0,0 +1,107 @@
+// Package logging sets up structured JSON logging with log/slog and carries
+// a request-scoped logger through contexts.
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"Booking/config"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys lists attribute keys whose values are never written to logs.
+var sensitiveKeys = map[string]bool{
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+	"authorization": true,
+	"api_key":       true,
+	"x-api-key":     true,
+	"cookie":        true,
+}
+
+// Setup installs a JSON or text slog handler as the default logger and
+// returns the level variable, which can be changed at runtime.
+func Setup(cfg config.LoggingConfig) (*slog.LevelVar, error) {
+	level := new(slog.LevelVar)
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q: %w", cfg.Level, err)
+	}
+
+	slog.SetDefault(slog.New(newHandler(os.Stdout, cfg.Format, level)))
+	return level, nil
+}
+
+func newHandler(w io.Writer, format string, level slog.Leveler) slog.Handler {
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	}
+	if format == "text" {
+		return slog.NewTextHandler(w, opts)
+	}
+	return slog.NewJSONHandler(w, opts)
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
+
+type loggerKey struct{}
+
+// WithLogger returns a copy of ctx that carries logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With adds attributes to the logger carried by ctx.
+func With(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
+
+type levelRequest struct {
+	Level string `json:"level"`
+}
+
+// LevelHandler reports the current log level on GET and changes it on PUT
+// with a body such as {"level":"debug"}.
+func LevelHandler(level *slog.LevelVar) func(http.ResponseWriter, *http.Request, map[string]string) {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		if r.Method == http.MethodPut {
+			var req levelRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			var newLevel slog.Level
+			if err := newLevel.UnmarshalText([]byte(req.Level)); err != nil {
+				http.Error(w, "invalid log level", http.StatusBadRequest)
+				return
+			}
+			level.Set(newLevel)
+			slog.Info("Log level changed", "level", newLevel.String())
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(levelRequest{Level: level.Level().String()})
+	}
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(newHandler(&buf, "json", slog.LevelInfo))
+	logger.Info("Request", "Authorization", "Bearer abc", "api_key", "bk_1_2", "path", "/v1/books")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid log line %q: %v", buf.String(), err)
+	}
+	for _, key := range []string{"Authorization", "api_key"} {
+		if entry[key] != redacted {
+			t.Errorf("%s = %v, want %s", key, entry[key], redacted)
+		}
+	}
+	if entry["path"] != "/v1/books" {
+		t.Errorf("path = %v, want /v1/books", entry["path"])
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("FromContext() without a logger is not the default logger")
+	}
+
+	var buf bytes.Buffer
+	ctx := WithLogger(context.Background(), slog.New(newHandler(&buf, "json", slog.LevelInfo)))
+	ctx = With(ctx, "book_id", 7)
+	FromContext(ctx).Info("Book read")
+	if !strings.Contains(buf.String(), `"book_id":7`) {
+		t.Errorf("log line %q lacks the book_id added with With()", buf.String())
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	level := new(slog.LevelVar)
+	handler := LevelHandler(level)
+
+	tests := []struct {
+		method string
+		body   string
+		code   int
+		want   slog.Level
+	}{
+		{http.MethodGet, "", http.StatusOK, slog.LevelInfo},
+		{http.MethodPut, `{"level":"debug"}`, http.StatusOK, slog.LevelDebug},
+		{http.MethodPut, `{"level":"loud"}`, http.StatusBadRequest, slog.LevelDebug},
+		{http.MethodPut, `not json`, http.StatusBadRequest, slog.LevelDebug},
+		{http.MethodPut, `{"level":"WARN"}`, http.StatusOK, slog.LevelWarn},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(tt.method, "/admin/log-level", strings.NewReader(tt.body)), nil)
+		if rec.Code != tt.code {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.body, rec.Code, tt.code)
+		}
+		if level.Level() != tt.want {
+			t.Errorf("after %s %s level = %v, want %v", tt.method, tt.body, level.Level(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// RequestIDHeader is the HTTP header carrying the request id.
+	RequestIDHeader = "X-Request-Id"
+	// RequestIDMetadataKey is the gRPC metadata key carrying the request id.
+	RequestIDMetadataKey = "x-request-id"
+
+	maxRequestIDLength = 128
+)
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// HTTPMiddleware takes the request id from the X-Request-Id header, or
+// generates one, echoes it in the response and logs every gateway request.
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		r.Header.Set(RequestIDHeader, requestID)
+		w.Header().Set(RequestIDHeader, requestID)
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		slog.LogAttrs(r.Context(), levelForHTTPStatus(rec.status), "HTTP request completed",
+			slog.String("request_id", requestID),
+			slog.String("http_method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+		)
+	})
+}
+
+// GatewayMetadata forwards the request id set by HTTPMiddleware to the gRPC
+// server. It is meant for runtime.WithMetadata.
+func GatewayMetadata(_ context.Context, r *http.Request) metadata.MD {
+	return metadata.Pairs(RequestIDMetadataKey, r.Header.Get(RequestIDHeader))
+}
+
+func levelForHTTPStatus(code int) slog.Level {
+	switch {
+	case code >= 500:
+		return slog.LevelError
+	case code >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// idGetter is implemented by requests and responses that refer to a book.
+type idGetter interface {
+	GetId() int64
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDMetadataKey); len(ids) > 0 && validRequestID(ids[0]) {
+			return ids[0]
+		}
+	}
+	return newRequestID()
+}
+
+// newRPCLogger returns the logger for an RPC, annotated with the request id,
+// method and trace id, and sends the request id back as a response header.
+func newRPCLogger(ctx context.Context, method string) *slog.Logger {
+	requestID := requestIDFromContext(ctx)
+	if err := grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, requestID)); err != nil {
+		slog.Debug("Failed to set request id header", "error", err)
+	}
+
+	logger := slog.Default().With("request_id", requestID, "rpc_method", method)
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		logger = logger.With("trace_id", sc.TraceID().String())
+	}
+	return logger
+}
+
+func levelForCode(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK:
+		return slog.LevelInfo
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.DeadlineExceeded:
+		return slog.LevelError
+	default:
+		return slog.LevelWarn
+	}
+}
+
+// UnaryServerInterceptor attaches a request-scoped logger to the context
+// and logs the outcome of every unary RPC.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+
+		logger := newRPCLogger(ctx, info.FullMethod)
+		bookID := int64(0)
+		if r, ok := req.(idGetter); ok && r.GetId() != 0 {
+			bookID = r.GetId()
+			logger = logger.With("book_id", bookID)
+		}
+
+		resp, err := handler(WithLogger(ctx, logger), req)
+
+		attrs := []slog.Attr{
+			slog.String("status", status.Code(err).String()),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+		}
+		if r, ok := resp.(idGetter); ok && bookID == 0 && r.GetId() != 0 {
+			attrs = append(attrs, slog.Int64("book_id", r.GetId()))
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		logger.LogAttrs(ctx, levelForCode(status.Code(err)), "RPC completed", attrs...)
+		return resp, err
+	}
+}
+
+type loggingServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *loggingServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// StreamServerInterceptor attaches a request-scoped logger to the stream
+// context and logs the outcome of every streaming RPC.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+
+		logger := newRPCLogger(ss.Context(), info.FullMethod)
+		err := handler(srv, &loggingServerStream{ServerStream: ss, ctx: WithLogger(ss.Context(), logger)})
+
+		attrs := []slog.Attr{
+			slog.String("status", status.Code(err).String()),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+		}
+		logger.LogAttrs(ss.Context(), levelForCode(status.Code(err)), "RPC completed", attrs...)
+		return err
+	}
+}
@@ -0,0 +1,160 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// captureLogs makes the default logger write JSON to the returned buffer for
+// the rest of the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(newHandler(&buf, "json", slog.LevelDebug)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+// lastEntry returns the last log line with the given message.
+func lastEntry(t *testing.T, buf *bytes.Buffer, msg string) map[string]interface{} {
+	t.Helper()
+	var found map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		if entry["msg"] == msg {
+			found = entry
+		}
+	}
+	if found == nil {
+		t.Fatalf("no %q log line in %q", msg, buf.String())
+	}
+	return found
+}
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"abc-123", true},
+		{"", false},
+		{"has space", false},
+		{"new\nline", false},
+		{"ünicode", false},
+		{strings.Repeat("a", maxRequestIDLength), true},
+		{strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+	for _, tt := range tests {
+		if got := validRequestID(tt.id); got != tt.want {
+			t.Errorf("validRequestID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	buf := captureLogs(t)
+	var forwarded string
+	handler := HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = GatewayMetadata(r.Context(), r).Get(RequestIDMetadataKey)[0]
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"valid id", "req-1", true},
+		{"missing id", "", false},
+		{"invalid id", "bad id", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/books/7", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(RequestIDHeader)
+			if tt.keep && id != tt.incoming {
+				t.Errorf("response request id = %q, want %q", id, tt.incoming)
+			}
+			if !tt.keep && (id == tt.incoming || !validRequestID(id)) {
+				t.Errorf("response request id = %q, want a generated one", id)
+			}
+			if forwarded != id {
+				t.Errorf("request id forwarded to gRPC = %q, want %q", forwarded, id)
+			}
+
+			entry := lastEntry(t, buf, "HTTP request completed")
+			if entry["request_id"] != id || entry["status"] != float64(http.StatusNotFound) || entry["level"] != "WARN" {
+				t.Errorf("log entry = %v, want request_id %q, status 404 at WARN", entry, id)
+			}
+		})
+	}
+}
+
+type bookRequest struct{ id int64 }
+
+func (r bookRequest) GetId() int64 { return r.id }
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	buf := captureLogs(t)
+	intercept := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/booking.BookingService/ReadBook"}
+
+	tests := []struct {
+		name   string
+		req    interface{}
+		resp   interface{}
+		err    error
+		level  string
+		bookID interface{}
+	}{
+		{"book from the request", bookRequest{7}, nil, nil, "INFO", float64(7)},
+		{"book from the response", nil, bookRequest{8}, nil, "INFO", float64(8)},
+		{"client error", bookRequest{9}, nil, status.Error(codes.NotFound, "book 9 not found"), "WARN", float64(9)},
+		{"server error", nil, nil, status.Error(codes.Internal, "boom"), "ERROR", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDMetadataKey, "req-1"))
+			_, err := intercept(ctx, tt.req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				FromContext(ctx).Info("In handler")
+				return tt.resp, tt.err
+			})
+			if err != tt.err {
+				t.Fatalf("interceptor error = %v, want %v", err, tt.err)
+			}
+
+			inner := lastEntry(t, buf, "In handler")
+			if inner["request_id"] != "req-1" || inner["rpc_method"] != info.FullMethod {
+				t.Errorf("handler log entry = %v, want the request id and method", inner)
+			}
+			entry := lastEntry(t, buf, "RPC completed")
+			if entry["level"] != tt.level || entry["book_id"] != tt.bookID || entry["request_id"] != "req-1" {
+				t.Errorf("log entry = %v, want level %s and book_id %v", entry, tt.level, tt.bookID)
+			}
+			if tt.err != nil && entry["error"] != tt.err.Error() {
+				t.Errorf("logged error = %v, want %q", entry["error"], tt.err.Error())
+			}
+		})
+	}
+}
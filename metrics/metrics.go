@@ -0,0 +1,212 @@
+// Package metrics exports Prometheus metrics for the gRPC server, the
+// gRPC-Gateway and the database.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "bookstore"
+
+// Metrics holds the collectors of the service and the registry they are
+// registered in.
+type Metrics struct {
+	registry *prometheus.Registry
+	handler  http.Handler
+
+	rpcRequests *prometheus.CounterVec
+	rpcDuration *prometheus.HistogramVec
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+}
+
+// New creates the service metrics. Database pool and catalog metrics are
+// collected from db on every scrape.
+func New(db *sql.DB) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		rpcRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "requests_total",
+			Help:      "Total number of gRPC requests handled, by method and status code.",
+		}, []string{"method", "code"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "grpc",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of gRPC requests, by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Total number of gateway HTTP requests, by method, route and status code.",
+		}, []string{"method", "route", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Latency of gateway HTTP requests, by method and route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "bookstore"),
+		newCatalogCollector(db),
+		m.rpcRequests,
+		m.rpcDuration,
+		m.httpRequests,
+		m.httpDuration,
+	)
+	m.handler = promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	return m
+}
+
+// Register adds extra collectors to the service registry.
+func (m *Metrics) Register(cs ...prometheus.Collector) {
+	m.registry.MustRegister(cs...)
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	m.handler.ServeHTTP(w, r)
+}
+
+// UnaryServerInterceptor records the count, status code and latency of
+// every unary RPC.
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.rpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		m.rpcRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor records the count, status code and latency of
+// every streaming RPC.
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		m.rpcDuration.WithLabelValues(info.FullMethod).Observe(time.Since(start).Seconds())
+		m.rpcRequests.WithLabelValues(info.FullMethod, status.Code(err).String()).Inc()
+		return err
+	}
+}
+
+// idSegment matches numeric path segments, which are replaced in the route
+// label to keep its cardinality bounded.
+var idSegment = regexp.MustCompile(`/[0-9]+(/|$)`)
+
+func route(path string) string {
+	return idSegment.ReplaceAllString(path, "/{id}$1")
+}
+
+// HTTPMiddleware records the count, status code and latency of every
+// gateway request.
+func (m *Metrics) HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		rt := route(r.URL.Path)
+		m.httpDuration.WithLabelValues(r.Method, rt).Observe(time.Since(start).Seconds())
+		m.httpRequests.WithLabelValues(r.Method, rt, strconv.Itoa(rec.status)).Inc()
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// catalogTimeout bounds the catalog query run on every scrape.
+const catalogTimeout = 5 * time.Second
+
+// catalogCollector exports business gauges computed from the books table.
+type catalogCollector struct {
+	db *sql.DB
+
+	books      *prometheus.Desc
+	stockUnits *prometheus.Desc
+	outOfStock *prometheus.Desc
+}
+
+func newCatalogCollector(db *sql.DB) *catalogCollector {
+	return &catalogCollector{
+		db: db,
+		books: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "catalog", "books"),
+			"Total number of books in the catalog.",
+			nil, nil,
+		),
+		stockUnits: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "catalog", "stock_units"),
+			"Total number of units in stock across all books.",
+			nil, nil,
+		),
+		outOfStock: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "catalog", "out_of_stock_books"),
+			"Number of books with no units in stock.",
+			nil, nil,
+		),
+	}
+}
+
+func (c *catalogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.books
+	ch <- c.stockUnits
+	ch <- c.outOfStock
+}
+
+func (c *catalogCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), catalogTimeout)
+	defer cancel()
+
+	sqlStatement := `
+		SELECT count(*), coalesce(sum(quantity), 0), count(*) FILTER (WHERE quantity <= 0)
+		FROM books
+	`
+
+	var books, stockUnits, outOfStock int64
+	err := c.db.QueryRowContext(ctx, sqlStatement).Scan(&books, &stockUnits, &outOfStock)
+	if err != nil {
+		slog.Error("Failed to collect catalog metrics", "error", err)
+		ch <- prometheus.NewInvalidMetric(c.books, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.books, prometheus.GaugeValue, float64(books))
+	ch <- prometheus.MustNewConstMetric(c.stockUnits, prometheus.GaugeValue, float64(stockUnits))
+	ch <- prometheus.MustNewConstMetric(c.outOfStock, prometheus.GaugeValue, float64(outOfStock))
+}
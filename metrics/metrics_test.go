@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"Booking/migrations"
+)
+
+// newTestMetrics returns metrics backed by a database that is never
+// connected to, for tests that do not scrape the catalog.
+func newTestMetrics(t *testing.T) *Metrics {
+	t.Helper()
+	db, err := sql.Open("postgres", "host=/nonexistent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return New(db)
+}
+
+func TestRoute(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/v1/books", "/v1/books"},
+		{"/v1/books/42", "/v1/books/{id}"},
+		{"/v1/books/42/cover", "/v1/books/{id}/cover"},
+		{"/v1/books/42/reviews/7", "/v1/books/{id}/reviews/{id}"},
+		{"/v1/books/4x2", "/v1/books/4x2"},
+	}
+	for _, tt := range tests {
+		if got := route(tt.path); got != tt.want {
+			t.Errorf("route(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	m := newTestMetrics(t)
+	intercept := m.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/booking.BookingService/ReadBook"}
+
+	for _, err := range []error{nil, nil, status.Error(codes.NotFound, "book 1 not found")} {
+		_, _ = intercept(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, err
+		})
+	}
+
+	if got := testutil.ToFloat64(m.rpcRequests.WithLabelValues(info.FullMethod, "OK")); got != 2 {
+		t.Errorf("OK requests = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.rpcRequests.WithLabelValues(info.FullMethod, "NotFound")); got != 1 {
+		t.Errorf("NotFound requests = %v, want 1", got)
+	}
+	if got := testutil.CollectAndCount(m.rpcDuration); got != 1 {
+		t.Errorf("duration series = %d, want 1", got)
+	}
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	m := newTestMetrics(t)
+	handler := m.HTTPMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/0") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("{}"))
+	}))
+
+	for _, path := range []string{"/v1/books/1", "/v1/books/2", "/v1/books/0"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues(http.MethodGet, "/v1/books/{id}", "200")); got != 2 {
+		t.Errorf("200 responses = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.httpRequests.WithLabelValues(http.MethodGet, "/v1/books/{id}", "404")); got != 1 {
+		t.Errorf("404 responses = %v, want 1", got)
+	}
+}
+
+func TestCatalogCollector(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if err := migrations.Apply(ctx, db); err != nil {
+		t.Fatalf("migrations.Apply() error = %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `TRUNCATE books RESTART IDENTITY CASCADE`); err != nil {
+		t.Fatal(err)
+	}
+	for _, quantity := range []int{3, 0, 5} {
+		_, err := db.ExecContext(ctx, `
+			INSERT INTO books (title, author, year, language, price, quantity)
+			VALUES ('Title', 'Author', 2000, 'en', 100, $1)
+		`, quantity)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := `
+# HELP bookstore_catalog_books Total number of books in the catalog.
+# TYPE bookstore_catalog_books gauge
+bookstore_catalog_books 3
+# HELP bookstore_catalog_out_of_stock_books Number of books with no units in stock.
+# TYPE bookstore_catalog_out_of_stock_books gauge
+bookstore_catalog_out_of_stock_books 1
+# HELP bookstore_catalog_stock_units Total number of units in stock across all books.
+# TYPE bookstore_catalog_stock_units gauge
+bookstore_catalog_stock_units 8
+`
+	if err := testutil.CollectAndCompare(newCatalogCollector(db), strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCatalogCollectorError(t *testing.T) {
+	m := newTestMetrics(t)
+	if _, err := m.registry.Gather(); err == nil {
+		t.Error("Gather() succeeded without a database")
+	}
+}
@@ -0,0 +1,189 @@
+package migrations
+
+import (
+	"context"
+	"crypto/md5"
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+// newEmptyTestDB returns the scratch database named by TEST_DATABASE_DSN
+// with an empty public schema, or skips the test when it is unset.
+func newEmptyTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`DROP SCHEMA public CASCADE; CREATE SCHEMA public`); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// applyThrough applies the migrations up to and including version last.
+func applyThrough(t *testing.T, db *sql.DB, last string) {
+	t.Helper()
+	migrations, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := db.ExecContext(ctx, createVersionTable); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range migrations {
+		if m.version > last {
+			return
+		}
+		if err := apply(ctx, db, m); err != nil {
+			t.Fatalf("apply migration %s: %v", m.version, err)
+		}
+	}
+}
+
+func TestGenreTaxonomyMigration(t *testing.T) {
+	db := newEmptyTestDB(t)
+	applyThrough(t, db, "0012_create_series")
+
+	books := []struct {
+		genres []string
+		want   []string
+	}{
+		// Slugs and aliases in any case and spacing, without duplicates.
+		{[]string{"Sci-Fi", "  Fantasy ", "science  fiction", "Фантастика", "Novel"}, []string{"science-fiction", "fantasy", "fiction"}},
+		// Unknown strings become top-level genres.
+		{[]string{"Space Opera", "mystery"}, []string{"space-opera", "mystery"}},
+		// A string whose slug is an alias joins that genre.
+		{[]string{"Sci fi!"}, []string{"science-fiction"}},
+		// A string without ASCII letters or digits gets a slug from its hash.
+		{[]string{"Роман"}, []string{fmt.Sprintf("genre-%x", md5.Sum([]byte("роман")))[:14]}},
+		{nil, []string{}},
+	}
+	ids := make([]int64, len(books))
+	for i, b := range books {
+		err := db.QueryRow(`
+			INSERT INTO books (title, author, year, language, genres, price, quantity)
+			VALUES ('Title', 'Author', 2000, 'en', $1, 100, 1)
+			RETURNING id
+		`, pq.StringArray(b.genres)).Scan(&ids[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	applyThrough(t, db, "0013_create_genre_taxonomy")
+
+	for i, b := range books {
+		var got pq.StringArray
+		err := db.QueryRow(`
+			SELECT ARRAY(
+				SELECT g.slug
+				FROM books, unnest(genre_ids) WITH ORDINALITY AS u (id, n)
+				JOIN genres g ON g.id = u.id
+				WHERE books.id = $1
+				ORDER BY u.n
+			)
+		`, ids[i]).Scan(&got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, b.want) {
+			t.Errorf("genres %q became %q, want %q", b.genres, got, b.want)
+		}
+	}
+
+	var slug string
+	err := db.QueryRow(`SELECT g.slug FROM genre_aliases a JOIN genres g ON g.id = a.genre_id WHERE a.alias = 'space opera'`).Scan(&slug)
+	if err != nil || slug != "space-opera" {
+		t.Errorf("alias %q resolves to %q, %v, want %q", "space opera", slug, err, "space-opera")
+	}
+}
+
+func TestConcurrentApply(t *testing.T) {
+	db := newEmptyTestDB(t)
+	ctx := context.Background()
+
+	// Replicas starting together must take turns rather than apply the
+	// same migration twice.
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() { errs <- Apply(ctx, db) }()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Apply() error = %v", err)
+		}
+	}
+
+	migrations, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var applied int
+	if err := db.QueryRow(`SELECT count(*) FROM schema_migrations`).Scan(&applied); err != nil {
+		t.Fatal(err)
+	}
+	if applied != len(migrations) {
+		t.Errorf("schema_migrations has %d rows, want %d", applied, len(migrations))
+	}
+	pending, err := Pending(ctx, db)
+	if err != nil || len(pending) != 0 {
+		t.Errorf("Pending() = %v, %v, want none", pending, err)
+	}
+
+	// The lock is released once Apply returns.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, lockID).Scan(&locked); err != nil {
+		t.Fatal(err)
+	}
+	if !locked {
+		t.Fatal("the migration lock is still held after Apply()")
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, lockID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPending(t *testing.T) {
+	db := newEmptyTestDB(t)
+	ctx := context.Background()
+
+	migrations, err := load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending, err := Pending(ctx, db)
+	if err != nil {
+		t.Fatalf("Pending() error = %v", err)
+	}
+	if len(pending) != len(migrations) || pending[0] != migrations[0].version {
+		t.Errorf("Pending() on an empty database = %v, want every migration", pending)
+	}
+
+	applyThrough(t, db, "0012_create_series")
+	pending, err = Pending(ctx, db)
+	if err != nil {
+		t.Fatalf("Pending() error = %v", err)
+	}
+	if len(pending) == 0 || pending[0] != "0013_create_genre_taxonomy" {
+		t.Errorf("Pending() = %v, want it to start at 0013_create_genre_taxonomy", pending)
+	}
+	if err := Apply(ctx, db); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+}
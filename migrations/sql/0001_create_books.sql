@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS books (
+    id       BIGSERIAL PRIMARY KEY,
+    title    TEXT    NOT NULL,
+    author   TEXT    NOT NULL,
+    year     INTEGER NOT NULL,
+    language TEXT    NOT NULL,
+    genres   TEXT[]  NOT NULL DEFAULT '{}',
+    price    INTEGER NOT NULL,
+    quantity INTEGER NOT NULL
+);
@@ -0,0 +1,13 @@
+-- Notifies other replicas of changed and deleted books so that they can
+-- drop them from their ReadBook cache.
+CREATE OR REPLACE FUNCTION notify_book_change() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('book_changes', OLD.id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS books_notify_change ON books;
+CREATE TRIGGER books_notify_change
+    AFTER UPDATE OR DELETE ON books
+    FOR EACH ROW EXECUTE PROCEDURE notify_book_change();
@@ -0,0 +1,7 @@
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN IF NOT EXISTS created_by TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS updated_by TEXT        NOT NULL DEFAULT '';
+
+-- ListBooks pages through books in (updated_at, id) order.
+CREATE INDEX IF NOT EXISTS books_updated_at_id_idx ON books (updated_at, id);
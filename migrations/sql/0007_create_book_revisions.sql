@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS book_revisions (
+    id         BIGSERIAL PRIMARY KEY,
+    book_id    BIGINT      NOT NULL,
+    operation  TEXT        NOT NULL,
+    before     JSONB,
+    after      JSONB,
+    actor      TEXT        NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS book_revisions_book_id_created_at_idx ON book_revisions (book_id, created_at);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope         TEXT        NOT NULL,
+    key           TEXT        NOT NULL,
+    method        TEXT        NOT NULL,
+    fingerprint   BYTEA       NOT NULL,
+    response_type TEXT,
+    response      BYTEA,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at    TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,2 @@
+-- The cover URLs of a book, as set by UploadCover. NULL when it has none.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS cover JSONB;
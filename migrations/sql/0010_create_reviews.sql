@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    id           BIGSERIAL PRIMARY KEY,
+    book_id      BIGINT      NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    user_id      TEXT        NOT NULL,
+    rating       SMALLINT    NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    text         TEXT        NOT NULL DEFAULT '',
+    status       TEXT        NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    moderated_by TEXT        NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (book_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS reviews_status_id_idx ON reviews (status, id);
+
+-- The rating of a book over its approved reviews, kept up to date by the
+-- review RPCs.
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS rating_sum   INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rating_count INTEGER NOT NULL DEFAULT 0;
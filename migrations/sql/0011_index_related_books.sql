@@ -0,0 +1,3 @@
+-- GetRelatedBooks looks up books sharing a genre or the author.
+CREATE INDEX IF NOT EXISTS books_genres_idx ON books USING GIN (genres);
+CREATE INDEX IF NOT EXISTS books_lower_author_idx ON books (lower(author));
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS series (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT        NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS series_id       BIGINT REFERENCES series (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS series_position DOUBLE PRECISION;
+
+-- Positions are unique within a series and give its reading order.
+CREATE UNIQUE INDEX IF NOT EXISTS books_series_id_position_idx ON books (series_id, series_position);
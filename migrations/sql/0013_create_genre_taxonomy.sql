@@ -0,0 +1,128 @@
+CREATE TABLE IF NOT EXISTS genres (
+    id        BIGSERIAL PRIMARY KEY,
+    parent_id BIGINT REFERENCES genres (id),
+    slug      TEXT  NOT NULL UNIQUE,
+    -- Display names by locale, such as {"en": "Fantasy"}.
+    names     JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS genres_parent_id_idx ON genres (parent_id);
+
+-- Alternative spellings of genres, lower-cased with single spaces. Book
+-- genres given as strings are resolved through slugs and aliases.
+CREATE TABLE IF NOT EXISTS genre_aliases (
+    alias    TEXT PRIMARY KEY,
+    genre_id BIGINT NOT NULL REFERENCES genres (id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS genre_aliases_genre_id_idx ON genre_aliases (genre_id);
+
+-- The base taxonomy.
+INSERT INTO genres (slug, names) VALUES
+    ('fiction',     '{"en": "Fiction", "ru": "Художественная литература", "kk": "Көркем әдебиет"}'),
+    ('non-fiction', '{"en": "Non-fiction", "ru": "Нон-фикшн", "kk": "Деректі әдебиет"}'),
+    ('childrens',   '{"en": "Children''s", "ru": "Детская литература", "kk": "Балалар әдебиеті"}')
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO genres (slug, parent_id, names)
+SELECT v.slug, p.id, v.names::jsonb
+FROM (VALUES
+    ('science-fiction', 'fiction',     '{"en": "Science fiction", "ru": "Научная фантастика", "kk": "Ғылыми фантастика"}'),
+    ('fantasy',         'fiction',     '{"en": "Fantasy", "ru": "Фэнтези", "kk": "Фэнтези"}'),
+    ('mystery',         'fiction',     '{"en": "Mystery", "ru": "Детектив", "kk": "Детектив"}'),
+    ('thriller',        'fiction',     '{"en": "Thriller", "ru": "Триллер", "kk": "Триллер"}'),
+    ('horror',          'fiction',     '{"en": "Horror", "ru": "Ужасы", "kk": "Қорқынышты әдебиет"}'),
+    ('romance',         'fiction',     '{"en": "Romance", "ru": "Любовный роман", "kk": "Махаббат романы"}'),
+    ('biography',       'non-fiction', '{"en": "Biography", "ru": "Биография", "kk": "Өмірбаян"}'),
+    ('history',         'non-fiction', '{"en": "History", "ru": "История", "kk": "Тарих"}')
+) AS v (slug, parent, names)
+JOIN genres p ON p.slug = v.parent
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO genre_aliases (alias, genre_id)
+SELECT v.alias, g.id
+FROM (VALUES
+    ('sci-fi',          'science-fiction'),
+    ('scifi',           'science-fiction'),
+    ('sf',              'science-fiction'),
+    ('science fiction', 'science-fiction'),
+    ('фантастика',      'science-fiction'),
+    ('фэнтези',         'fantasy'),
+    ('detective',       'mystery'),
+    ('crime',           'mystery'),
+    ('детектив',        'mystery'),
+    ('nonfiction',      'non-fiction'),
+    ('non fiction',     'non-fiction'),
+    ('children',        'childrens'),
+    ('children''s',     'childrens'),
+    ('kids',            'childrens'),
+    ('novel',           'fiction')
+) AS v (alias, slug)
+JOIN genres g ON g.slug = v.slug
+ON CONFLICT (alias) DO NOTHING;
+
+-- Every existing genre string that is neither a slug nor an alias becomes a
+-- top-level genre, with the string as its alias.
+CREATE TEMPORARY TABLE legacy_genres ON COMMIT DROP AS
+SELECT DISTINCT ON (alias)
+    alias,
+    name,
+    COALESCE(
+        NULLIF(trim(BOTH '-' FROM regexp_replace(alias, '[^a-z0-9]+', '-', 'g')), ''),
+        'genre-' || left(md5(alias), 8)
+    ) AS slug
+FROM (
+    SELECT lower(regexp_replace(trim(g), '\s+', ' ', 'g')) AS alias, trim(g) AS name
+    FROM books, unnest(books.genres) AS g
+    WHERE trim(g) <> ''
+) AS strings
+ORDER BY alias, name;
+
+-- Strings whose slug is a known alias, such as "sci fi", join that genre.
+INSERT INTO genre_aliases (alias, genre_id)
+SELECT l.alias, a.genre_id
+FROM legacy_genres l
+JOIN genre_aliases a ON a.alias = l.slug
+ON CONFLICT (alias) DO NOTHING;
+
+DELETE FROM legacy_genres l
+WHERE EXISTS (SELECT 1 FROM genres g WHERE g.slug = l.alias)
+   OR EXISTS (SELECT 1 FROM genre_aliases a WHERE a.alias = l.alias);
+
+INSERT INTO genres (slug, names)
+SELECT DISTINCT ON (slug) slug, jsonb_build_object('en', name)
+FROM legacy_genres
+ORDER BY slug, name
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO genre_aliases (alias, genre_id)
+SELECT l.alias, g.id
+FROM legacy_genres l
+JOIN genres g ON g.slug = l.slug
+WHERE l.alias <> l.slug
+ON CONFLICT (alias) DO NOTHING;
+
+-- Books reference genres by id from now on, in their original order.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS genre_ids BIGINT[] NOT NULL DEFAULT '{}';
+
+UPDATE books b
+SET genre_ids = ARRAY(
+    SELECT r.id
+    FROM unnest(b.genres) WITH ORDINALITY AS u (name, n)
+    CROSS JOIN LATERAL (
+        SELECT g.id FROM genres g
+        WHERE g.slug = lower(regexp_replace(trim(u.name), '\s+', ' ', 'g'))
+        UNION ALL
+        SELECT a.genre_id FROM genre_aliases a
+        WHERE a.alias = lower(regexp_replace(trim(u.name), '\s+', ' ', 'g'))
+        LIMIT 1
+    ) AS r
+    GROUP BY r.id
+    ORDER BY min(u.n)
+)
+WHERE cardinality(b.genres) > 0;
+
+DROP INDEX IF EXISTS books_genres_idx;
+ALTER TABLE books DROP COLUMN IF EXISTS genres;
+
+CREATE INDEX IF NOT EXISTS books_genre_ids_idx ON books USING GIN (genre_ids);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS book_translations (
+    book_id     BIGINT      NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    -- Canonical BCP 47 tag, such as "kk" or "en-US".
+    locale      TEXT        NOT NULL,
+    title       TEXT        NOT NULL,
+    subtitle    TEXT        NOT NULL DEFAULT '',
+    description TEXT        NOT NULL DEFAULT '',
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (book_id, locale)
+);
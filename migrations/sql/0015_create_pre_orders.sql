@@ -0,0 +1,25 @@
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS release_date DATE,
+    ADD COLUMN IF NOT EXISTS availability TEXT NOT NULL DEFAULT 'in_stock'
+        CHECK (availability IN ('announced', 'pre_order', 'in_stock', 'out_of_print'));
+
+-- Books waiting for their release, checked by the allocation job.
+CREATE INDEX IF NOT EXISTS books_unreleased_idx ON books (release_date)
+    WHERE availability IN ('announced', 'pre_order');
+
+CREATE INDEX IF NOT EXISTS books_availability_idx ON books (availability, updated_at, id);
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id           BIGSERIAL PRIMARY KEY,
+    book_id      BIGINT      NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    type         TEXT        NOT NULL CHECK (type IN ('pre_order')),
+    status       TEXT        NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'allocated', 'cancelled')),
+    customer_id  TEXT        NOT NULL,
+    quantity     INTEGER     NOT NULL CHECK (quantity > 0),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    allocated_at TIMESTAMPTZ
+);
+
+-- The queue of each book, served in id order.
+CREATE INDEX IF NOT EXISTS reservations_queued_idx ON reservations (book_id, id) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS reservations_book_id_idx ON reservations (book_id, id);
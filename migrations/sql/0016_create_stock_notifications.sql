@@ -0,0 +1,24 @@
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS reorder_threshold INTEGER NOT NULL DEFAULT 0 CHECK (reorder_threshold >= 0);
+
+CREATE TABLE IF NOT EXISTS back_in_stock_subscriptions (
+    book_id     BIGINT      NOT NULL REFERENCES books (id) ON DELETE CASCADE,
+    customer_id TEXT        NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (book_id, customer_id)
+);
+
+-- Outbox of catalog events. Events are written in the transaction of the
+-- change they describe and relayed to AMQP afterwards. book_id has no
+-- foreign key so that events outlive deleted books.
+CREATE TABLE IF NOT EXISTS events (
+    id           BIGSERIAL PRIMARY KEY,
+    type         TEXT        NOT NULL,
+    book_id      BIGINT,
+    payload      JSONB       NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS events_unpublished_idx ON events (id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS events_created_at_idx ON events (created_at);
@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id          BIGSERIAL PRIMARY KEY,
+    url         TEXT        NOT NULL,
+    -- HMAC-SHA256 key of the payload signatures.
+    secret      TEXT        NOT NULL,
+    -- Delivered event types; all when empty.
+    event_types TEXT[]      NOT NULL DEFAULT '{}',
+    description TEXT        NOT NULL DEFAULT '',
+    enabled     BOOLEAN     NOT NULL DEFAULT true,
+    created_by  TEXT        NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Deliveries copy the event payload, so that they can be replayed after
+-- the event itself was deleted.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               BIGSERIAL PRIMARY KEY,
+    endpoint_id      BIGINT      NOT NULL REFERENCES webhook_endpoints (id) ON DELETE CASCADE,
+    event_id         BIGINT      NOT NULL,
+    event_type       TEXT        NOT NULL,
+    payload          JSONB       NOT NULL,
+    status           TEXT        NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts         INTEGER     NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_status_code INTEGER     NOT NULL DEFAULT 0,
+    last_error       TEXT        NOT NULL DEFAULT '',
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_endpoint_id_idx ON webhook_deliveries (endpoint_id, id);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_created_at_idx ON webhook_deliveries (created_at);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id            BIGSERIAL PRIMARY KEY,
+    delivery_id   BIGINT      NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    attempted_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    status_code   INTEGER     NOT NULL DEFAULT 0,
+    error         TEXT        NOT NULL DEFAULT '',
+    duration_ms   BIGINT      NOT NULL,
+    response_body TEXT        NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_attempts_delivery_id_idx ON webhook_delivery_attempts (delivery_id, id);
@@ -0,0 +1,3 @@
+-- claimed_at is when the running attempt of a key claimed it. Claims without
+-- a response that are older than the claim timeout are taken over by retries.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMPTZ NOT NULL DEFAULT now();
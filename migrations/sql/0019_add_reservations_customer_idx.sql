@@ -0,0 +1,3 @@
+-- Books reserved by the same customers, for GetRelatedBooks.
+CREATE INDEX IF NOT EXISTS reservations_customer_id_idx ON reservations (customer_id, book_id)
+    WHERE status <> 'cancelled';
@@ -0,0 +1,19 @@
+-- ListBooks pages through books in the order of the transaction that last
+-- changed them, up to the oldest one still running, so that a change that
+-- commits late is not listed behind a page already returned. xid8 needs
+-- PostgreSQL 13 or later.
+ALTER TABLE books ADD COLUMN IF NOT EXISTS changed_xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE OR REPLACE FUNCTION set_book_changed_xid() RETURNS trigger AS $$
+BEGIN
+    NEW.changed_xid := pg_current_xact_id();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS books_set_changed_xid ON books;
+CREATE TRIGGER books_set_changed_xid
+    BEFORE INSERT OR UPDATE ON books
+    FOR EACH ROW EXECUTE PROCEDURE set_book_changed_xid();
+
+CREATE INDEX IF NOT EXISTS books_changed_xid_id_idx ON books (changed_xid, id);
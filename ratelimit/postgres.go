@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"Booking/config"
+	"Booking/tracing"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table, so that all
+// replicas share the same limits.
+type PostgresStore struct {
+	db *tracing.DB
+}
+
+func NewPostgresStore(db *tracing.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit config.RateLimit) (Decision, error) {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (key) DO NOTHING
+	`, key, limit.Burst)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	// The refilled token count is computed and a token taken, if one is
+	// available, in a single statement that holds the row lock.
+	sqlStatement := `
+		UPDATE rate_limit_buckets b
+		SET tokens = CASE WHEN r.tokens >= 1 THEN r.tokens - 1 ELSE r.tokens END,
+		    updated_at = now()
+		FROM (
+			SELECT key, least($2::float8, tokens + extract(epoch FROM now() - updated_at) * $3::float8) AS tokens
+			FROM rate_limit_buckets
+			WHERE key = $1
+			FOR UPDATE
+		) r
+		WHERE b.key = r.key
+		RETURNING r.tokens
+	`
+
+	var tokens float64
+	if err := s.db.QueryRowContext(ctx, sqlStatement, key, limit.Burst, limit.Rate).Scan(&tokens); err != nil {
+		return Decision{}, err
+	}
+	return decide(tokens, limit), nil
+}
+
+// Cleanup deletes buckets that have not been used for idle, every
+// interval, until ctx is cancelled, so that the table does not grow with
+// every client ever seen.
+func (s *PostgresStore) Cleanup(ctx context.Context, interval, idle time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := s.db.ExecContext(ctx, `
+				DELETE FROM rate_limit_buckets
+				WHERE updated_at < now() - make_interval(secs => $1)
+			`, idle.Seconds())
+			if err != nil {
+				slog.Error("Failed to delete idle rate limit buckets", "error", err)
+			}
+		}
+	}
+}
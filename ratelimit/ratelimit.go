@@ -0,0 +1,312 @@
+// Package ratelimit enforces per-client token-bucket limits on RPCs. Clients
+// are identified by API key, JWT subject or IP address. Every IP address is
+// also limited over all RPCs before it is authenticated. Buckets live in
+// process memory, or in Postgres when several replicas must share them.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"Booking/auth"
+	"Booking/config"
+)
+
+// Header names of the rate limit response metadata. The gateway forwards
+// them as HTTP headers.
+const (
+	HeaderLimit      = "ratelimit-limit"
+	HeaderRemaining  = "ratelimit-remaining"
+	HeaderReset      = "ratelimit-reset"
+	HeaderRetryAfter = "retry-after"
+)
+
+// Decision is the outcome of taking a token from a bucket.
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until a token is available, when not allowed.
+	RetryAfter time.Duration
+}
+
+// Store holds token buckets.
+type Store interface {
+	Take(ctx context.Context, key string, limit config.RateLimit) (Decision, error)
+}
+
+// decide computes the decision for a bucket that holds tokens after refill.
+func decide(tokens float64, limit config.RateLimit) Decision {
+	d := Decision{Limit: limit.Burst}
+	if tokens >= 1 {
+		d.Allowed = true
+		tokens--
+	} else {
+		d.RetryAfter = time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+	}
+	d.Remaining = int(math.Floor(tokens))
+	d.Reset = time.Duration((float64(limit.Burst) - tokens) / limit.Rate * float64(time.Second))
+	return d
+}
+
+// Limiter applies the configured limits to incoming RPCs.
+type Limiter struct {
+	store   Store
+	def     config.RateLimit
+	methods map[string]config.RateLimit
+	ip      config.RateLimit
+	trusted []*net.IPNet
+}
+
+// NewLimiter returns a Limiter. Loopback addresses, from which the local
+// gateway proxies requests, are always trusted proxies.
+func NewLimiter(store Store, cfg config.RateLimitConfig) (*Limiter, error) {
+	l := &Limiter{store: store, def: cfg.Default, methods: cfg.Methods, ip: cfg.IP}
+	for _, proxy := range append([]string{"127.0.0.0/8", "::1/128"}, cfg.TrustedProxies...) {
+		network, err := parseNetwork(proxy)
+		if err != nil {
+			return nil, err
+		}
+		l.trusted = append(l.trusted, network)
+	}
+	return l, nil
+}
+
+// parseNetwork parses a CIDR range or a single address.
+func parseNetwork(s string) (*net.IPNet, error) {
+	if _, network, err := net.ParseCIDR(s); err == nil {
+		return network, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid trusted proxy %q", s)
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+func methodName(fullMethod string) string {
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[i+1:]
+	}
+	return fullMethod
+}
+
+func (l *Limiter) limitFor(fullMethod string) config.RateLimit {
+	if limit, ok := l.methods[methodName(fullMethod)]; ok {
+		return limit
+	}
+	return l.def
+}
+
+// clientKey identifies the caller: by API key or JWT subject when
+// authenticated, otherwise by IP address.
+func (l *Limiter) clientKey(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		if principal.APIKeyID != 0 {
+			return "apikey:" + strconv.FormatInt(principal.APIKeyID, 10)
+		}
+		return "sub:" + principal.Subject
+	}
+	return "ip:" + l.clientIP(ctx)
+}
+
+func (l *Limiter) isTrusted(ip net.IP) bool {
+	for _, network := range l.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the caller. While the request came from a
+// trusted proxy, such as the local gateway, the X-Forwarded-For entries are
+// walked from the right, each appended by the proxy it reached; the first
+// untrusted one is the client. Entries further left were sent by the client
+// and could be forged.
+func (l *Limiter) clientIP(ctx context.Context) string {
+	ip := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil || !l.isTrusted(parsed) {
+		return ip
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	var entries []string
+	for _, fwd := range md.Get("x-forwarded-for") {
+		entries = append(entries, strings.Split(fwd, ",")...)
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := strings.TrimSpace(entries[i])
+		hop := net.ParseIP(entry)
+		if hop == nil {
+			// Not an address, so not appended by a trusted proxy.
+			return ip
+		}
+		ip = entry
+		if !l.isTrusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// take takes a token from the bucket of key. It reports false when the
+// store failed, in which case the request is allowed: an unavailable shared
+// store must not take the API down.
+func (l *Limiter) take(ctx context.Context, key string, limit config.RateLimit) (Decision, bool) {
+	d, err := l.store.Take(ctx, key, limit)
+	if err != nil {
+		slog.Warn("Rate limit store failed, allowing request", "error", err)
+		return Decision{}, false
+	}
+	return d, true
+}
+
+// exceeded returns the ResourceExhausted error of a rejected request.
+func exceeded(d Decision, format string, args ...any) error {
+	st := status.Newf(codes.ResourceExhausted, format, args...)
+	if detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(d.RetryAfter)}); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+// checkIP takes a token for the caller's IP address. It only sets the
+// Retry-After header, when the request is rejected, since check sets the
+// RateLimit headers of the method limit.
+func (l *Limiter) checkIP(ctx context.Context) error {
+	if l.ip.Rate <= 0 {
+		return nil
+	}
+	d, ok := l.take(ctx, "ip|"+l.clientIP(ctx), l.ip)
+	if !ok || d.Allowed {
+		return nil
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs(HeaderRetryAfter, seconds(d.RetryAfter))); err != nil {
+		slog.Debug("Failed to set rate limit headers", "error", err)
+	}
+	return exceeded(d, "rate limit exceeded for this address")
+}
+
+// check takes a token for the caller of fullMethod and sets the rate limit
+// response headers.
+func (l *Limiter) check(ctx context.Context, fullMethod string) error {
+	limit := l.limitFor(fullMethod)
+	if limit.Rate <= 0 {
+		return nil
+	}
+
+	d, ok := l.take(ctx, methodName(fullMethod)+"|"+l.clientKey(ctx), limit)
+	if !ok {
+		return nil
+	}
+
+	md := metadata.Pairs(
+		HeaderLimit, strconv.Itoa(d.Limit),
+		HeaderRemaining, strconv.Itoa(d.Remaining),
+		HeaderReset, seconds(d.Reset),
+	)
+	if !d.Allowed {
+		md.Set(HeaderRetryAfter, seconds(d.RetryAfter))
+	}
+	if err := grpc.SetHeader(ctx, md); err != nil {
+		slog.Debug("Failed to set rate limit headers", "error", err)
+	}
+
+	if d.Allowed {
+		return nil
+	}
+	return exceeded(d, "rate limit exceeded for %s", methodName(fullMethod))
+}
+
+// UnaryIPInterceptor rejects unary calls over the limit of the caller's IP
+// address. It must run before the auth interceptor, so that credentials
+// are not checked for callers over the limit.
+func (l *Limiter) UnaryIPInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := l.checkIP(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamIPInterceptor rejects streaming calls over the limit of the
+// caller's IP address.
+func (l *Limiter) StreamIPInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.checkIP(ss.Context()); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// UnaryServerInterceptor rejects unary calls over the caller's limit. It
+// must run after the auth interceptor to key limits by principal.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := l.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects streaming calls over the caller's limit.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := l.check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// OutgoingHeaderMatcher exposes the rate limit metadata under the standard
+// RateLimit-* and Retry-After HTTP header names. It is meant for
+// runtime.WithOutgoingHeaderMatcher.
+func OutgoingHeaderMatcher(key string) (string, bool) {
+	switch strings.ToLower(key) {
+	case HeaderLimit:
+		return "RateLimit-Limit", true
+	case HeaderRemaining:
+		return "RateLimit-Remaining", true
+	case HeaderReset:
+		return "RateLimit-Reset", true
+	case HeaderRetryAfter:
+		return "Retry-After", true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
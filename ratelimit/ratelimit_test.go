@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"Booking/config"
+)
+
+func TestDecide(t *testing.T) {
+	limit := config.RateLimit{Rate: 2, Burst: 10}
+
+	tests := []struct {
+		name   string
+		tokens float64
+		want   Decision
+	}{
+		{"full bucket", 10, Decision{Allowed: true, Limit: 10, Remaining: 9, Reset: 500 * time.Millisecond}},
+		{"last token", 1, Decision{Allowed: true, Limit: 10, Remaining: 0, Reset: 5 * time.Second}},
+		{"fraction left after taking", 2.5, Decision{Allowed: true, Limit: 10, Remaining: 1, Reset: 4250 * time.Millisecond}},
+		{"empty bucket", 0, Decision{Limit: 10, Remaining: 0, Reset: 5 * time.Second, RetryAfter: 500 * time.Millisecond}},
+		{"part of a token", 0.5, Decision{Limit: 10, Remaining: 0, Reset: 4750 * time.Millisecond, RetryAfter: 250 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decide(tt.tokens, limit); got != tt.want {
+				t.Errorf("decide(%v) = %+v, want %+v", tt.tokens, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreRefill(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	limit := config.RateLimit{Rate: 2, Burst: 3}
+	ctx := context.Background()
+
+	take := func() Decision {
+		t.Helper()
+		d, err := s.Take(ctx, "k", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	for i := 0; i < 3; i++ {
+		if d := take(); !d.Allowed || d.Remaining != 2-i {
+			t.Fatalf("take %d = %+v, want allowed with %d remaining", i, d, 2-i)
+		}
+	}
+	if d := take(); d.Allowed || d.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("take from empty bucket = %+v, want rejected with retry after 500ms", d)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if d := take(); !d.Allowed {
+		t.Fatalf("take after one token refilled = %+v, want allowed", d)
+	}
+	if d := take(); d.Allowed {
+		t.Fatalf("second take after one token refilled = %+v, want rejected", d)
+	}
+
+	// The bucket never refills past Burst.
+	now = now.Add(time.Hour)
+	if d := take(); !d.Allowed || d.Remaining != 2 {
+		t.Fatalf("take after an hour = %+v, want allowed with 2 remaining", d)
+	}
+
+	if d, _ := s.Take(ctx, "other", limit); !d.Allowed || d.Remaining != 2 {
+		t.Fatalf("take from another key = %+v, want a full bucket", d)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	limiter, err := NewLimiter(NewMemoryStore(), config.RateLimitConfig{
+		TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		peer string
+		xff  []string
+		want string
+	}{
+		{"direct client", "203.0.113.5:4000", nil, "203.0.113.5"},
+		{"direct client cannot forge", "203.0.113.5:4000", []string{"198.51.100.1"}, "203.0.113.5"},
+		{"gateway", "127.0.0.1:4000", []string{"203.0.113.5"}, "203.0.113.5"},
+		{"gateway without header", "127.0.0.1:4000", nil, "127.0.0.1"},
+		{"forged entries before the gateway's", "127.0.0.1:4000", []string{"198.51.100.1, 203.0.113.5"}, "203.0.113.5"},
+		{"behind trusted load balancers", "[::1]:4000", []string{"198.51.100.1, 203.0.113.5, 192.0.2.1, 10.1.2.3"}, "203.0.113.5"},
+		{"several header values", "127.0.0.1:4000", []string{"203.0.113.5", "10.1.2.3"}, "203.0.113.5"},
+		{"only trusted hops", "127.0.0.1:4000", []string{"10.1.2.3, 10.4.5.6"}, "10.1.2.3"},
+		{"garbage entry", "127.0.0.1:4000", []string{"203.0.113.5, bogus, 10.1.2.3"}, "10.1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, err := net.ResolveTCPAddr("tcp", tt.peer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+			if tt.xff != nil {
+				md := metadata.MD{}
+				md.Append("x-forwarded-for", tt.xff...)
+				ctx = metadata.NewIncomingContext(ctx, md)
+			}
+			if got := limiter.clientIP(ctx); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewLimiterRejectsInvalidProxy(t *testing.T) {
+	if _, err := NewLimiter(NewMemoryStore(), config.RateLimitConfig{TrustedProxies: []string{"lb.internal"}}); err == nil {
+		t.Error("NewLimiter() accepted a host name as a trusted proxy")
+	}
+}
+
+func TestUnaryIPInterceptor(t *testing.T) {
+	limiter, err := NewLimiter(NewMemoryStore(), config.RateLimitConfig{IP: config.RateLimit{Rate: 0.001, Burst: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := limiter.UnaryIPInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/booking.BookingService/ReadBook"}
+	addr := &net.TCPAddr{IP: net.ParseIP("203.0.113.5"), Port: 4000}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+
+	calls := 0
+	handler := func(context.Context, interface{}) (interface{}, error) {
+		calls++
+		return nil, nil
+	}
+	for i := 0; i < 3; i++ {
+		_, err = interceptor(ctx, nil, info, handler)
+	}
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("third call error = %v, want ResourceExhausted", err)
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"Booking/apikey"
+	"Booking/auth"
+	pb "Booking/bookserver/test"
+	"Booking/logging"
+)
+
+// adminMethods require the admin role or scope.
+var adminMethods = map[string]bool{
+	pb.BookingService_CreateApiKey_FullMethodName:            true,
+	pb.BookingService_RevokeApiKey_FullMethodName:            true,
+	pb.BookingService_ListApiKeys_FullMethodName:             true,
+	pb.BookingService_ListPendingReviews_FullMethodName:      true,
+	pb.BookingService_ModerateReview_FullMethodName:          true,
+	pb.BookingService_CreateWebhookEndpoint_FullMethodName:   true,
+	pb.BookingService_GetWebhookEndpoint_FullMethodName:      true,
+	pb.BookingService_ListWebhookEndpoints_FullMethodName:    true,
+	pb.BookingService_UpdateWebhookEndpoint_FullMethodName:   true,
+	pb.BookingService_DeleteWebhookEndpoint_FullMethodName:   true,
+	pb.BookingService_ListWebhookDeliveries_FullMethodName:   true,
+	pb.BookingService_GetWebhookDelivery_FullMethodName:      true,
+	pb.BookingService_ReplayWebhookDeliveries_FullMethodName: true,
+}
+
+func apiKeyToProto(k *apikey.Key) *pb.ApiKey {
+	key := &pb.ApiKey{
+		Id:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    k.Scopes,
+		CreatedBy: k.CreatedBy,
+		CreatedAt: timestamppb.New(k.CreatedAt),
+	}
+	if k.LastUsedAt.Valid {
+		key.LastUsedAt = timestamppb.New(k.LastUsedAt.Time)
+	}
+	if k.RevokedAt.Valid {
+		key.RevokedAt = timestamppb.New(k.RevokedAt.Time)
+	}
+	return key
+}
+
+// verifyAPIKey is the auth.APIKeyVerifier backed by the API key store. The
+// scopes of a key are also its roles for the authorization policy.
+func verifyAPIKey(store *apikey.Store) auth.APIKeyVerifier {
+	return func(ctx context.Context, key string) (*auth.Principal, error) {
+		k, err := store.Verify(ctx, key)
+		if errors.Is(err, apikey.ErrInvalidKey) {
+			return nil, status.Error(codes.Unauthenticated, "invalid API key")
+		}
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to verify API key", "error", err)
+			return nil, status.Error(codes.Internal, "failed to verify API key")
+		}
+		return &auth.Principal{
+			Subject:  "apikey:" + k.Prefix,
+			Roles:    k.Scopes,
+			Scopes:   k.Scopes,
+			APIKeyID: k.ID,
+		}, nil
+	}
+}
+
+func (s *server) CreateApiKey(ctx context.Context, req *pb.CreateApiKeyRequest) (*pb.CreateApiKeyResponse, error) {
+	name := strings.TrimSpace(req.GetName())
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	createdBy := ""
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		createdBy = principal.Subject
+	}
+
+	key, plain, err := s.apiKeys.Create(ctx, name, req.GetScopes(), createdBy)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to create API key", "error", err)
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("API key created", "api_key_id", key.ID, "api_key_prefix", key.Prefix)
+	return &pb.CreateApiKeyResponse{
+		ApiKey: apiKeyToProto(key),
+		Key:    plain,
+	}, nil
+}
+
+func (s *server) RevokeApiKey(ctx context.Context, req *pb.RevokeApiKeyRequest) (*pb.ApiKey, error) {
+	key, err := s.apiKeys.Revoke(ctx, req.GetId())
+	if errors.Is(err, apikey.ErrNotFound) {
+		return nil, status.Errorf(codes.NotFound, "API key %d not found", req.GetId())
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to revoke API key", "error", err)
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("API key revoked", "api_key_id", key.ID, "api_key_prefix", key.Prefix)
+	return apiKeyToProto(key), nil
+}
+
+func (s *server) ListApiKeys(ctx context.Context, req *pb.ListApiKeysRequest) (*pb.ListApiKeysResponse, error) {
+	keys, err := s.apiKeys.List(ctx, req.GetIncludeRevoked())
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to list API keys", "error", err)
+		return nil, err
+	}
+
+	response := &pb.ListApiKeysResponse{ApiKeys: make([]*pb.ApiKey, 0, len(keys))}
+	for _, key := range keys {
+		response.ApiKeys = append(response.ApiKeys, apiKeyToProto(key))
+	}
+	return response, nil
+}
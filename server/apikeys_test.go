@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"Booking/apikey"
+	"Booking/auth"
+	pb "Booking/bookserver/test"
+)
+
+func TestAPIKeys(t *testing.T) {
+	s := newTestServer(t)
+	s.apiKeys = apikey.NewStore(s.db)
+	admin := asPrincipal("admin", auth.AdminRole)
+	verify := verifyAPIKey(s.apiKeys)
+
+	if _, err := s.CreateApiKey(admin, &pb.CreateApiKeyRequest{Name: "  "}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("CreateApiKey() without a name error = %v, want InvalidArgument", err)
+	}
+
+	created, err := s.CreateApiKey(admin, &pb.CreateApiKeyRequest{Name: "orders", Scopes: []string{"orders_service"}})
+	if err != nil {
+		t.Fatalf("CreateApiKey() error = %v", err)
+	}
+	if created.GetApiKey().GetCreatedBy() != "admin" {
+		t.Errorf("created_by = %q, want admin", created.GetApiKey().GetCreatedBy())
+	}
+
+	principal, err := verify(context.Background(), created.GetKey())
+	if err != nil {
+		t.Fatalf("verifyAPIKey() error = %v", err)
+	}
+	if principal.APIKeyID != created.GetApiKey().GetId() || !slices.Equal(principal.Roles, []string{"orders_service"}) {
+		t.Errorf("principal = %+v, want key %d with role orders_service", principal, created.GetApiKey().GetId())
+	}
+	if _, err := verify(context.Background(), "bk_nope_nope"); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("verifyAPIKey() of an unknown key error = %v, want Unauthenticated", err)
+	}
+
+	revoked, err := s.RevokeApiKey(admin, &pb.RevokeApiKeyRequest{Id: created.GetApiKey().GetId()})
+	if err != nil {
+		t.Fatalf("RevokeApiKey() error = %v", err)
+	}
+	if revoked.GetRevokedAt() == nil {
+		t.Error("RevokeApiKey() did not set revoked_at")
+	}
+	if _, err := verify(context.Background(), created.GetKey()); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("verifyAPIKey() of a revoked key error = %v, want Unauthenticated", err)
+	}
+	if _, err := s.RevokeApiKey(admin, &pb.RevokeApiKeyRequest{Id: 999}); status.Code(err) != codes.NotFound {
+		t.Errorf("RevokeApiKey() of a missing key error = %v, want NotFound", err)
+	}
+
+	list, err := s.ListApiKeys(admin, &pb.ListApiKeysRequest{IncludeRevoked: true})
+	if err != nil {
+		t.Fatalf("ListApiKeys() error = %v", err)
+	}
+	if len(list.GetApiKeys()) != 1 || list.GetApiKeys()[0].GetLastUsedAt() == nil {
+		t.Errorf("ListApiKeys() = %v, want the used key", list.GetApiKeys())
+	}
+}
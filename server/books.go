@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"Booking/auth"
+	pb "Booking/bookserver/test"
+)
+
+// bookFields lists the updatable Book fields. Their names are also the
+// column names in the books table, except for genres, see bookFieldColumn.
+var bookFields = []string{"title", "author", "year", "language", "genres", "price", "quantity", "release_date", "availability", "reorder_threshold"}
+
+// maskedBookFields are the bookFields an UpdateBook request only changes
+// when its update mask names them. An empty mask replaces the others, as it
+// did before these fields existed, so that clients unaware of them do not
+// reset them to their zero values.
+var maskedBookFields = []string{"release_date", "availability", "reorder_threshold"}
+
+// catalogBookFields are the bookFields restored by RevertBook. Stock and
+// availability follow sales and pre-order allocation, so reverting them
+// would undo changes that have nothing to do with the revision.
+var catalogBookFields = []string{"title", "author", "year", "language", "genres", "price", "release_date"}
+
+// bookColumns is the column list matching scanBook. The series name and
+// genre slugs are looked up with unqualified columns, so that the list also
+// works on subqueries and in RETURNING clauses.
+const bookColumns = "id, title, author, year, language, " +
+	"ARRAY(SELECT g.slug FROM unnest(genre_ids) WITH ORDINALITY AS u (id, n) JOIN genres g ON g.id = u.id ORDER BY u.n), " +
+	"price, quantity, updated_at, created_at, created_by, updated_by, cover, rating_sum, rating_count, " +
+	"series_id, series_position, (SELECT s.name FROM series s WHERE s.id = series_id), genre_ids, " +
+	"COALESCE(to_char(release_date, 'YYYY-MM-DD'), ''), availability, reorder_threshold"
+
+// bookChangesChannel is notified by a trigger on the books table with the
+// id of every updated or deleted book.
+const bookChangesChannel = "book_changes"
+
+// bookCacheKey is the ReadBook cache key of a book.
+func bookCacheKey(id int64) string {
+	return "book:" + strconv.FormatInt(id, 10)
+}
+
+// bookCacheKeyFromNotification maps a bookChangesChannel payload to the
+// cache key of the book.
+func bookCacheKeyFromNotification(payload string) string {
+	return "book:" + payload
+}
+
+// bookFieldColumn returns the books column holding a field.
+func bookFieldColumn(field string) string {
+	if field == "genres" {
+		return "genre_ids"
+	}
+	return field
+}
+
+func isBookField(name string) bool {
+	for _, f := range bookFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// updatedFields returns the fields an UpdateBook request changes: the paths
+// of its update mask, or every field but maskedBookFields when the mask is
+// empty.
+func updatedFields(req *pb.UpdateBookRequest) ([]string, error) {
+	paths := req.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		fields := make([]string, 0, len(bookFields))
+		for _, field := range bookFields {
+			if !slices.Contains(maskedBookFields, field) {
+				fields = append(fields, field)
+			}
+		}
+		return fields, nil
+	}
+
+	seen := make(map[string]bool, len(paths))
+	fields := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if path == "genre_ids" {
+			path = "genres"
+		}
+		if !isBookField(path) {
+			return nil, status.Errorf(codes.InvalidArgument, "update_mask: unknown field %q", path)
+		}
+		if !seen[path] {
+			seen[path] = true
+			fields = append(fields, path)
+		}
+	}
+	return fields, nil
+}
+
+// bookFieldValue returns the SQL argument for a field of book.
+func bookFieldValue(book *pb.Book, field string) (interface{}, error) {
+	switch field {
+	case "title":
+		return book.GetTitle(), nil
+	case "author":
+		return book.GetAuthor(), nil
+	case "year":
+		return book.GetYear(), nil
+	case "language":
+		return book.GetLanguage(), nil
+	case "genres":
+		// The genre strings must have been resolved to ids by resolveGenres.
+		ids := book.GetGenreIds()
+		if ids == nil {
+			ids = []int64{}
+		}
+		idsArray := &pgtype.Int8Array{}
+		if err := idsArray.Set(ids); err != nil {
+			return nil, err
+		}
+		return idsArray, nil
+	case "price":
+		return book.GetPrice(), nil
+	case "quantity":
+		return book.GetQuantity(), nil
+	case "release_date":
+		if book.GetReleaseDate() == "" {
+			return nil, nil
+		}
+		if _, err := time.Parse(time.DateOnly, book.GetReleaseDate()); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "release_date %q is not a YYYY-MM-DD date", book.GetReleaseDate())
+		}
+		return book.GetReleaseDate(), nil
+	case "availability":
+		availability := book.GetAvailability()
+		if availability == pb.Book_AVAILABILITY_UNSPECIFIED {
+			availability = pb.Book_IN_STOCK
+		}
+		if _, ok := pb.Book_Availability_name[int32(availability)]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown availability %d", availability)
+		}
+		return strings.ToLower(availability.String()), nil
+	case "reorder_threshold":
+		if book.GetReorderThreshold() < 0 {
+			return nil, status.Error(codes.InvalidArgument, "reorder_threshold must not be negative")
+		}
+		return book.GetReorderThreshold(), nil
+	}
+	return nil, fmt.Errorf("unknown book field %q", field)
+}
+
+// insertBookStatement returns an INSERT statement taking bookFields in
+// order followed by the actor, or, with withID, the id followed by those.
+func insertBookStatement(withID bool) string {
+	var columns, values []string
+	if withID {
+		columns, values = append(columns, "id"), append(values, "$1")
+	}
+	for _, field := range bookFields {
+		columns = append(columns, bookFieldColumn(field))
+		values = append(values, fmt.Sprintf("$%d", len(values)+1))
+	}
+	actor := fmt.Sprintf("$%d", len(values)+1)
+	columns = append(columns, "created_by", "updated_by")
+	values = append(values, actor, actor)
+
+	return fmt.Sprintf(`
+		INSERT INTO books (%s)
+		VALUES (%s)
+		RETURNING %s
+	`, strings.Join(columns, ", "), strings.Join(values, ", "), bookColumns)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanBook reads a book selected with bookColumns.
+func scanBook(row rowScanner) (*pb.Book, error) {
+	book := &pb.Book{}
+	genres := pgtype.TextArray{}
+	genreIDs := pgtype.Int8Array{}
+	var updatedAt, createdAt time.Time
+	var cover []byte
+	var ratingSum int64
+	var seriesID sql.NullInt64
+	var seriesPosition sql.NullFloat64
+	var seriesName sql.NullString
+	var availability string
+
+	err := row.Scan(
+		&book.Id,
+		&book.Title,
+		&book.Author,
+		&book.Year,
+		&book.Language,
+		&genres,
+		&book.Price,
+		&book.Quantity,
+		&updatedAt,
+		&createdAt,
+		&book.CreatedBy,
+		&book.UpdatedBy,
+		&cover,
+		&ratingSum,
+		&book.RatingCount,
+		&seriesID,
+		&seriesPosition,
+		&seriesName,
+		&genreIDs,
+		&book.ReleaseDate,
+		&availability,
+		&book.ReorderThreshold,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := genres.AssignTo(&book.Genres); err != nil {
+		return nil, err
+	}
+	if err := genreIDs.AssignTo(&book.GenreIds); err != nil {
+		return nil, err
+	}
+	book.Availability = pb.Book_Availability(pb.Book_Availability_value[strings.ToUpper(availability)])
+	book.UpdatedAt = timestamppb.New(updatedAt)
+	book.CreatedAt = timestamppb.New(createdAt)
+	if book.RatingCount > 0 {
+		book.RatingAverage = float64(ratingSum) / float64(book.RatingCount)
+	}
+	if seriesID.Valid {
+		book.Series = &pb.BookSeries{Id: seriesID.Int64, Name: seriesName.String, Position: seriesPosition.Float64}
+	}
+	if cover != nil {
+		book.Cover = &pb.Cover{}
+		if err := protojson.Unmarshal(cover, book.Cover); err != nil {
+			return nil, fmt.Errorf("decode cover of book %d: %w", book.Id, err)
+		}
+	}
+	return book, nil
+}
+
+// actor returns the authenticated subject making a change, or "" when
+// authentication is disabled.
+func actor(ctx context.Context) string {
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		return principal.Subject
+	}
+	return ""
+}
+
+// normalizePageSize applies the default and maximum to a requested page
+// size.
+func normalizePageSize(size int32) (int, error) {
+	switch {
+	case size < 0:
+		return 0, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	case size == 0:
+		return defaultPageSize, nil
+	case size > maxPageSize:
+		return maxPageSize, nil
+	}
+	return int(size), nil
+}
+
+// bookPageToken is the position after the last book of a ListBooks page:
+// the transaction that last changed it, see changed_xid, and its id.
+type bookPageToken struct {
+	xid uint64
+	id  int64
+}
+
+// bookPageTokenPrefix tells tokens apart from those of earlier versions,
+// which held an updated_at time instead of a transaction id.
+const bookPageTokenPrefix = "x"
+
+func (t bookPageToken) encode() string {
+	raw := bookPageTokenPrefix + strconv.FormatUint(t.xid, 10) + ":" + strconv.FormatInt(t.id, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeBookPageToken(token string) (bookPageToken, error) {
+	if token == "" {
+		return bookPageToken{}, nil
+	}
+	invalid := status.Error(codes.InvalidArgument, "invalid page_token")
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return bookPageToken{}, invalid
+	}
+	rest, ok := strings.CutPrefix(string(raw), bookPageTokenPrefix)
+	if !ok {
+		return bookPageToken{}, invalid
+	}
+	xid, id, ok := strings.Cut(rest, ":")
+	if !ok {
+		return bookPageToken{}, invalid
+	}
+	x, err := strconv.ParseUint(xid, 10, 64)
+	if err != nil {
+		return bookPageToken{}, invalid
+	}
+	i, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return bookPageToken{}, invalid
+	}
+	return bookPageToken{xid: x, id: i}, nil
+}
+
+// pagedBookRow scans a book selected with bookColumns followed by its
+// changed_xid as text.
+type pagedBookRow struct {
+	rowScanner
+	xid *string
+}
+
+func (r pagedBookRow) Scan(dest ...interface{}) error {
+	return r.rowScanner.Scan(append(dest, r.xid)...)
+}
+
+// changedBookFields is the authz.FieldExtractor for BookingService requests.
+func changedBookFields(req interface{}) ([]string, bool, error) {
+	switch r := req.(type) {
+	case *pb.UpdateBookRequest:
+		fields, err := updatedFields(r)
+		return fields, true, err
+	case *pb.RevertBookRequest:
+		// A revert may restore any catalog field.
+		return catalogBookFields, true, nil
+	}
+	return nil, false, nil
+}
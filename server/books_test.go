@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"Booking/auth"
+	pb "Booking/bookserver/test"
+)
+
+func TestUpdatedFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  []string
+	}{
+		{"empty mask", nil, []string{"title", "author", "year", "language", "genres", "price", "quantity"}},
+		{"named fields", []string{"availability", "title"}, []string{"availability", "title"}},
+		{"genre ids", []string{"genre_ids", "genres"}, []string{"genres"}},
+		{"release date", []string{"release_date"}, []string{"release_date"}},
+		{"reorder threshold", []string{"reorder_threshold"}, []string{"reorder_threshold"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &pb.UpdateBookRequest{UpdateMask: &fieldmaskpb.FieldMask{Paths: tt.paths}}
+			got, err := updatedFields(req)
+			if err != nil {
+				t.Fatalf("updatedFields() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("updatedFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	_, err := updatedFields(&pb.UpdateBookRequest{UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"isbn"}}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("updatedFields() with an unknown path error = %v, want InvalidArgument", err)
+	}
+}
+
+func TestUpdateBookWithoutMaskKeepsPreOrder(t *testing.T) {
+	s := newTestServer(t)
+	book := createTestBook(t, s, &pb.Book{
+		Title:        "Upcoming",
+		Author:       "Author",
+		Year:         2027,
+		Quantity:     5,
+		ReleaseDate:  "2027-03-01",
+		Availability: pb.Book_PRE_ORDER,
+	})
+
+	// A client that predates release dates sends the book it read back
+	// without them.
+	updated, err := s.UpdateBook(asPrincipal("admin", auth.AdminRole), &pb.UpdateBookRequest{
+		Id:   book.GetId(),
+		Book: &pb.Book{Title: "Upcoming, revised", Author: "Author", Year: 2027, Quantity: 5},
+	})
+	if err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+	if updated.GetTitle() != "Upcoming, revised" {
+		t.Errorf("title = %q, want the new one", updated.GetTitle())
+	}
+	if updated.GetAvailability() != pb.Book_PRE_ORDER || updated.GetReleaseDate() != "2027-03-01" {
+		t.Errorf("availability, release date = %v, %q, want PRE_ORDER, 2027-03-01", updated.GetAvailability(), updated.GetReleaseDate())
+	}
+}
+
+func TestUpdateBookWithoutMaskKeepsReorderThreshold(t *testing.T) {
+	s := newTestServer(t)
+	book := createTestBook(t, s, &pb.Book{Title: "Stocked", Author: "Author", Year: 2020, Quantity: 10, ReorderThreshold: 3})
+
+	updated, err := s.UpdateBook(asPrincipal("admin", auth.AdminRole), &pb.UpdateBookRequest{
+		Id:   book.GetId(),
+		Book: &pb.Book{Title: "Stocked", Author: "Author", Year: 2020, Quantity: 8},
+	})
+	if err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+	if updated.GetQuantity() != 8 || updated.GetReorderThreshold() != 3 {
+		t.Errorf("quantity, reorder threshold = %d, %d, want 8, 3", updated.GetQuantity(), updated.GetReorderThreshold())
+	}
+}
+
+func TestBookPageToken(t *testing.T) {
+	token := bookPageToken{xid: 1 << 40, id: 42}
+	got, err := decodeBookPageToken(token.encode())
+	if err != nil || got != token {
+		t.Errorf("decodeBookPageToken(encode()) = %v, %v, want %v", got, err, token)
+	}
+
+	// Tokens of earlier versions held an updated_at time.
+	legacy := base64.RawURLEncoding.EncodeToString([]byte("1700000000000000000:42"))
+	for _, invalid := range []string{legacy, "!", base64.RawURLEncoding.EncodeToString([]byte("x1"))} {
+		if _, err := decodeBookPageToken(invalid); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("decodeBookPageToken(%q) error = %v, want InvalidArgument", invalid, err)
+		}
+	}
+}
+
+// listBookIDs returns the ids of the books listed from pageToken, and the
+// sync token of the last page.
+func listBookIDs(t *testing.T, s *server, pageToken string) ([]int64, string) {
+	t.Helper()
+	var ids []int64
+	req := &pb.ListBooksRequest{PageSize: 1, PageToken: pageToken}
+	for {
+		resp, err := s.ListBooks(context.Background(), req)
+		if err != nil {
+			t.Fatalf("ListBooks() error = %v", err)
+		}
+		for _, book := range resp.GetBooks() {
+			ids = append(ids, book.GetId())
+		}
+		if resp.GetNextPageToken() == "" {
+			return ids, resp.GetSyncToken()
+		}
+		req.PageToken = resp.GetNextPageToken()
+	}
+}
+
+func TestListBooksDoesNotSkipLateCommits(t *testing.T) {
+	s := newTestServer(t)
+	a := createTestBook(t, s, &pb.Book{Title: "A", Author: "Author", Year: 2020})
+	b := createTestBook(t, s, &pb.Book{Title: "B", Author: "Author", Year: 2020})
+
+	ids, syncToken := listBookIDs(t, s, "")
+	if !slices.Equal(ids, []int64{a.GetId(), b.GetId()}) {
+		t.Fatalf("listed %v, want %v", ids, []int64{a.GetId(), b.GetId()})
+	}
+
+	// A changes in a transaction that starts first and commits last.
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `UPDATE books SET quantity = 1 WHERE id = $1`, a.GetId()); err != nil {
+		t.Fatal(err)
+	}
+	setQuantity(t, s, b.GetId(), 2)
+
+	// B is held back until A's transaction finishes.
+	ids, next := listBookIDs(t, s, syncToken)
+	if len(ids) != 0 || next != syncToken {
+		t.Fatalf("listed %v while a transaction is running, want nothing and the same sync token", ids)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	ids, _ = listBookIDs(t, s, syncToken)
+	if !slices.Equal(ids, []int64{a.GetId(), b.GetId()}) {
+		t.Errorf("listed %v after the commit, want %v", ids, []int64{a.GetId(), b.GetId()})
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "Booking/bookserver/test"
+	"Booking/covers"
+	"Booking/logging"
+	"Booking/tracing"
+)
+
+const (
+	// coverFormField is the multipart field holding the uploaded image.
+	coverFormField = "file"
+	// coverChunkSize is the size of the chunks the gateway streams to
+	// UploadCover.
+	coverChunkSize = 64 << 10
+	// multipartOverhead leaves room for the multipart framing of an upload.
+	multipartOverhead = 64 << 10
+)
+
+// receiveCover reads an UploadCover stream and returns the book id and the
+// image.
+func receiveCover(stream pb.BookingService_UploadCoverServer, maxBytes int) (int64, []byte, error) {
+	first, err := stream.Recv()
+	if errors.Is(err, io.EOF) {
+		return 0, nil, status.Error(codes.InvalidArgument, "book_id is required")
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	metadata, ok := first.GetData().(*pb.UploadCoverRequest_BookId)
+	if !ok {
+		return 0, nil, status.Error(codes.InvalidArgument, "the first message must set book_id")
+	}
+
+	var data []byte
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		chunk, ok := req.GetData().(*pb.UploadCoverRequest_Chunk)
+		if !ok {
+			return 0, nil, status.Error(codes.InvalidArgument, "only the first message may set book_id")
+		}
+		if len(data)+len(chunk.Chunk) > maxBytes {
+			return 0, nil, status.Errorf(codes.InvalidArgument, "cover image exceeds %d bytes", maxBytes)
+		}
+		data = append(data, chunk.Chunk...)
+	}
+	if len(data) == 0 {
+		return 0, nil, status.Error(codes.InvalidArgument, "cover image is empty")
+	}
+	return metadata.BookId, data, nil
+}
+
+func (s *server) UploadCover(stream pb.BookingService_UploadCoverServer) error {
+	ctx := stream.Context()
+
+	bookID, data, err := receiveCover(stream, s.covers.MaxBytes())
+	if err != nil {
+		return err
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	var exists bool
+	err = s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM books WHERE id = $1)`, bookID).Scan(&exists)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to upload cover", "error", err)
+		return err
+	}
+	if !exists {
+		return status.Errorf(codes.NotFound, "book %d not found", bookID)
+	}
+
+	version, err := s.covers.Save(ctx, bookID, data)
+	if err != nil {
+		if errors.Is(err, covers.ErrTooLarge) || errors.Is(err, covers.ErrUnsupportedType) || errors.Is(err, covers.ErrInvalidImage) {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		logging.FromContext(ctx).Error("Failed to store cover", "error", err)
+		return status.Error(codes.Internal, "failed to store cover")
+	}
+
+	url, thumbnails := s.covers.URLs(bookID, version)
+	cover, err := protojson.Marshal(&pb.Cover{Url: url, ThumbnailUrls: thumbnails, Version: version})
+	if err != nil {
+		s.covers.Delete(ctx, bookID, version)
+		return err
+	}
+
+	sqlStatement := `
+		UPDATE books
+		SET cover = $1, updated_at = now(), updated_by = $2
+		WHERE id = $3
+		RETURNING ` + bookColumns
+
+	var book, before *pb.Book
+	err = s.inTx(ctx, func(tx *tracing.Tx) error {
+		var err error
+		before, err = lockBook(ctx, tx, bookID)
+		if err != nil {
+			return err
+		}
+		// JSONB parameters must be sent as text.
+		book, err = scanBook(tx.QueryRowContext(ctx, sqlStatement, string(cover), actor(ctx), bookID))
+		if err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, bookID, pb.BookRevision_UPDATE, before, book)
+	})
+	if err != nil {
+		// The new version is not referenced by the book.
+		s.covers.Delete(context.WithoutCancel(ctx), bookID, version)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return status.Errorf(codes.NotFound, "book %d not found", bookID)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to upload cover", "error", err)
+		return err
+	}
+	s.books.Invalidate(ctx, bookCacheKey(bookID))
+	// Only revisions of the book still name the previous version, as
+	// history.
+	if previous := before.GetCover(); previous != nil {
+		s.covers.Delete(ctx, bookID, previous.GetVersion())
+	}
+
+	return stream.SendAndClose(book)
+}
+
+// coverPart returns the image part of a multipart/form-data upload.
+func coverPart(r *http.Request) (*multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "cover uploads must be multipart/form-data")
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, status.Errorf(codes.InvalidArgument, "missing %q field", coverFormField)
+		}
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "malformed multipart body")
+		}
+		if part.FormName() == coverFormField {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// uploadCoverHandler serves POST /books/{id}/cover. It streams the "file"
+// field of a multipart/form-data body to UploadCover through client, so that
+// the upload passes the same interceptors as every other gateway request.
+func uploadCoverHandler(mux *runtime.ServeMux, client pb.BookingServiceClient, maxBytes int) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+
+		bookID, err := strconv.ParseInt(pathParams["id"], 10, 64)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, status.Errorf(codes.InvalidArgument, "invalid book id %q", pathParams["id"]))
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes)+multipartOverhead)
+		part, err := coverPart(r)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+
+		ctx, err = runtime.AnnotateContext(ctx, mux, r, pb.BookingService_UploadCover_FullMethodName, runtime.WithHTTPPathPattern("/books/{id}/cover"))
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+		var md runtime.ServerMetadata
+		stream, err := client.UploadCover(ctx, grpc.Header(&md.HeaderMD), grpc.Trailer(&md.TrailerMD))
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+
+		// A failed Send means the server has ended the call; its status is
+		// returned by CloseAndRecv.
+		err = stream.Send(&pb.UploadCoverRequest{Data: &pb.UploadCoverRequest_BookId{BookId: bookID}})
+		buf := make([]byte, coverChunkSize)
+		for err == nil {
+			n, readErr := part.Read(buf)
+			if n > 0 {
+				err = stream.Send(&pb.UploadCoverRequest{Data: &pb.UploadCoverRequest_Chunk{Chunk: buf[:n]}})
+			}
+			if errors.Is(readErr, io.EOF) {
+				break
+			}
+			if readErr != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(readErr, &tooLarge) {
+					readErr = status.Errorf(codes.InvalidArgument, "cover image exceeds %d bytes", maxBytes)
+				} else {
+					readErr = status.Error(codes.InvalidArgument, "failed to read cover upload")
+				}
+				runtime.HTTPError(ctx, mux, outbound, w, r, readErr)
+				return
+			}
+		}
+
+		book, err := stream.CloseAndRecv()
+		ctx = runtime.NewServerMetadataContext(ctx, md)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outbound, w, r, err)
+			return
+		}
+		runtime.ForwardResponseMessage(ctx, mux, outbound, w, r, book)
+	}
+}
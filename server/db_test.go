@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"Booking/auth"
+	pb "Booking/bookserver/test"
+	"Booking/migrations"
+	"Booking/tracing"
+	"Booking/webhooks"
+)
+
+// testDSNEnv names the environment variable holding the DSN of a scratch
+// PostgreSQL database. Tests that need one are skipped when it is unset;
+// each of them migrates the database and empties every table first. The
+// packages share it, so they must run one at a time:
+//
+//	TEST_DATABASE_DSN=postgres://localhost/booking_test go test -p 1 ./...
+const testDSNEnv = "TEST_DATABASE_DSN"
+
+// truncateStatement empties every table but schema_migrations.
+const truncateStatement = `
+	DO $$
+	BEGIN
+		EXECUTE (
+			SELECT 'TRUNCATE ' || string_agg(format('%I', tablename), ', ') || ' RESTART IDENTITY CASCADE'
+			FROM pg_tables
+			WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'
+		);
+	END
+	$$
+`
+
+// newTestServer returns a server backed by the database of testDSNEnv.
+func newTestServer(t *testing.T) *server {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	if err := migrations.Apply(ctx, db); err != nil {
+		t.Fatalf("migrations.Apply() error = %v", err)
+	}
+	if _, err := db.ExecContext(ctx, truncateStatement); err != nil {
+		t.Fatalf("failed to empty the database: %v", err)
+	}
+	tracedDB := tracing.WrapDB(db)
+	return &server{db: tracedDB, webhooks: webhooks.NewStore(tracedDB)}
+}
+
+// asPrincipal returns a context authenticated as subject with roles.
+func asPrincipal(subject string, roles ...string) context.Context {
+	return auth.ContextWithPrincipal(context.Background(), &auth.Principal{Subject: subject, Roles: roles})
+}
+
+// createTestBook creates book as an admin and returns it as stored.
+func createTestBook(t *testing.T, s *server, book *pb.Book) *pb.Book {
+	t.Helper()
+	created, err := s.CreateBook(asPrincipal("admin", auth.AdminRole), &pb.CreateBookRequest{Book: book})
+	if err != nil {
+		t.Fatalf("CreateBook() error = %v", err)
+	}
+	return created
+}
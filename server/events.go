@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "Booking/bookserver/test"
+	"Booking/tracing"
+	"Booking/webhooks"
+)
+
+// Event types, which are also the AMQP routing keys.
+const (
+	eventBookCreated = "book.created"
+	eventBookUpdated = "book.updated"
+	eventBookDeleted = "book.deleted"
+	eventLowStock    = "book.low_stock"
+	eventBackInStock = "book.back_in_stock"
+)
+
+// eventTypes lists the event types webhook endpoints can filter on.
+var eventTypes = []string{eventBookCreated, eventBookUpdated, eventBookDeleted, eventLowStock, eventBackInStock}
+
+// recordEvent adds an event about the book bookID to the outbox and queues
+// its webhook deliveries. It is published once tx commits.
+func recordEvent(ctx context.Context, tx *tracing.Tx, bookID int64, event *pb.Event) error {
+	// The id is taken first so that the payload can carry it.
+	if err := tx.QueryRowContext(ctx, `SELECT nextval('events_id_seq')`).Scan(&event.Id); err != nil {
+		return err
+	}
+	event.CreatedAt = timestamppb.Now()
+	payload, err := protojson.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	sqlStatement := `
+		INSERT INTO events (id, type, book_id, payload, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	// JSONB parameters must be sent as text, lib/pq sends []byte as bytea.
+	_, err = tx.ExecContext(ctx, sqlStatement, event.GetId(), event.GetType(), bookID, string(payload), event.GetCreatedAt().AsTime())
+	if err != nil {
+		return err
+	}
+	return webhooks.Enqueue(ctx, tx, event.GetId(), event.GetType(), payload)
+}
+
+// recordBookEvents records the events caused by a change of the book id.
+func recordBookEvents(ctx context.Context, tx *tracing.Tx, id int64, before, after *pb.Book) error {
+	eventType := eventBookUpdated
+	switch {
+	case before == nil:
+		eventType = eventBookCreated
+	case after == nil:
+		eventType = eventBookDeleted
+	}
+	event := &pb.Event{
+		Type:    eventType,
+		Payload: &pb.Event_BookChanged{BookChanged: &pb.BookChangedEvent{Before: before, After: after, Actor: actor(ctx)}},
+	}
+	if err := recordEvent(ctx, tx, id, event); err != nil {
+		return err
+	}
+	return recordStockEvents(ctx, tx, before, after)
+}
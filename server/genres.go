@@ -0,0 +1,467 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "Booking/bookserver/test"
+	"Booking/logging"
+	"Booking/tracing"
+)
+
+// genreColumns is the column list matching scanGenre.
+const genreColumns = "id, parent_id, slug, names, " +
+	"ARRAY(SELECT a.alias FROM genre_aliases a WHERE a.genre_id = genres.id ORDER BY a.alias)"
+
+var validGenreSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// normalizeGenreName brings a genre string into the form of the aliases in
+// genre_aliases: lower-case with single spaces.
+func normalizeGenreName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func scanGenre(row rowScanner) (*pb.Genre, error) {
+	genre := &pb.Genre{}
+	var parentID sql.NullInt64
+	var names []byte
+	var aliases pq.StringArray
+
+	if err := row.Scan(&genre.Id, &parentID, &genre.Slug, &names, &aliases); err != nil {
+		return nil, err
+	}
+	genre.ParentId = parentID.Int64
+	if err := json.Unmarshal(names, &genre.Names); err != nil {
+		return nil, err
+	}
+	genre.Aliases = aliases
+	return genre, nil
+}
+
+// resolveGenres sets the genre ids of a book that is about to be written in
+// tx. Unless ids are given, its genre strings are resolved through genre
+// slugs and aliases. Unknown genres are an InvalidArgument error. The genres
+// are locked until tx ends, so that they cannot be deleted before the book
+// referencing them is written.
+func resolveGenres(ctx context.Context, tx *tracing.Tx, book *pb.Book) error {
+	if len(book.GetGenreIds()) == 0 && len(book.GetGenres()) > 0 {
+		names := make([]string, len(book.GetGenres()))
+		for i, name := range book.GetGenres() {
+			names[i] = normalizeGenreName(name)
+		}
+
+		sqlStatement := `
+			SELECT n.name, COALESCE(g.id, a.genre_id)
+			FROM unnest($1::text[]) AS n (name)
+			LEFT JOIN genres g ON g.slug = n.name
+			LEFT JOIN genre_aliases a ON a.alias = n.name
+		`
+		rows, err := tx.QueryContext(ctx, sqlStatement, pq.Array(names))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		resolved := make(map[string]int64, len(names))
+		for rows.Next() {
+			var name string
+			var id sql.NullInt64
+			if err := rows.Scan(&name, &id); err != nil {
+				return err
+			}
+			if id.Valid {
+				resolved[name] = id.Int64
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		ids := make([]int64, 0, len(names))
+		for i, name := range names {
+			id, ok := resolved[name]
+			if !ok {
+				return status.Errorf(codes.InvalidArgument, "unknown genre %q", book.GetGenres()[i])
+			}
+			ids = append(ids, id)
+		}
+		book.GenreIds = ids
+	}
+
+	// Duplicates are dropped, keeping the first occurrence.
+	seen := make(map[int64]bool, len(book.GetGenreIds()))
+	ids := book.GetGenreIds()[:0]
+	for _, id := range book.GetGenreIds() {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	book.GenreIds = ids
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM genres WHERE id = ANY($1) FOR SHARE`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	found := 0
+	for rows.Next() {
+		found++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if found != len(ids) {
+		return status.Error(codes.InvalidArgument, "genre_ids contains unknown genres")
+	}
+	return nil
+}
+
+// validateGenre checks a genre of a CreateGenre or UpdateGenre request and
+// normalizes its aliases.
+func validateGenre(genre *pb.Genre) error {
+	if genre == nil {
+		return status.Error(codes.InvalidArgument, "genre is required")
+	}
+	if !validGenreSlug.MatchString(genre.GetSlug()) {
+		return status.Error(codes.InvalidArgument, "slug must consist of lower-case letters, digits and dashes")
+	}
+	for locale, name := range genre.GetNames() {
+		if _, err := language.Parse(locale); err != nil {
+			return status.Errorf(codes.InvalidArgument, "names: invalid locale %q", locale)
+		}
+		if strings.TrimSpace(name) == "" {
+			return status.Errorf(codes.InvalidArgument, "names: empty name for %q", locale)
+		}
+	}
+
+	aliases := make([]string, 0, len(genre.GetAliases()))
+	seen := make(map[string]bool, len(genre.GetAliases()))
+	for _, alias := range genre.GetAliases() {
+		alias = normalizeGenreName(alias)
+		if alias == "" {
+			return status.Error(codes.InvalidArgument, "aliases must not be empty")
+		}
+		if !seen[alias] && alias != genre.GetSlug() {
+			seen[alias] = true
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	genre.Aliases = aliases
+	return nil
+}
+
+// writeGenreAliases replaces the aliases of a genre.
+func writeGenreAliases(ctx context.Context, tx *tracing.Tx, id int64, aliases []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM genre_aliases WHERE genre_id = $1`, id); err != nil {
+		return err
+	}
+	sqlStatement := `
+		INSERT INTO genre_aliases (alias, genre_id)
+		SELECT alias, $2 FROM unnest($1::text[]) AS alias
+	`
+	_, err := tx.ExecContext(ctx, sqlStatement, pq.Array(aliases), id)
+	return err
+}
+
+// genreWriteError maps constraint violations of genre writes to status
+// errors.
+func genreWriteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch {
+		case pqErr.Code == foreignKeyViolation:
+			return status.Error(codes.NotFound, "parent genre not found")
+		case pqErr.Code == uniqueViolation && pqErr.Table == "genre_aliases":
+			return status.Error(codes.AlreadyExists, "an alias is already used by another genre")
+		case pqErr.Code == uniqueViolation:
+			return status.Error(codes.AlreadyExists, "slug is already used by another genre")
+		}
+	}
+	return err
+}
+
+func nullableID(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+func (s *server) CreateGenre(ctx context.Context, req *pb.CreateGenreRequest) (*pb.Genre, error) {
+	genre := req.GetGenre()
+	if err := validateGenre(genre); err != nil {
+		return nil, err
+	}
+	names, err := json.Marshal(genre.GetNames())
+	if err != nil {
+		return nil, err
+	}
+
+	var created *pb.Genre
+	err = s.inTx(ctx, func(tx *tracing.Tx) error {
+		var id int64
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO genres (parent_id, slug, names)
+			VALUES ($1, $2, $3)
+			RETURNING id
+		`, nullableID(genre.GetParentId()), genre.GetSlug(), string(names)).Scan(&id)
+		if err != nil {
+			return err
+		}
+		if err := writeGenreAliases(ctx, tx, id, genre.GetAliases()); err != nil {
+			return err
+		}
+		created, err = scanGenre(tx.QueryRowContext(ctx, `SELECT `+genreColumns+` FROM genres WHERE id = $1`, id))
+		return err
+	})
+	if err := genreWriteError(err); err != nil {
+		if _, ok := status.FromError(err); !ok {
+			logging.FromContext(ctx).Error("Failed to create genre", "error", err)
+		}
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *server) GetGenre(ctx context.Context, req *pb.GetGenreRequest) (*pb.Genre, error) {
+	genre, err := scanGenre(s.db.QueryRowContext(ctx, `SELECT `+genreColumns+` FROM genres WHERE id = $1`, req.GetId()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "genre %d not found", req.GetId())
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get genre", "error", err)
+		return nil, err
+	}
+	return genre, nil
+}
+
+func (s *server) ListGenres(ctx context.Context, _ *pb.ListGenresRequest) (*pb.ListGenresResponse, error) {
+	// Genres are ordered by depth, so that parents come first.
+	sqlStatement := `
+		WITH RECURSIVE tree AS (
+			SELECT id, 0 AS depth FROM genres WHERE parent_id IS NULL
+			UNION ALL
+			SELECT g.id, tree.depth + 1 FROM genres g JOIN tree ON g.parent_id = tree.id
+		)
+		SELECT ` + genreColumns + `
+		FROM genres
+		JOIN tree USING (id)
+		ORDER BY tree.depth, genres.slug
+	`
+
+	rows, err := s.db.QueryContext(ctx, sqlStatement)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to list genres", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	response := &pb.ListGenresResponse{}
+	for rows.Next() {
+		genre, err := scanGenre(rows)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to list genres", "error", err)
+			return nil, err
+		}
+		response.Genres = append(response.Genres, genre)
+	}
+	if err := rows.Err(); err != nil {
+		logging.FromContext(ctx).Error("Failed to list genres", "error", err)
+		return nil, err
+	}
+	return response, nil
+}
+
+func (s *server) UpdateGenre(ctx context.Context, req *pb.UpdateGenreRequest) (*pb.Genre, error) {
+	genreID := req.GetId()
+	genre := req.GetGenre()
+	if err := validateGenre(genre); err != nil {
+		return nil, err
+	}
+	if genre.GetParentId() == genreID {
+		return nil, status.Error(codes.InvalidArgument, "a genre cannot be its own parent")
+	}
+	names, err := json.Marshal(genre.GetNames())
+	if err != nil {
+		return nil, err
+	}
+
+	// The ancestors of the new parent must not include the genre itself.
+	cycleCheck := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM genres WHERE id = $1
+			UNION ALL
+			SELECT g.id, g.parent_id FROM genres g JOIN ancestors a ON g.id = a.parent_id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)
+	`
+
+	var updated *pb.Genre
+	var touched []int64
+	err = s.inTx(ctx, func(tx *tracing.Tx) error {
+		// Concurrent moves could otherwise form a cycle together.
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE genres IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+		var oldSlug string
+		if err := tx.QueryRowContext(ctx, `SELECT slug FROM genres WHERE id = $1`, genreID).Scan(&oldSlug); err != nil {
+			return err
+		}
+		if genre.GetParentId() != 0 {
+			var cycle bool
+			if err := tx.QueryRowContext(ctx, cycleCheck, genre.GetParentId(), genreID).Scan(&cycle); err != nil {
+				return err
+			}
+			if cycle {
+				return status.Error(codes.InvalidArgument, "parent_id would make the genre its own ancestor")
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, `
+			UPDATE genres
+			SET parent_id = $1, slug = $2, names = $3
+			WHERE id = $4
+		`, nullableID(genre.GetParentId()), genre.GetSlug(), string(names), genreID)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			if err == nil {
+				err = sql.ErrNoRows
+			}
+			return err
+		}
+		if err := writeGenreAliases(ctx, tx, genreID, genre.GetAliases()); err != nil {
+			return err
+		}
+		if genre.GetSlug() != oldSlug {
+			if touched, err = touchGenreBooks(ctx, tx, genreID, oldSlug); err != nil {
+				return err
+			}
+		}
+		updated, err = scanGenre(tx.QueryRowContext(ctx, `SELECT `+genreColumns+` FROM genres WHERE id = $1`, genreID))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "genre %d not found", genreID)
+	}
+	if err := genreWriteError(err); err != nil {
+		if _, ok := status.FromError(err); !ok {
+			logging.FromContext(ctx).Error("Failed to update genre", "error", err)
+		}
+		return nil, err
+	}
+	for _, id := range touched {
+		s.books.Invalidate(ctx, bookCacheKey(id))
+	}
+
+	return updated, nil
+}
+
+// touchGenreBooks records a change of every book of a genre that was just
+// renamed from oldSlug, as books show genre slugs: like any other change, it
+// moves their update time and actor on, which changes their ETag and
+// Last-Modified and notifies every replica to drop them from its cache, and
+// records a revision and a book.updated event. It returns their ids.
+//
+// The genre row must be locked by the rename, so that no book write adds the
+// genre concurrently.
+func touchGenreBooks(ctx context.Context, tx *tracing.Tx, genreID int64, oldSlug string) ([]int64, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT `+bookColumns+`
+		FROM books
+		WHERE genre_ids @> ARRAY[$1::bigint]
+		ORDER BY id
+		FOR UPDATE
+	`, genreID)
+	if err != nil {
+		return nil, err
+	}
+	var books []*pb.Book
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		books = append(books, book)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(books))
+	for _, before := range books {
+		// The books were read with the new slug.
+		for i, id := range before.GetGenreIds() {
+			if id == genreID && i < len(before.Genres) {
+				before.Genres[i] = oldSlug
+			}
+		}
+		after, err := writeBookFields(ctx, tx, before.GetId(), before, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := recordRevision(ctx, tx, before.GetId(), pb.BookRevision_UPDATE, before, after); err != nil {
+			return nil, err
+		}
+		ids = append(ids, before.GetId())
+	}
+	return ids, nil
+}
+
+func (s *server) DeleteGenre(ctx context.Context, req *pb.DeleteGenreRequest) (*pb.DeleteGenreResponse, error) {
+	genreID := req.GetId()
+
+	err := s.inTx(ctx, func(tx *tracing.Tx) error {
+		if _, err := tx.ExecContext(ctx, `LOCK TABLE genres IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+		// The row lock waits for book writes holding the genre, so that the
+		// books they wrote are seen below.
+		if _, err := tx.ExecContext(ctx, `SELECT 1 FROM genres WHERE id = $1 FOR UPDATE`, genreID); err != nil {
+			return err
+		}
+		var hasChildren, hasBooks bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT
+				EXISTS (SELECT 1 FROM genres WHERE parent_id = $1),
+				EXISTS (SELECT 1 FROM books WHERE genre_ids @> ARRAY[$1::bigint])
+		`, genreID).Scan(&hasChildren, &hasBooks)
+		if err != nil {
+			return err
+		}
+		if hasChildren {
+			return status.Errorf(codes.FailedPrecondition, "genre %d has child genres", genreID)
+		}
+		if hasBooks {
+			return status.Errorf(codes.FailedPrecondition, "genre %d is used by books", genreID)
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM genres WHERE id = $1`, genreID)
+		return err
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); !ok {
+			logging.FromContext(ctx).Error("Failed to delete genre", "error", err)
+		}
+		return nil, err
+	}
+
+	return &pb.DeleteGenreResponse{Success: true}, nil
+}
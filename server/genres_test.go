@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"Booking/auth"
+	pb "Booking/bookserver/test"
+)
+
+// createTestGenre creates a genre under parentID, 0 for a top-level one.
+func createTestGenre(t *testing.T, s *server, slug string, parentID int64) *pb.Genre {
+	t.Helper()
+	genre, err := s.CreateGenre(asPrincipal("admin", auth.AdminRole), &pb.CreateGenreRequest{Genre: &pb.Genre{Slug: slug, ParentId: parentID}})
+	if err != nil {
+		t.Fatalf("CreateGenre(%q) error = %v", slug, err)
+	}
+	return genre
+}
+
+func TestUpdateGenreRejectsCycles(t *testing.T) {
+	s := newTestServer(t)
+	fiction := createTestGenre(t, s, "fiction", 0)
+	fantasy := createTestGenre(t, s, "fantasy", fiction.GetId())
+	epic := createTestGenre(t, s, "epic-fantasy", fantasy.GetId())
+	other := createTestGenre(t, s, "other", 0)
+
+	tests := []struct {
+		name   string
+		id     int64
+		parent int64
+		want   codes.Code
+	}{
+		{"own parent", fiction.GetId(), fiction.GetId(), codes.InvalidArgument},
+		{"under its child", fiction.GetId(), fantasy.GetId(), codes.InvalidArgument},
+		{"under its grandchild", fiction.GetId(), epic.GetId(), codes.InvalidArgument},
+		{"under a missing genre", fiction.GetId(), 1 << 40, codes.NotFound},
+		{"under another tree", fiction.GetId(), other.GetId(), codes.OK},
+		{"back to the top", fiction.GetId(), 0, codes.OK},
+		{"child under its grandparent's sibling", epic.GetId(), other.GetId(), codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			genre, err := s.GetGenre(context.Background(), &pb.GetGenreRequest{Id: tt.id})
+			if err != nil {
+				t.Fatal(err)
+			}
+			genre.ParentId = tt.parent
+			_, err = s.UpdateGenre(asPrincipal("admin", auth.AdminRole), &pb.UpdateGenreRequest{Id: tt.id, Genre: genre})
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("UpdateGenre() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenameGenreRecordsBookChanges(t *testing.T) {
+	s := newTestServer(t)
+	fiction := createTestGenre(t, s, "fiction", 0)
+	scifi := createTestGenre(t, s, "scifi", fiction.GetId())
+	book := createTestBook(t, s, &pb.Book{Title: "Dune", Author: "Frank Herbert", Year: 1965, Genres: []string{"fiction", "scifi"}})
+	other := createTestBook(t, s, &pb.Book{Title: "Emma", Author: "Jane Austen", Year: 1815, Genres: []string{"fiction"}})
+
+	scifi.Slug = "science-fiction"
+	ctx := asPrincipal("curator", auth.AdminRole)
+	if _, err := s.UpdateGenre(ctx, &pb.UpdateGenreRequest{Id: scifi.GetId(), Genre: scifi}); err != nil {
+		t.Fatalf("UpdateGenre() error = %v", err)
+	}
+
+	revisions, err := s.ListBookRevisions(ctx, &pb.ListBookRevisionsRequest{BookId: book.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions.GetRevisions()) != 2 {
+		t.Fatalf("%d revisions, want the creation and the rename", len(revisions.GetRevisions()))
+	}
+	rename := revisions.GetRevisions()[0]
+	if rename.GetOperation() != pb.BookRevision_UPDATE || rename.GetActor() != "curator" {
+		t.Errorf("revision = %v by %q, want an update by curator", rename.GetOperation(), rename.GetActor())
+	}
+	if got := rename.GetBefore().GetGenres(); !slices.Equal(got, []string{"fiction", "scifi"}) {
+		t.Errorf("genres before = %v, want the old slug", got)
+	}
+	if got := rename.GetAfter().GetGenres(); !slices.Equal(got, []string{"fiction", "science-fiction"}) {
+		t.Errorf("genres after = %v, want the new slug", got)
+	}
+	if rename.GetAfter().GetUpdatedBy() != "curator" || !rename.GetAfter().GetUpdatedAt().AsTime().After(book.GetUpdatedAt().AsTime()) {
+		t.Errorf("book updated by %q at %v, want curator after %v", rename.GetAfter().GetUpdatedBy(), rename.GetAfter().GetUpdatedAt().AsTime(), book.GetUpdatedAt().AsTime())
+	}
+	if n := countEvents(t, s, eventBookUpdated); n != 1 {
+		t.Errorf("%d book.updated events, want 1", n)
+	}
+
+	revisions, err = s.ListBookRevisions(ctx, &pb.ListBookRevisionsRequest{BookId: other.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions.GetRevisions()) != 1 {
+		t.Errorf("a book without the genre has %d revisions, want 1", len(revisions.GetRevisions()))
+	}
+}
@@ -2,151 +2,374 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
-
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
-
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"Booking/apikey"
+	"Booking/auth"
+	"Booking/authz"
+	"Booking/blob"
 	pb "Booking/bookserver/test"
+	"Booking/cache"
+	"Booking/config"
+	"Booking/covers"
+	"Booking/events"
+	"Booking/health"
+	"Booking/httpcache"
+	"Booking/idempotency"
+	"Booking/logging"
+	"Booking/metrics"
+	"Booking/migrations"
+	"Booking/ratelimit"
+	"Booking/tlsconfig"
+	"Booking/tracing"
+	"Booking/webhooks"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
-	"github.com/jackc/pgtype"
 	_ "github.com/lib/pq"
 )
 
+// publicMethods can be called without a bearer token. All other methods,
+// including every write RPC, require one.
+var publicMethods = map[string]bool{
+	pb.BookingService_ReadBook_FullMethodName:             true,
+	pb.BookingService_ListBooks_FullMethodName:            true,
+	pb.BookingService_GetRelatedBooks_FullMethodName:      true,
+	pb.BookingService_ListReviews_FullMethodName:          true,
+	pb.BookingService_GetSeries_FullMethodName:            true,
+	pb.BookingService_GetSeriesNeighbors_FullMethodName:   true,
+	pb.BookingService_GetGenre_FullMethodName:             true,
+	pb.BookingService_ListGenres_FullMethodName:           true,
+	pb.BookingService_ListBookTranslations_FullMethodName: true,
+	"/grpc.health.v1.Health/":                             true,
+}
+
+// idempotentMethods honour the idempotency-key metadata. CreateApiKey and
+// CreateWebhookEndpoint are left out so that their secrets are never stored
+// with a response.
+var idempotentMethods = map[string]bool{
+	pb.BookingService_CreateBook_FullMethodName:              true,
+	pb.BookingService_UpdateBook_FullMethodName:              true,
+	pb.BookingService_DeleteBook_FullMethodName:              true,
+	pb.BookingService_RevertBook_FullMethodName:              true,
+	pb.BookingService_CreateReview_FullMethodName:            true,
+	pb.BookingService_ModerateReview_FullMethodName:          true,
+	pb.BookingService_CreateSeries_FullMethodName:            true,
+	pb.BookingService_SetBookSeries_FullMethodName:           true,
+	pb.BookingService_CreateGenre_FullMethodName:             true,
+	pb.BookingService_UpdateGenre_FullMethodName:             true,
+	pb.BookingService_DeleteGenre_FullMethodName:             true,
+	pb.BookingService_SetBookTranslation_FullMethodName:      true,
+	pb.BookingService_DeleteBookTranslation_FullMethodName:   true,
+	pb.BookingService_CreatePreOrder_FullMethodName:          true,
+	pb.BookingService_CancelReservation_FullMethodName:       true,
+	pb.BookingService_SubscribeBackInStock_FullMethodName:    true,
+	pb.BookingService_UnsubscribeBackInStock_FullMethodName:  true,
+	pb.BookingService_UpdateWebhookEndpoint_FullMethodName:   true,
+	pb.BookingService_DeleteWebhookEndpoint_FullMethodName:   true,
+	pb.BookingService_ReplayWebhookDeliveries_FullMethodName: true,
+}
+
 const (
-	port = ":50052"
+	// Page sizes of the List RPCs.
+	defaultPageSize = 50
+	maxPageSize     = 1000
+
+	idempotencyCleanupInterval = 10 * time.Minute
+	eventsCleanupInterval      = time.Hour
+
+	// Rate limit buckets unused for rateLimitIdleTimeout are dropped.
+	rateLimitCleanupInterval = time.Minute
+	rateLimitIdleTimeout     = 10 * time.Minute
 )
 
 type server struct {
 	pb.UnimplementedBookingServiceServer
-	db *sql.DB
+	db      *tracing.DB
+	apiKeys *apikey.Store
+	// books caches ReadBook results. It is nil when caching is disabled.
+	books  *cache.Cache
+	covers *covers.Service
+	// webhooks holds the webhook endpoints and their deliveries.
+	webhooks *webhooks.Store
+	// fallbackLocales are tried after the locales a caller prefers.
+	fallbackLocales []string
 }
 
 func (s *server) CreateBook(ctx context.Context, req *pb.CreateBookRequest) (*pb.Book, error) {
 	book := req.GetBook()
-
-	sqlStatement := `
-		INSERT INTO books (title, author, year, language, genres, price, quantity)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id
-	`
-
-	genresArray := &pgtype.TextArray{}
-	if err := genresArray.Set(book.Genres); err != nil {
-		log.Printf("Failed to convert genres to array-compatible format: %v", err)
-		return nil, err
+	if book == nil {
+		return nil, status.Error(codes.InvalidArgument, "book is required")
 	}
+	sqlStatement := insertBookStatement(false)
+
+	var created *pb.Book
+	err := s.inTx(ctx, func(tx *tracing.Tx) error {
+		if err := resolveGenres(ctx, tx, book); err != nil {
+			return err
+		}
+		args := make([]interface{}, 0, len(bookFields)+1)
+		for _, field := range bookFields {
+			value, err := bookFieldValue(book, field)
+			if err != nil {
+				return err
+			}
+			args = append(args, value)
+		}
+		args = append(args, actor(ctx))
 
-	var id int64
-	err := s.db.QueryRowContext(
-		ctx,
-		sqlStatement,
-		book.Title,
-		book.Author,
-		book.Year,
-		book.Language,
-		genresArray,
-		book.Price,
-		book.Quantity,
-	).Scan(&id)
-	if err != nil {
-		log.Printf("Failed to create book: %v", err)
+		var err error
+		created, err = scanBook(tx.QueryRowContext(ctx, sqlStatement, args...))
+		if err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, created.Id, pb.BookRevision_CREATE, nil, created)
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); !ok {
+			logging.FromContext(ctx).Error("Failed to create book", "error", err)
+		}
 		return nil, err
 	}
 
-	book.Id = id
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", created.Id))
 
-	return book, nil
+	return created, nil
 }
 
 func (s *server) ReadBook(ctx context.Context, req *pb.ReadBookRequest) (*pb.Book, error) {
 	bookID := req.GetId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	if req.GetAsOf() != nil {
+		return s.readBookAsOf(ctx, bookID, req.GetAsOf().AsTime())
+	}
 
 	sqlStatement := `
-		SELECT id, title, author, year, language, genres, price, quantity
+		SELECT ` + bookColumns + `
 		FROM books
 		WHERE id = $1
 	`
 
-	row := s.db.QueryRowContext(ctx, sqlStatement, bookID)
+	data, err := s.books.Get(ctx, bookCacheKey(bookID), func(ctx context.Context) ([]byte, error) {
+		book, err := scanBook(s.db.QueryRowContext(ctx, sqlStatement, bookID))
+		if err != nil {
+			return nil, err
+		}
+		// Translations are cached with the book and picked per request.
+		if err := s.loadTranslations(ctx, book); err != nil {
+			return nil, err
+		}
+		return proto.Marshal(book)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to read book", "error", err)
+		return nil, err
+	}
 
 	book := &pb.Book{}
+	if err := proto.Unmarshal(data, book); err != nil {
+		logging.FromContext(ctx).Error("Failed to decode cached book", "error", err)
+		return nil, err
+	}
+	localizeBook(book, s.localeChain(ctx, req.GetLocale()))
+	if !req.GetIncludeTranslations() {
+		book.Translations = nil
+	}
+	return book, nil
+}
 
-	err := row.Scan(
-		&book.Id,
-		&book.Title,
-		&book.Author,
-		&book.Year,
-		&book.Language,
-		&book.Genres,
-		&book.Price,
-		&book.Quantity,
-	)
+func (s *server) ListBooks(ctx context.Context, req *pb.ListBooksRequest) (*pb.ListBooksResponse, error) {
+	pageSize, err := normalizePageSize(req.GetPageSize())
 	if err != nil {
-		log.Printf("Failed to read book: %v", err)
 		return nil, err
 	}
+	after, err := decodeBookPageToken(req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	var updatedSince time.Time
+	if req.GetUpdatedSince() != nil {
+		updatedSince = req.GetUpdatedSince().AsTime()
+	}
+	var availability string
+	if a := req.GetAvailability(); a != pb.Book_AVAILABILITY_UNSPECIFIED {
+		availability = strings.ToLower(a.String())
+	}
 
-	return book, nil
+	// Books are listed in the order of the transaction that last changed
+	// them, and only up to the oldest transaction still running: any change
+	// that commits later has a higher changed_xid than every book listed, so
+	// it is not missed by a client resuming from a page token.
+	sqlStatement := `
+		SELECT ` + bookColumns + `, changed_xid::text
+		FROM books
+		WHERE updated_at >= $1
+			AND (changed_xid, id) > ($2::text::xid8, $3)
+			AND changed_xid < pg_snapshot_xmin(pg_current_snapshot())
+			AND ($5::bigint = 0 OR genre_ids && ARRAY(
+				WITH RECURSIVE subtree AS (
+					SELECT id FROM genres WHERE id = $5
+					UNION ALL
+					SELECT g.id FROM genres g JOIN subtree ON g.parent_id = subtree.id
+				)
+				SELECT id FROM subtree
+			))
+			AND ($6::text = '' OR availability = $6)
+		ORDER BY changed_xid, id
+		LIMIT $4
+	`
+
+	// One extra row tells whether there is a next page.
+	rows, err := s.db.QueryContext(ctx, sqlStatement, updatedSince, strconv.FormatUint(after.xid, 10), after.id, pageSize+1, req.GetGenreId(), availability)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to list books", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	response := &pb.ListBooksResponse{}
+	var positions []bookPageToken
+	for rows.Next() {
+		var xid string
+		book, err := scanBook(pagedBookRow{rowScanner: rows, xid: &xid})
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to list books", "error", err)
+			return nil, err
+		}
+		position := bookPageToken{id: book.GetId()}
+		if position.xid, err = strconv.ParseUint(xid, 10, 64); err != nil {
+			logging.FromContext(ctx).Error("Failed to list books", "error", err)
+			return nil, err
+		}
+		response.Books = append(response.Books, book)
+		positions = append(positions, position)
+	}
+	if err := rows.Err(); err != nil {
+		logging.FromContext(ctx).Error("Failed to list books", "error", err)
+		return nil, err
+	}
+
+	if len(response.Books) > pageSize {
+		response.Books = response.Books[:pageSize]
+		response.NextPageToken = positions[pageSize-1].encode()
+	}
+	response.SyncToken = req.GetPageToken()
+	if len(response.Books) > 0 {
+		response.SyncToken = positions[len(response.Books)-1].encode()
+	}
+
+	if err := s.loadTranslations(ctx, response.Books...); err != nil {
+		logging.FromContext(ctx).Error("Failed to list books", "error", err)
+		return nil, err
+	}
+	chain := s.localeChain(ctx, req.GetLocale())
+	for _, book := range response.Books {
+		localizeBook(book, chain)
+		book.Translations = nil
+	}
+	return response, nil
 }
 
 func (s *server) UpdateBook(ctx context.Context, req *pb.UpdateBookRequest) (*pb.Book, error) {
 	bookID := req.GetId()
-	updatedBook := req.GetBook()
-
-	sqlStatement := `
-		UPDATE books
-		SET title = $1, author = $2, year = $3, language = $4, genres = $5, price = $6, quantity = $7
-		WHERE id = $8
-		RETURNING id
-	`
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
 
-	genresArray := &pgtype.TextArray{}
-	if err := genresArray.Set(updatedBook.Genres); err != nil {
-		log.Printf("Failed to convert genres to array-compatible format: %v", err)
+	fields, err := updatedFields(req)
+	if err != nil {
 		return nil, err
 	}
+	book := req.GetBook()
+	if book == nil {
+		book = &pb.Book{}
+	}
+	// A localized title read back from ReadBook must not overwrite the
+	// untranslated one.
+	if slices.Contains(fields, "title") && book.GetLocale() != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "book has the %s title; use SetBookTranslation to change it", book.GetLocale())
+	}
 
-	var id int64
-	err := s.db.QueryRowContext(
-		ctx,
-		sqlStatement,
-		updatedBook.Title,
-		updatedBook.Author,
-		updatedBook.Year,
-		updatedBook.Language,
-		genresArray,
-		updatedBook.Price,
-		updatedBook.Quantity,
-		bookID,
-	).Scan(&id)
-	if err != nil {
-		log.Printf("Failed to update book: %v", err)
+	var updated *pb.Book
+	err = s.inTx(ctx, func(tx *tracing.Tx) error {
+		before, err := lockBook(ctx, tx, bookID)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(fields, "genres") {
+			if err := resolveGenres(ctx, tx, book); err != nil {
+				return err
+			}
+		}
+		updated, err = writeBookFields(ctx, tx, bookID, book, fields)
+		if err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, bookID, pb.BookRevision_UPDATE, before, updated)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "book %d not found", bookID)
+	}
+	if err != nil {
+		if _, ok := status.FromError(err); !ok {
+			logging.FromContext(ctx).Error("Failed to update book", "error", err)
+		}
 		return nil, err
 	}
+	s.books.Invalidate(ctx, bookCacheKey(bookID))
 
-	updatedBook.Id = id
-	return updatedBook, nil
+	return updated, nil
 }
 
 func (s *server) DeleteBook(ctx context.Context, req *pb.DeleteBookRequest) (*pb.DeleteBookResponse, error) {
 	bookID := req.GetId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
 
 	sqlStatement := `
 		DELETE FROM books
 		WHERE id = $1
-	`
+		RETURNING ` + bookColumns
 
-	_, err := s.db.ExecContext(ctx, sqlStatement, bookID)
+	err := s.inTx(ctx, func(tx *tracing.Tx) error {
+		before, err := scanBook(tx.QueryRowContext(ctx, sqlStatement, bookID))
+		if errors.Is(err, sql.ErrNoRows) {
+			// Deleting a missing book succeeds, as it always has.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, bookID, pb.BookRevision_DELETE, before, nil)
+	})
 	if err != nil {
-		log.Printf("Failed to delete book: %v", err)
+		logging.FromContext(ctx).Error("Failed to delete book", "error", err)
 		return nil, err
 	}
+	s.books.Invalidate(ctx, bookCacheKey(bookID))
 
 	response := &pb.DeleteBookResponse{
 		Success: true,
@@ -155,49 +378,418 @@ func (s *server) DeleteBook(ctx context.Context, req *pb.DeleteBookRequest) (*pb
 }
 
 func main() {
-	host := os.Getenv("DB_HOST")
-	portDB := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, portDB, user, password, dbname)
-	db, err := sql.Open("postgres", connStr)
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "config" {
+		if err := runConfigCommand(args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	logLevel, err := logging.Setup(cfg.Logging)
+	if err != nil {
+		log.Fatalf("Failed to set up logging: %v", err)
+	}
+	if err := run(cfg, logLevel); err != nil {
+		slog.Error("Server exited with error", "error", err)
+		os.Exit(1)
+	}
+}
+
+// runConfigCommand implements the "config print" subcommand.
+func runConfigCommand(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errors.New("usage: bookservice config print [flags]")
+	}
+	cfg, err := config.Load(args[1:])
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	return cfg.Print(os.Stdout)
+}
+
+func run(cfg *config.Config, logLevel *slog.LevelVar) error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := sql.Open("postgres", cfg.Database.DSN())
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 	defer db.Close()
 
-	err = db.Ping()
+	err = db.PingContext(ctx)
 	if err != nil {
-		log.Fatalf("Failed to ping database: %v", err)
+		return fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	lis, err := net.Listen("tcp", port)
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
 	if err != nil {
-		log.Fatalf("Failed to listen: %v", err)
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("Failed to flush traces", "error", err)
+		}
+	}()
+
+	if cfg.Database.AutoMigrate {
+		if err := migrations.Apply(ctx, db); err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
 	}
-	s := grpc.NewServer()
-	pb.RegisterBookingServiceServer(s, &server{db: db})
 
-	log.Printf("gRPC server listening on %s", port)
-	go func() {
-		err = s.Serve(lis)
+	checker := health.NewChecker(pb.BookingService_ServiceDesc.ServiceName)
+	checker.Add("database", health.DBCheck(db))
+	checker.Add("migrations", health.MigrationCheck(db))
+
+	var amqpConn *events.Conn
+	if url := cfg.AMQP.URL.Value(); url != "" {
+		amqpConn, err = events.Dial(url)
 		if err != nil {
-			log.Fatalf("Failed to serve gRPC: %v", err)
+			return fmt.Errorf("failed to connect to AMQP: %w", err)
 		}
-	}()
+		defer amqpConn.Close()
+		checker.Add("amqp", health.AMQPCheck(amqpConn))
+	}
+
+	lis, err := net.Listen("tcp", cfg.GRPC.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	workers := newWorkerGroup()
+	defer workers.Stop()
+
+	tracedDB := tracing.WrapDB(db)
+	apiKeys := apikey.NewStore(tracedDB)
+
+	m := metrics.New(db)
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		otelgrpc.UnaryServerInterceptor(),
+		m.UnaryServerInterceptor(),
+		logging.UnaryServerInterceptor(),
+	}
+	streamInterceptors := []grpc.StreamServerInterceptor{
+		otelgrpc.StreamServerInterceptor(),
+		m.StreamServerInterceptor(),
+		logging.StreamServerInterceptor(),
+	}
 
-	mux := runtime.NewServeMux()
-	opts := []grpc.DialOption{grpc.WithInsecure()}
-	err = pb.RegisterBookingServiceHandlerFromEndpoint(context.Background(), mux, fmt.Sprintf("localhost%s", port), opts)
+	// Every IP address is limited before authentication, so that API keys
+	// and tokens cannot be tried faster than its limit.
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		var store ratelimit.Store
+		switch cfg.RateLimit.Backend {
+		case "postgres":
+			pgStore := ratelimit.NewPostgresStore(tracedDB)
+			workers.Go(func(ctx context.Context) {
+				pgStore.Cleanup(ctx, rateLimitCleanupInterval, rateLimitIdleTimeout)
+			})
+			store = pgStore
+		default:
+			memStore := ratelimit.NewMemoryStore()
+			workers.Go(func(ctx context.Context) {
+				memStore.Cleanup(ctx, rateLimitCleanupInterval, rateLimitIdleTimeout)
+			})
+			store = memStore
+		}
+		limiter, err = ratelimit.NewLimiter(store, cfg.RateLimit)
+		if err != nil {
+			return fmt.Errorf("failed to set up rate limiting: %w", err)
+		}
+		unaryInterceptors = append(unaryInterceptors, limiter.UnaryIPInterceptor())
+		streamInterceptors = append(streamInterceptors, limiter.StreamIPInterceptor())
+	}
+
+	// adminHTTP protects the gateway handlers that are not gRPC methods.
+	adminHTTP := func(h runtime.HandlerFunc) runtime.HandlerFunc { return h }
+	if cfg.Auth.Enabled {
+		authenticator, err := auth.NewAuthenticator(ctx, cfg.Auth)
+		if err != nil {
+			return fmt.Errorf("failed to set up authentication: %w", err)
+		}
+		authenticator.SetAPIKeyVerifier(verifyAPIKey(apiKeys))
+		adminHTTP = authenticator.RequireAdminHTTP
+		workers.Go(func(ctx context.Context) {
+			authenticator.RefreshJWKS(ctx, cfg.Auth.JWKSRefreshInterval)
+		})
+
+		policy := auth.Policy{
+			Public:        publicMethods,
+			RequiredScope: cfg.Auth.RequiredScope,
+			Admin:         adminMethods,
+		}
+		unaryInterceptors = append(unaryInterceptors, authenticator.UnaryServerInterceptor(policy))
+		streamInterceptors = append(streamInterceptors, authenticator.StreamServerInterceptor(policy))
+
+		if cfg.Authz.PolicyFile != "" {
+			engine, err := authz.NewEngine(cfg.Authz.PolicyFile, bookFields, changedBookFields)
+			if err != nil {
+				return fmt.Errorf("failed to load authorization policy: %w", err)
+			}
+			workers.Go(func(ctx context.Context) {
+				engine.Watch(ctx, cfg.Authz.ReloadInterval)
+			})
+			unaryInterceptors = append(unaryInterceptors, engine.UnaryServerInterceptor(policy))
+			streamInterceptors = append(streamInterceptors, engine.StreamServerInterceptor(policy))
+		}
+	} else {
+		slog.Warn("Authentication is disabled, write and admin RPCs are open to every caller")
+	}
+
+	// The per-client limits run after authentication so that they are keyed
+	// by principal where there is one.
+	if limiter != nil {
+		unaryInterceptors = append(unaryInterceptors, limiter.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, limiter.StreamServerInterceptor())
+	}
+
+	if cfg.Idempotency.Enabled {
+		idempotencyKeys := idempotency.NewStore(tracedDB, cfg.Idempotency.TTL, cfg.Idempotency.ClaimTimeout)
+		workers.Go(func(ctx context.Context) {
+			idempotencyKeys.Cleanup(ctx, idempotencyCleanupInterval)
+		})
+		unaryInterceptors = append(unaryInterceptors, idempotencyKeys.UnaryServerInterceptor(idempotentMethods))
+	}
+
+	serverOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	}
+	if cfg.GRPC.TLS.Enabled {
+		tlsCfg, err := watchedServerTLS(cfg.GRPC.TLS, cfg.TLSReloadInterval, workers)
+		if err != nil {
+			return fmt.Errorf("failed to set up gRPC TLS: %w", err)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+	s := grpc.NewServer(serverOpts...)
+	var books *cache.Cache
+	if cfg.Cache.Enabled {
+		books = cache.New(cfg.Cache.Size, cfg.Cache.TTL, nil)
+		m.Register(books.Collectors()...)
+		if cfg.Cache.Notify {
+			workers.Go(func(ctx context.Context) {
+				books.Listen(ctx, cfg.Database.DSN(), bookChangesChannel, bookCacheKeyFromNotification)
+			})
+		}
+	}
+
+	blobs, err := blob.NewFileStore(cfg.Covers.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to set up cover storage: %w", err)
+	}
+	coverService := covers.New(blobs, cfg.Covers.MaxBytes, cfg.Covers.Thumbnails, cfg.Covers.BaseURL)
+
+	bookingServer := &server{
+		db:              tracedDB,
+		apiKeys:         apiKeys,
+		books:           books,
+		covers:          coverService,
+		fallbackLocales: cfg.Locales.Fallback,
+		webhooks:        webhooks.NewStore(tracedDB),
+	}
+	pb.RegisterBookingServiceServer(s, bookingServer)
+	workers.Go(func(ctx context.Context) {
+		bookingServer.allocatePreOrders(ctx, cfg.PreOrders.AllocationInterval)
+	})
+	if amqpConn != nil {
+		workers.Go(amqpConn.Run)
+		relay := events.NewRelay(tracedDB, amqpConn, cfg.Events.Exchange)
+		workers.Go(func(ctx context.Context) {
+			relay.Run(ctx, cfg.Events.RelayInterval)
+		})
+	}
+	workers.Go(func(ctx context.Context) {
+		events.Cleanup(ctx, tracedDB, eventsCleanupInterval, cfg.Events.Retention, amqpConn != nil)
+	})
+	dispatcher := webhooks.NewDispatcher(tracedDB, cfg.Webhooks)
+	workers.Go(dispatcher.Run)
+	workers.Go(func(ctx context.Context) {
+		webhooks.Cleanup(ctx, tracedDB, eventsCleanupInterval, cfg.Webhooks.Retention)
+	})
+	healthpb.RegisterHealthServer(s, checker.Server())
+
+	// The gateway connection and gatewayCtx are released on return, only
+	// after in-flight HTTP requests have been drained.
+	gatewayCtx, cancelGateway := context.WithCancel(context.Background())
+	defer cancelGateway()
+
+	mux := runtime.NewServeMux(
+		runtime.WithMetadata(logging.GatewayMetadata),
+		runtime.WithIncomingHeaderMatcher(gatewayHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(gatewayOutgoingHeaderMatcher),
+		runtime.WithForwardResponseOption(httpcache.NewPolicy(cfg.HTTP.CacheControl).ForwardResponse),
+	)
+	gatewayCreds := insecure.NewCredentials()
+	if cfg.GRPC.TLS.Enabled {
+		tlsCfg, reloader, err := tlsconfig.ClientConfig(cfg.HTTP.GRPCClient)
+		if err != nil {
+			return fmt.Errorf("failed to set up gRPC-Gateway client TLS: %w", err)
+		}
+		workers.Go(func(ctx context.Context) {
+			reloader.Watch(ctx, cfg.TLSReloadInterval)
+		})
+		gatewayCreds = credentials.NewTLS(tlsCfg)
+	}
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(gatewayCreds),
+		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()),
+	}
+	gatewayConn, err := grpc.DialContext(gatewayCtx, grpcDialTarget(cfg.GRPC.Addr), opts...)
 	if err != nil {
-		log.Fatalf("Failed to register gRPC-Gateway: %v", err)
+		return fmt.Errorf("failed to dial gRPC server for gRPC-Gateway: %w", err)
+	}
+	defer gatewayConn.Close()
+	if err := pb.RegisterBookingServiceHandler(gatewayCtx, mux, gatewayConn); err != nil {
+		return fmt.Errorf("failed to register gRPC-Gateway: %w", err)
+	}
+	uploadCover := uploadCoverHandler(mux, pb.NewBookingServiceClient(gatewayConn), cfg.Covers.MaxBytes)
+	if err := mux.HandlePath(http.MethodPost, "/books/{id}/cover", uploadCover); err != nil {
+		return fmt.Errorf("failed to register /books/{id}/cover: %w", err)
+	}
+	if err := mux.HandlePath(http.MethodGet, "/covers/{id}/{size}", coverService.ServeHTTP); err != nil {
+		return fmt.Errorf("failed to register /covers/{id}/{size}: %w", err)
+	}
+	if err := mux.HandlePath(http.MethodGet, "/healthz", checker.LivenessHandler); err != nil {
+		return fmt.Errorf("failed to register /healthz: %w", err)
+	}
+	if err := mux.HandlePath(http.MethodGet, "/readyz", checker.ReadinessHandler); err != nil {
+		return fmt.Errorf("failed to register /readyz: %w", err)
+	}
+	if err := mux.HandlePath(http.MethodGet, "/metrics", m.Handler); err != nil {
+		return fmt.Errorf("failed to register /metrics: %w", err)
+	}
+	if err := mux.HandlePath(http.MethodGet, "/admin/loglevel", adminHTTP(logging.LevelHandler(logLevel))); err != nil {
+		return fmt.Errorf("failed to register /admin/loglevel: %w", err)
+	}
+	if err := mux.HandlePath(http.MethodPut, "/admin/loglevel", adminHTTP(logging.LevelHandler(logLevel))); err != nil {
+		return fmt.Errorf("failed to register /admin/loglevel: %w", err)
 	}
+	handler := otelhttp.NewHandler(m.HTTPMiddleware(logging.HTTPMiddleware(httpcache.Middleware(mux))), "gateway",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "HTTP " + r.Method
+		}),
+	)
+	httpServer := &http.Server{
+		Addr:    cfg.HTTP.Addr,
+		Handler: handler,
+	}
+	if cfg.HTTP.TLS.Enabled {
+		httpServer.TLSConfig, err = watchedServerTLS(cfg.HTTP.TLS, cfg.TLSReloadInterval, workers)
+		if err != nil {
+			return fmt.Errorf("failed to set up gRPC-Gateway TLS: %w", err)
+		}
+	}
+
+	workers.Go(func(ctx context.Context) {
+		checker.Run(ctx, cfg.Health.CheckInterval)
+	})
 
-	log.Println("gRPC-Gateway server listening on :8081")
-	err = http.ListenAndServe(":8081", mux)
+	errCh := make(chan error, 2)
+	go func() {
+		slog.Info("gRPC server listening", "addr", cfg.GRPC.Addr, "tls", cfg.GRPC.TLS.Enabled)
+		if err := s.Serve(lis); err != nil {
+			errCh <- fmt.Errorf("failed to serve gRPC: %w", err)
+		}
+	}()
+	go func() {
+		slog.Info("gRPC-Gateway server listening", "addr", cfg.HTTP.Addr, "tls", cfg.HTTP.TLS.Enabled)
+		var err error
+		if cfg.HTTP.TLS.Enabled {
+			// The certificate comes from TLSConfig.GetCertificate.
+			err = httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = httpServer.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- fmt.Errorf("failed to serve gRPC-Gateway: %w", err)
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutdown signal received, draining connections")
+	case err = <-errCh:
+		slog.Error("Server error, shutting down", "error", err)
+	}
+	stop()
+	checker.Shutdown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	// Stop accepting HTTP traffic first, so that the gateway does not forward
+	// new requests to a gRPC server that is already draining.
+	if shutdownErr := httpServer.Shutdown(shutdownCtx); shutdownErr != nil {
+		slog.Error("Failed to shut down gRPC-Gateway gracefully", "error", shutdownErr)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		slog.Warn("gRPC graceful stop timed out, forcing shutdown")
+		s.Stop()
+	}
+
+	workers.Stop()
+	slog.Info("Server stopped")
+	return err
+}
+
+// gatewayHeaderMatcher forwards the API key and idempotency key headers to
+// the gRPC server in addition to the headers grpc-gateway forwards by
+// default.
+func gatewayHeaderMatcher(key string) (string, bool) {
+	switch {
+	case strings.EqualFold(key, auth.APIKeyMetadataKey):
+		return auth.APIKeyMetadataKey, true
+	case strings.EqualFold(key, idempotency.MetadataKey):
+		return idempotency.MetadataKey, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// gatewayOutgoingHeaderMatcher maps response metadata to HTTP headers.
+func gatewayOutgoingHeaderMatcher(key string) (string, bool) {
+	if key == idempotency.ReplayedMetadataKey {
+		return "Idempotent-Replayed", true
+	}
+	return ratelimit.OutgoingHeaderMatcher(key)
+}
+
+// watchedServerTLS returns the TLS configuration of a listener and reloads
+// its certificates in the background.
+func watchedServerTLS(cfg config.TLSConfig, interval time.Duration, workers *workerGroup) (*tls.Config, error) {
+	tlsCfg, reloader, err := tlsconfig.ServerConfig(cfg)
 	if err != nil {
-		log.Fatalf("Failed to serve gRPC-Gateway: %v", err)
+		return nil, err
+	}
+	workers.Go(func(ctx context.Context) {
+		reloader.Watch(ctx, interval)
+	})
+	return tlsCfg, nil
+}
+
+// grpcDialTarget returns the address the gateway uses to reach the local gRPC
+// server listening on addr.
+func grpcDialTarget(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host == "" || host == "0.0.0.0" || host == "::" {
+		return net.JoinHostPort("localhost", port)
 	}
+	return addr
 }
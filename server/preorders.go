@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"Booking/auth"
+	pb "Booking/bookserver/test"
+	"Booking/logging"
+	"Booking/tracing"
+)
+
+// reservationColumns is the column list matching scanReservation.
+const reservationColumns = "id, book_id, type, status, customer_id, quantity, created_at, allocated_at"
+
+// preOrderAllocator is the actor recorded for the changes of the allocation
+// job.
+const preOrderAllocator = "system:pre-order-allocator"
+
+// scanReservation reads a reservation selected with reservationColumns.
+func scanReservation(row rowScanner) (*pb.Reservation, error) {
+	reservation := &pb.Reservation{}
+	var reservationType, reservationStatus string
+	var createdAt time.Time
+	var allocatedAt sql.NullTime
+
+	err := row.Scan(
+		&reservation.Id,
+		&reservation.BookId,
+		&reservationType,
+		&reservationStatus,
+		&reservation.CustomerId,
+		&reservation.Quantity,
+		&createdAt,
+		&allocatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	reservation.Type = pb.Reservation_Type(pb.Reservation_Type_value[strings.ToUpper(reservationType)])
+	reservation.Status = pb.Reservation_Status(pb.Reservation_Status_value[strings.ToUpper(reservationStatus)])
+	reservation.CreatedAt = timestamppb.New(createdAt)
+	if allocatedAt.Valid {
+		reservation.AllocatedAt = timestamppb.New(allocatedAt.Time)
+	}
+	return reservation, nil
+}
+
+func (s *server) CreatePreOrder(ctx context.Context, req *pb.CreatePreOrderRequest) (*pb.Reservation, error) {
+	bookID := req.GetBookId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	customerID := actor(ctx)
+	if customerID == "" {
+		return nil, status.Error(codes.Unauthenticated, "pre-orders require an authenticated user")
+	}
+	quantity := req.GetQuantity()
+	if quantity == 0 {
+		quantity = 1
+	}
+	if quantity < 0 {
+		return nil, status.Error(codes.InvalidArgument, "quantity must be positive")
+	}
+
+	sqlStatement := `
+		INSERT INTO reservations (book_id, type, customer_id, quantity)
+		VALUES ($1, 'pre_order', $2, $3)
+		RETURNING ` + reservationColumns
+
+	var reservation *pb.Reservation
+	err := s.inTx(ctx, func(tx *tracing.Tx) error {
+		// The share lock keeps the allocation job from releasing the book
+		// while the pre-order is queued.
+		var availability string
+		err := tx.QueryRowContext(ctx, `SELECT availability FROM books WHERE id = $1 FOR SHARE`, bookID).Scan(&availability)
+		if err != nil {
+			return err
+		}
+		if availability != "pre_order" {
+			return status.Errorf(codes.FailedPrecondition, "book %d is not open for pre-order", bookID)
+		}
+		reservation, err = scanReservation(tx.QueryRowContext(ctx, sqlStatement, bookID, customerID, quantity))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "book %d not found", bookID)
+	}
+	if err != nil {
+		if _, ok := status.FromError(err); !ok {
+			logging.FromContext(ctx).Error("Failed to create pre-order", "error", err)
+		}
+		return nil, err
+	}
+	return reservation, nil
+}
+
+func (s *server) ListPreOrders(ctx context.Context, req *pb.ListPreOrdersRequest) (*pb.ListReservationsResponse, error) {
+	bookID := req.GetBookId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	pageSize, err := normalizePageSize(req.GetPageSize())
+	if err != nil {
+		return nil, err
+	}
+	after, err := idPageToken(req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStatement := `
+		SELECT ` + reservationColumns + `
+		FROM reservations
+		WHERE book_id = $1 AND type = 'pre_order' AND id > $2
+			AND ($4::text IS NULL OR customer_id = $4)
+		ORDER BY id
+		LIMIT $3
+	`
+
+	// Only admins see the pre-orders of other customers.
+	var customerID sql.NullString
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && !isAdmin(principal) {
+		customerID = sql.NullString{String: principal.Subject, Valid: true}
+	}
+
+	// One extra row tells whether there is a next page.
+	rows, err := s.db.QueryContext(ctx, sqlStatement, bookID, after, pageSize+1, customerID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to list pre-orders", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	response := &pb.ListReservationsResponse{}
+	for rows.Next() {
+		reservation, err := scanReservation(rows)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to list pre-orders", "error", err)
+			return nil, err
+		}
+		response.Reservations = append(response.Reservations, reservation)
+	}
+	if err := rows.Err(); err != nil {
+		logging.FromContext(ctx).Error("Failed to list pre-orders", "error", err)
+		return nil, err
+	}
+
+	if len(response.Reservations) > pageSize {
+		response.Reservations = response.Reservations[:pageSize]
+		response.NextPageToken = strconv.FormatInt(response.Reservations[pageSize-1].GetId(), 10)
+	}
+	return response, nil
+}
+
+// mayManageReservation reports whether the caller may act on a
+// reservation: its customer, an admin, or anyone when authentication is
+// disabled.
+func mayManageReservation(ctx context.Context, reservation *pb.Reservation) bool {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return true
+	}
+	return principal.Subject == reservation.GetCustomerId() || isAdmin(principal)
+}
+
+// isAdmin reports whether principal holds the admin role, either as a role
+// or as a scope.
+func isAdmin(principal *auth.Principal) bool {
+	return principal.HasRole(auth.AdminRole) || principal.HasScope(auth.AdminRole)
+}
+
+func (s *server) CancelReservation(ctx context.Context, req *pb.CancelReservationRequest) (*pb.Reservation, error) {
+	reservationID := req.GetId()
+
+	var reservation *pb.Reservation
+	err := s.inTx(ctx, func(tx *tracing.Tx) error {
+		var err error
+		reservation, err = scanReservation(tx.QueryRowContext(ctx,
+			`SELECT `+reservationColumns+` FROM reservations WHERE id = $1 FOR UPDATE`, reservationID))
+		if err != nil {
+			return err
+		}
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", reservation.GetBookId()))
+		if !mayManageReservation(ctx, reservation) {
+			return status.Errorf(codes.PermissionDenied, "reservation %d belongs to another customer", reservationID)
+		}
+		if reservation.GetStatus() != pb.Reservation_QUEUED {
+			return status.Errorf(codes.FailedPrecondition, "reservation %d is %s", reservationID, strings.ToLower(reservation.GetStatus().String()))
+		}
+		reservation, err = scanReservation(tx.QueryRowContext(ctx,
+			`UPDATE reservations SET status = 'cancelled' WHERE id = $1 RETURNING `+reservationColumns, reservationID))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "reservation %d not found", reservationID)
+	}
+	if err != nil {
+		if _, ok := status.FromError(err); !ok {
+			logging.FromContext(ctx).Error("Failed to cancel reservation", "error", err)
+		}
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// allocatePreOrders runs the allocation job every interval until ctx is
+// cancelled. Every replica runs it; book row locks keep them from
+// allocating the same stock twice.
+func (s *server) allocatePreOrders(ctx context.Context, interval time.Duration) {
+	ctx = auth.ContextWithPrincipal(ctx, &auth.Principal{Subject: preOrderAllocator})
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.allocateDue(ctx, now.UTC().Format(time.DateOnly)); err != nil {
+				slog.Error("Failed to allocate pre-orders", "error", err)
+			}
+		}
+	}
+}
+
+// allocateDue allocates stock to queued pre-orders and releases the books
+// whose release date is on or before today.
+func (s *server) allocateDue(ctx context.Context, today string) error {
+	sqlStatement := `
+		SELECT b.id
+		FROM books b
+		WHERE b.quantity > 0 AND (
+			EXISTS (SELECT 1 FROM reservations r WHERE r.book_id = b.id AND r.status = 'queued')
+			OR (b.availability IN ('announced', 'pre_order') AND b.release_date <= $1::date)
+		)
+		ORDER BY b.id
+	`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, today)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		changed, err := s.allocateBook(ctx, id, today)
+		if err != nil {
+			slog.Error("Failed to allocate pre-orders", "book_id", id, "error", err)
+			continue
+		}
+		if changed {
+			s.books.Invalidate(ctx, bookCacheKey(id))
+		}
+	}
+	return ctx.Err()
+}
+
+// allocateBook allocates the stock of a book to its queued pre-orders in
+// FIFO order. A pre-order is served in full or not at all, and none is
+// served ahead of an older one. The book becomes IN_STOCK when it is
+// released with stock left and no pre-order waiting.
+func (s *server) allocateBook(ctx context.Context, id int64, today string) (bool, error) {
+	var changed bool
+	err := s.inTx(ctx, func(tx *tracing.Tx) error {
+		before, err := lockBook(ctx, tx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Deleted since it was selected.
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, quantity
+			FROM reservations
+			WHERE book_id = $1 AND status = 'queued'
+			ORDER BY id
+			FOR UPDATE
+		`, id)
+		if err != nil {
+			return err
+		}
+		stock := before.GetQuantity()
+		var allocated []int64
+		waiting := 0
+		for rows.Next() {
+			var reservationID int64
+			var quantity int32
+			if err := rows.Scan(&reservationID, &quantity); err != nil {
+				rows.Close()
+				return err
+			}
+			if waiting == 0 && quantity <= stock {
+				stock -= quantity
+				allocated = append(allocated, reservationID)
+			} else {
+				waiting++
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		book := proto.Clone(before).(*pb.Book)
+		book.Quantity = stock
+		released := before.GetReleaseDate() != "" && before.GetReleaseDate() <= today
+		unreleased := before.GetAvailability() == pb.Book_ANNOUNCED || before.GetAvailability() == pb.Book_PRE_ORDER
+		if unreleased && released && waiting == 0 && stock > 0 {
+			book.Availability = pb.Book_IN_STOCK
+		}
+		if len(allocated) == 0 && book.GetAvailability() == before.GetAvailability() {
+			return nil
+		}
+
+		if len(allocated) > 0 {
+			_, err := tx.ExecContext(ctx, `
+				UPDATE reservations
+				SET status = 'allocated', allocated_at = now()
+				WHERE id = ANY($1)
+			`, pq.Array(allocated))
+			if err != nil {
+				return err
+			}
+		}
+		updated, err := writeBookFields(ctx, tx, id, book, []string{"quantity", "availability"})
+		if err != nil {
+			return err
+		}
+		changed = true
+		return recordRevision(ctx, tx, id, pb.BookRevision_UPDATE, before, updated)
+	})
+	return changed, err
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"Booking/auth"
+	pb "Booking/bookserver/test"
+)
+
+func preOrder(t *testing.T, s *server, customer string, bookID int64, quantity int32) *pb.Reservation {
+	t.Helper()
+	reservation, err := s.CreatePreOrder(asPrincipal(customer), &pb.CreatePreOrderRequest{BookId: bookID, Quantity: quantity})
+	if err != nil {
+		t.Fatalf("CreatePreOrder() error = %v", err)
+	}
+	return reservation
+}
+
+// reservationStatuses returns the statuses of the pre-orders of a book by
+// customer.
+func reservationStatuses(t *testing.T, s *server, bookID int64) map[string]pb.Reservation_Status {
+	t.Helper()
+	resp, err := s.ListPreOrders(asPrincipal("admin", auth.AdminRole), &pb.ListPreOrdersRequest{BookId: bookID})
+	if err != nil {
+		t.Fatalf("ListPreOrders() error = %v", err)
+	}
+	statuses := make(map[string]pb.Reservation_Status)
+	for _, r := range resp.GetReservations() {
+		statuses[r.GetCustomerId()] = r.GetStatus()
+	}
+	return statuses
+}
+
+func readTestBook(t *testing.T, s *server, id int64) *pb.Book {
+	t.Helper()
+	book, err := s.ReadBook(context.Background(), &pb.ReadBookRequest{Id: id})
+	if err != nil {
+		t.Fatalf("ReadBook() error = %v", err)
+	}
+	return book
+}
+
+func TestAllocatePreOrders(t *testing.T) {
+	s := newTestServer(t)
+	allocator := asPrincipal(preOrderAllocator)
+	book := createTestBook(t, s, &pb.Book{Title: "Upcoming", Author: "Author", Year: 2030, ReleaseDate: "2030-06-01", Availability: pb.Book_ANNOUNCED})
+
+	_, err := s.CreatePreOrder(asPrincipal("alice"), &pb.CreatePreOrderRequest{BookId: book.GetId()})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("CreatePreOrder() of an announced book error = %v, want FailedPrecondition", err)
+	}
+	updateTestBook(t, s, book.GetId(), &pb.Book{Availability: pb.Book_PRE_ORDER}, "availability")
+
+	preOrder(t, s, "alice", book.GetId(), 2)
+	preOrder(t, s, "bob", book.GetId(), 3)
+	preOrder(t, s, "carol", book.GetId(), 1)
+
+	// Bob's pre-order does not fit and is not served in part, and Carol's
+	// is not served ahead of it.
+	setQuantity(t, s, book.GetId(), 4)
+	if err := s.allocateDue(allocator, "2030-05-01"); err != nil {
+		t.Fatalf("allocateDue() error = %v", err)
+	}
+	want := map[string]pb.Reservation_Status{"alice": pb.Reservation_ALLOCATED, "bob": pb.Reservation_QUEUED, "carol": pb.Reservation_QUEUED}
+	if got := reservationStatuses(t, s, book.GetId()); !maps.Equal(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	if got := readTestBook(t, s, book.GetId()); got.GetQuantity() != 2 || got.GetAvailability() != pb.Book_PRE_ORDER {
+		t.Errorf("quantity, availability = %d, %v, want 2, PRE_ORDER", got.GetQuantity(), got.GetAvailability())
+	}
+
+	// More stock serves the rest of the queue, but the book is not released
+	// before its date.
+	setQuantity(t, s, book.GetId(), 5)
+	if err := s.allocateDue(allocator, "2030-05-01"); err != nil {
+		t.Fatalf("allocateDue() error = %v", err)
+	}
+	want = map[string]pb.Reservation_Status{"alice": pb.Reservation_ALLOCATED, "bob": pb.Reservation_ALLOCATED, "carol": pb.Reservation_ALLOCATED}
+	if got := reservationStatuses(t, s, book.GetId()); !maps.Equal(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+	if got := readTestBook(t, s, book.GetId()); got.GetQuantity() != 1 || got.GetAvailability() != pb.Book_PRE_ORDER {
+		t.Errorf("quantity, availability = %d, %v, want 1, PRE_ORDER", got.GetQuantity(), got.GetAvailability())
+	}
+
+	if err := s.allocateDue(allocator, "2030-06-01"); err != nil {
+		t.Fatalf("allocateDue() error = %v", err)
+	}
+	got := readTestBook(t, s, book.GetId())
+	if got.GetQuantity() != 1 || got.GetAvailability() != pb.Book_IN_STOCK {
+		t.Errorf("quantity, availability on release = %d, %v, want 1, IN_STOCK", got.GetQuantity(), got.GetAvailability())
+	}
+	if got.GetUpdatedBy() != preOrderAllocator {
+		t.Errorf("updated by %q, want %q", got.GetUpdatedBy(), preOrderAllocator)
+	}
+}
+
+func TestReleaseWaitsForQueuedPreOrders(t *testing.T) {
+	s := newTestServer(t)
+	book := createTestBook(t, s, &pb.Book{Title: "Upcoming", Author: "Author", Year: 2030, ReleaseDate: "2030-06-01", Availability: pb.Book_PRE_ORDER})
+	preOrder(t, s, "alice", book.GetId(), 5)
+	setQuantity(t, s, book.GetId(), 3)
+
+	if err := s.allocateDue(asPrincipal(preOrderAllocator), "2030-07-01"); err != nil {
+		t.Fatalf("allocateDue() error = %v", err)
+	}
+	if got := readTestBook(t, s, book.GetId()); got.GetQuantity() != 3 || got.GetAvailability() != pb.Book_PRE_ORDER {
+		t.Errorf("quantity, availability = %d, %v, want 3, PRE_ORDER while a pre-order waits", got.GetQuantity(), got.GetAvailability())
+	}
+}
+
+func TestListPreOrdersOfOthers(t *testing.T) {
+	s := newTestServer(t)
+	book := createTestBook(t, s, &pb.Book{Title: "Upcoming", Author: "Author", Year: 2030, Availability: pb.Book_PRE_ORDER})
+	preOrder(t, s, "alice", book.GetId(), 1)
+	preOrder(t, s, "bob", book.GetId(), 1)
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want int
+	}{
+		{"customer", asPrincipal("alice"), 1},
+		{"customer without pre-orders", asPrincipal("carol"), 0},
+		{"admin", asPrincipal("admin", auth.AdminRole), 2},
+		{"admin API key", auth.ContextWithPrincipal(context.Background(), &auth.Principal{Subject: "ops", Scopes: []string{auth.AdminRole}}), 2},
+		{"authentication disabled", context.Background(), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.ListPreOrders(tt.ctx, &pb.ListPreOrdersRequest{BookId: book.GetId()})
+			if err != nil {
+				t.Fatalf("ListPreOrders() error = %v", err)
+			}
+			if len(resp.GetReservations()) != tt.want {
+				t.Fatalf("%d pre-orders, want %d", len(resp.GetReservations()), tt.want)
+			}
+			principal, ok := auth.PrincipalFromContext(tt.ctx)
+			for _, r := range resp.GetReservations() {
+				if ok && !isAdmin(principal) && r.GetCustomerId() != principal.Subject {
+					t.Errorf("%s sees the pre-order of %s", principal.Subject, r.GetCustomerId())
+				}
+			}
+		})
+	}
+}
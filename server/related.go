@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "Booking/bookserver/test"
+	"Booking/logging"
+)
+
+const (
+	defaultRelatedBooks = 10
+	maxRelatedBooks     = 50
+)
+
+// Weights of the GetRelatedBooks signals. A shared genre counts per genre;
+// the year weight is scaled down linearly to zero at relatedYearSpan years
+// apart. The co-purchase weight is multiplied by the natural logarithm of
+// one plus the number of customers who reserved both books, so that a few
+// customers cannot outweigh every other signal.
+const (
+	relatedGenreWeight      = 3.0
+	relatedAuthorWeight     = 5.0
+	relatedLanguageWeight   = 1.0
+	relatedYearWeight       = 2.0
+	relatedYearSpan         = 10.0
+	relatedCoPurchaseWeight = 4.0
+)
+
+// scoredRow scans a book selected with bookColumns followed by a score.
+type scoredRow struct {
+	rowScanner
+	score *float64
+}
+
+func (r scoredRow) Scan(dest ...interface{}) error {
+	return r.rowScanner.Scan(append(dest, r.score)...)
+}
+
+func (s *server) GetRelatedBooks(ctx context.Context, req *pb.GetRelatedBooksRequest) (*pb.GetRelatedBooksResponse, error) {
+	bookID := req.GetId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	limit := int(req.GetLimit())
+	switch {
+	case limit < 0:
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	case limit == 0:
+		limit = defaultRelatedBooks
+	case limit > maxRelatedBooks:
+		limit = maxRelatedBooks
+	}
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM books WHERE id = $1)`, bookID).Scan(&exists)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get related books", "error", err)
+		return nil, err
+	}
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "book %d not found", bookID)
+	}
+
+	// Candidates are gathered by indexed lookups, through the GIN index on
+	// genre_ids, the index on lower(author) and the reservations of the
+	// book, and only they are scored. The target values are scalar
+	// subqueries so that the lookups can use the indexes.
+	sqlStatement := `
+		WITH target AS (
+			SELECT id, author, language, year, genre_ids FROM books WHERE id = $1
+		),
+		co_reserved AS (
+			SELECT r.book_id, count(DISTINCT r.customer_id) AS customers
+			FROM reservations t
+			JOIN reservations r ON r.customer_id = t.customer_id
+			WHERE t.book_id = $1 AND t.status <> 'cancelled'
+				AND r.book_id <> $1 AND r.status <> 'cancelled'
+			GROUP BY r.book_id
+		),
+		candidates AS (
+			SELECT id FROM books WHERE genre_ids && (SELECT genre_ids FROM target)
+			UNION
+			SELECT id FROM books
+			WHERE lower(author) = (SELECT lower(author) FROM target) AND (SELECT author FROM target) <> ''
+			UNION
+			SELECT book_id FROM co_reserved
+		)
+		SELECT ` + bookColumns + `, score
+		FROM (
+			SELECT b.*,
+				$4::float8 * cardinality(ARRAY(SELECT unnest(b.genre_ids) INTERSECT SELECT unnest(t.genre_ids)))
+				+ CASE WHEN t.author <> '' AND lower(b.author) = lower(t.author) THEN $5::float8 ELSE 0 END
+				+ CASE WHEN t.language <> '' AND b.language = t.language THEN $6::float8 ELSE 0 END
+				+ CASE WHEN b.year > 0 AND t.year > 0
+					THEN $7::float8 * GREATEST(0, 1 - abs(b.year - t.year) / $8::float8)
+					ELSE 0 END
+				+ $9::float8 * ln(1 + coalesce(c.customers, 0)::float8) AS score
+			FROM candidates k
+			JOIN books b ON b.id = k.id
+			CROSS JOIN target t
+			LEFT JOIN co_reserved c ON c.book_id = b.id
+			WHERE b.id <> t.id
+				AND (NOT $3::boolean OR b.quantity > 0)
+		) scored
+		ORDER BY score DESC, id
+		LIMIT $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, sqlStatement, bookID, limit, req.GetInStockOnly(),
+		relatedGenreWeight, relatedAuthorWeight, relatedLanguageWeight, relatedYearWeight, relatedYearSpan,
+		relatedCoPurchaseWeight)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get related books", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	response := &pb.GetRelatedBooksResponse{}
+	for rows.Next() {
+		related := &pb.RelatedBook{}
+		related.Book, err = scanBook(scoredRow{rowScanner: rows, score: &related.Score})
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to get related books", "error", err)
+			return nil, err
+		}
+		response.Books = append(response.Books, related)
+	}
+	if err := rows.Err(); err != nil {
+		logging.FromContext(ctx).Error("Failed to get related books", "error", err)
+		return nil, err
+	}
+	return response, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	pb "Booking/bookserver/test"
+)
+
+func TestGetRelatedBooks(t *testing.T) {
+	s := newTestServer(t)
+	createTestGenre(t, s, "fantasy", 0)
+	createTestGenre(t, s, "history", 0)
+
+	target := createTestBook(t, s, &pb.Book{Title: "Target", Author: "Ursula Le Guin", Year: 1968, Language: "en", Genres: []string{"fantasy"}, Quantity: 1})
+	sameGenre := createTestBook(t, s, &pb.Book{Title: "Genre", Author: "Other", Year: 1990, Genres: []string{"fantasy"}, Quantity: 1})
+	sameAuthor := createTestBook(t, s, &pb.Book{Title: "Author", Author: "ursula le guin", Year: 1974, Genres: []string{"history"}, Quantity: 1})
+	both := createTestBook(t, s, &pb.Book{Title: "Both", Author: "Ursula Le Guin", Year: 1968, Language: "en", Genres: []string{"fantasy"}})
+	coReserved := createTestBook(t, s, &pb.Book{Title: "Reserved", Author: "Someone", Year: 2001, Genres: []string{"history"}, Quantity: 1})
+	createTestBook(t, s, &pb.Book{Title: "Unrelated", Author: "Nobody", Year: 1968, Language: "en", Genres: []string{"history"}, Quantity: 1})
+
+	// A customer reserved both the target and coReserved.
+	for _, id := range []int64{target.GetId(), coReserved.GetId()} {
+		_, err := s.db.ExecContext(context.Background(), `
+			INSERT INTO reservations (book_id, type, customer_id, quantity) VALUES ($1, 'pre_order', 'alice', 1)
+		`, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		inStockOnly bool
+		want        []int64
+	}{
+		{"all", false, []int64{both.GetId(), sameAuthor.GetId(), sameGenre.GetId(), coReserved.GetId()}},
+		{"in stock", true, []int64{sameAuthor.GetId(), sameGenre.GetId(), coReserved.GetId()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.GetRelatedBooks(context.Background(), &pb.GetRelatedBooksRequest{Id: target.GetId(), InStockOnly: tt.inStockOnly})
+			if err != nil {
+				t.Fatalf("GetRelatedBooks() error = %v", err)
+			}
+			var got []int64
+			for _, related := range resp.GetBooks() {
+				got = append(got, related.GetBook().GetId())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("related books = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "Booking/bookserver/test"
+	"Booking/logging"
+	"Booking/tracing"
+)
+
+// reviewColumns is the column list matching scanReview.
+const reviewColumns = "id, book_id, user_id, rating, text, status, moderated_by, created_at, updated_at"
+
+const maxReviewLength = 10000
+
+// Postgres error codes of constraint violations.
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+// scanReview reads a review selected with reviewColumns.
+func scanReview(row rowScanner) (*pb.Review, error) {
+	review := &pb.Review{}
+	var reviewStatus string
+	var createdAt, updatedAt time.Time
+
+	err := row.Scan(
+		&review.Id,
+		&review.BookId,
+		&review.UserId,
+		&review.Rating,
+		&review.Text,
+		&reviewStatus,
+		&review.ModeratedBy,
+		&createdAt,
+		&updatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	review.Status = pb.Review_Status(pb.Review_Status_value[strings.ToUpper(reviewStatus)])
+	review.CreatedAt = timestamppb.New(createdAt)
+	review.UpdatedAt = timestamppb.New(updatedAt)
+	return review, nil
+}
+
+// refreshBookRating recomputes the rating of a book from its approved
+// reviews. The book must be locked by tx, so that concurrent review changes
+// are applied one after the other. Like any other change, it sets updated_at,
+// so that HTTP validators change with the rating, and updated_by, to the
+// moderator.
+func refreshBookRating(ctx context.Context, tx *tracing.Tx, bookID int64) error {
+	sqlStatement := `
+		UPDATE books
+		SET (rating_sum, rating_count) = (
+			SELECT COALESCE(sum(rating), 0), count(*)
+			FROM reviews
+			WHERE book_id = $1 AND status = 'approved'
+		), updated_at = now(), updated_by = $2
+		WHERE id = $1
+	`
+	_, err := tx.ExecContext(ctx, sqlStatement, bookID, actor(ctx))
+	return err
+}
+
+func (s *server) CreateReview(ctx context.Context, req *pb.CreateReviewRequest) (*pb.Review, error) {
+	bookID := req.GetBookId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	userID := actor(ctx)
+	if userID == "" {
+		return nil, status.Error(codes.Unauthenticated, "reviews require an authenticated user")
+	}
+	if req.GetRating() < 1 || req.GetRating() > 5 {
+		return nil, status.Error(codes.InvalidArgument, "rating must be between 1 and 5")
+	}
+	if utf8.RuneCountInString(req.GetText()) > maxReviewLength {
+		return nil, status.Errorf(codes.InvalidArgument, "text must be at most %d characters", maxReviewLength)
+	}
+
+	sqlStatement := `
+		INSERT INTO reviews (book_id, user_id, rating, text)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + reviewColumns
+
+	// A new review is pending, so the rating of the book does not change.
+	review, err := scanReview(s.db.QueryRowContext(ctx, sqlStatement, bookID, userID, req.GetRating(), req.GetText()))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case foreignKeyViolation:
+			return nil, status.Errorf(codes.NotFound, "book %d not found", bookID)
+		case uniqueViolation:
+			return nil, status.Errorf(codes.AlreadyExists, "book %d was already reviewed by %s", bookID, userID)
+		}
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to create review", "error", err)
+		return nil, err
+	}
+	return review, nil
+}
+
+// listReviews runs a query selecting reviewColumns, whose last argument is
+// the limit, and pages its results by review id.
+func (s *server) listReviews(ctx context.Context, sqlStatement string, pageSize int, args ...interface{}) (*pb.ListReviewsResponse, error) {
+	rows, err := s.db.QueryContext(ctx, sqlStatement, append(args, pageSize+1)...)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to list reviews", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	response := &pb.ListReviewsResponse{}
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to list reviews", "error", err)
+			return nil, err
+		}
+		response.Reviews = append(response.Reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		logging.FromContext(ctx).Error("Failed to list reviews", "error", err)
+		return nil, err
+	}
+
+	if len(response.Reviews) > pageSize {
+		response.Reviews = response.Reviews[:pageSize]
+		response.NextPageToken = strconv.FormatInt(response.Reviews[pageSize-1].GetId(), 10)
+	}
+	return response, nil
+}
+
+// idPageToken decodes a page token that is the id of the last item
+// returned, as issued by listReviews and ListPreOrders.
+func idPageToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(token, 10, 64)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, "invalid page_token")
+	}
+	return id, nil
+}
+
+func (s *server) ListReviews(ctx context.Context, req *pb.ListReviewsRequest) (*pb.ListReviewsResponse, error) {
+	bookID := req.GetBookId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	pageSize, err := normalizePageSize(req.GetPageSize())
+	if err != nil {
+		return nil, err
+	}
+	before, err := idPageToken(req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStatement := `
+		SELECT ` + reviewColumns + `
+		FROM reviews
+		WHERE book_id = $1 AND status = 'approved' AND ($2::bigint = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`
+	return s.listReviews(ctx, sqlStatement, pageSize, bookID, before)
+}
+
+func (s *server) ListPendingReviews(ctx context.Context, req *pb.ListPendingReviewsRequest) (*pb.ListReviewsResponse, error) {
+	pageSize, err := normalizePageSize(req.GetPageSize())
+	if err != nil {
+		return nil, err
+	}
+	after, err := idPageToken(req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStatement := `
+		SELECT ` + reviewColumns + `
+		FROM reviews
+		WHERE status = 'pending' AND id > $1
+		ORDER BY id
+		LIMIT $2
+	`
+	return s.listReviews(ctx, sqlStatement, pageSize, after)
+}
+
+func (s *server) ModerateReview(ctx context.Context, req *pb.ModerateReviewRequest) (*pb.Review, error) {
+	reviewID := req.GetId()
+	newStatus := req.GetStatus()
+	if newStatus != pb.Review_APPROVED && newStatus != pb.Review_REJECTED {
+		return nil, status.Error(codes.InvalidArgument, "status must be APPROVED or REJECTED")
+	}
+
+	sqlStatement := `
+		UPDATE reviews
+		SET status = $1, moderated_by = $2, updated_at = now()
+		WHERE id = $3
+		RETURNING ` + reviewColumns
+
+	var review *pb.Review
+	err := s.inTx(ctx, func(tx *tracing.Tx) error {
+		var bookID int64
+		err := tx.QueryRowContext(ctx, `SELECT book_id FROM reviews WHERE id = $1`, reviewID).Scan(&bookID)
+		if err != nil {
+			return err
+		}
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+		if _, err := lockBook(ctx, tx, bookID); err != nil {
+			return err
+		}
+		review, err = scanReview(tx.QueryRowContext(ctx, sqlStatement, strings.ToLower(newStatus.String()), actor(ctx), reviewID))
+		if err != nil {
+			return err
+		}
+		return refreshBookRating(ctx, tx, bookID)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "review %d not found", reviewID)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to moderate review", "error", err)
+		return nil, err
+	}
+	s.books.Invalidate(ctx, bookCacheKey(review.GetBookId()))
+
+	return review, nil
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"Booking/auth"
+	pb "Booking/bookserver/test"
+	"Booking/config"
+)
+
+func TestModerationRequiresAdmin(t *testing.T) {
+	const secret = "test-secret"
+	authenticator, err := auth.NewAuthenticator(context.Background(), config.AuthConfig{HMACSecret: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	interceptor := authenticator.UnaryServerInterceptor(auth.Policy{Public: publicMethods, Admin: adminMethods})
+	token := func(roles ...string) string {
+		claims := auth.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+			Roles:            roles,
+		}
+		signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "Bearer " + signed
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		want          codes.Code
+	}{
+		{"anonymous reads reviews", pb.BookingService_ListReviews_FullMethodName, "", codes.OK},
+		{"anonymous reviews", pb.BookingService_CreateReview_FullMethodName, "", codes.Unauthenticated},
+		{"user reviews", pb.BookingService_CreateReview_FullMethodName, token("reader"), codes.OK},
+		{"user lists pending reviews", pb.BookingService_ListPendingReviews_FullMethodName, token("reader"), codes.PermissionDenied},
+		{"user moderates", pb.BookingService_ModerateReview_FullMethodName, token("reader"), codes.PermissionDenied},
+		{"anonymous moderates", pb.BookingService_ModerateReview_FullMethodName, "", codes.Unauthenticated},
+		{"admin lists pending reviews", pb.BookingService_ListPendingReviews_FullMethodName, token(auth.AdminRole), codes.OK},
+		{"admin moderates", pb.BookingService_ModerateReview_FullMethodName, token(auth.AdminRole), codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.authorization))
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("interceptor error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func review(t *testing.T, s *server, user string, bookID int64, rating int32) *pb.Review {
+	t.Helper()
+	r, err := s.CreateReview(asPrincipal(user), &pb.CreateReviewRequest{BookId: bookID, Rating: rating, Text: "Text"})
+	if err != nil {
+		t.Fatalf("CreateReview() error = %v", err)
+	}
+	return r
+}
+
+func moderate(t *testing.T, s *server, reviewID int64, reviewStatus pb.Review_Status) {
+	t.Helper()
+	_, err := s.ModerateReview(asPrincipal("moderator", auth.AdminRole), &pb.ModerateReviewRequest{Id: reviewID, Status: reviewStatus})
+	if err != nil {
+		t.Fatalf("ModerateReview() error = %v", err)
+	}
+}
+
+func TestBookRating(t *testing.T) {
+	s := newTestServer(t)
+	book := createTestBook(t, s, &pb.Book{Title: "Rated", Author: "Author", Year: 2000})
+	alice := review(t, s, "alice", book.GetId(), 5)
+	bob := review(t, s, "bob", book.GetId(), 2)
+	carol := review(t, s, "carol", book.GetId(), 4)
+
+	if _, err := s.CreateReview(asPrincipal("alice"), &pb.CreateReviewRequest{BookId: book.GetId(), Rating: 1}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("second review by alice error = %v, want AlreadyExists", err)
+	}
+	for _, rating := range []int32{0, 6} {
+		if _, err := s.CreateReview(asPrincipal("dave"), &pb.CreateReviewRequest{BookId: book.GetId(), Rating: rating}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("rating %d error = %v, want InvalidArgument", rating, err)
+		}
+	}
+
+	steps := []struct {
+		name    string
+		review  *pb.Review
+		status  pb.Review_Status
+		count   int32
+		average float64
+	}{
+		{"first approval", alice, pb.Review_APPROVED, 1, 5},
+		{"second approval", bob, pb.Review_APPROVED, 2, 3.5},
+		{"rejection", carol, pb.Review_REJECTED, 2, 3.5},
+		{"approval of a rejected review", carol, pb.Review_APPROVED, 3, 11.0 / 3},
+		{"rejection of an approved review", alice, pb.Review_REJECTED, 2, 3},
+	}
+	// Pending reviews do not count.
+	if got := readTestBook(t, s, book.GetId()); got.GetRatingCount() != 0 || got.GetRatingAverage() != 0 {
+		t.Errorf("rating with pending reviews = %v over %d, want none", got.GetRatingAverage(), got.GetRatingCount())
+	}
+	for _, step := range steps {
+		moderate(t, s, step.review.GetId(), step.status)
+		got := readTestBook(t, s, book.GetId())
+		if got.GetRatingCount() != step.count || math.Abs(got.GetRatingAverage()-step.average) > 1e-9 {
+			t.Errorf("after %s: rating = %v over %d, want %v over %d", step.name, got.GetRatingAverage(), got.GetRatingCount(), step.average, step.count)
+		}
+		if got.GetUpdatedBy() != "moderator" {
+			t.Errorf("after %s: updated by %q, want the moderator", step.name, got.GetUpdatedBy())
+		}
+	}
+}
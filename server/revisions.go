@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "Booking/bookserver/test"
+	"Booking/logging"
+	"Booking/tracing"
+)
+
+// revisionColumns is the column list matching scanRevision.
+const revisionColumns = "id, book_id, operation, before, after, actor, created_at"
+
+// inTx runs fn in a transaction, which is committed if fn succeeds.
+func (s *server) inTx(ctx context.Context, fn func(tx *tracing.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockBook returns the current state of a book and locks its row until the
+// transaction ends. It returns sql.ErrNoRows if the book does not exist.
+func lockBook(ctx context.Context, tx *tracing.Tx, id int64) (*pb.Book, error) {
+	sqlStatement := `
+		SELECT ` + bookColumns + `
+		FROM books
+		WHERE id = $1
+		FOR UPDATE
+	`
+	return scanBook(tx.QueryRowContext(ctx, sqlStatement, id))
+}
+
+// writeBookFields sets fields of the book id to their values in book and
+// returns the updated book.
+func writeBookFields(ctx context.Context, tx *tracing.Tx, id int64, book *pb.Book, fields []string) (*pb.Book, error) {
+	assignments := make([]string, 0, len(fields)+2)
+	args := make([]interface{}, 0, len(fields)+2)
+	for _, field := range fields {
+		value, err := bookFieldValue(book, field)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+		assignments = append(assignments, fmt.Sprintf("%s = $%d", bookFieldColumn(field), len(args)))
+	}
+	args = append(args, actor(ctx))
+	assignments = append(assignments, "updated_at = now()", fmt.Sprintf("updated_by = $%d", len(args)))
+	args = append(args, id)
+
+	sqlStatement := fmt.Sprintf(`
+		UPDATE books
+		SET %s
+		WHERE id = $%d
+		RETURNING %s
+	`, strings.Join(assignments, ", "), len(args), bookColumns)
+
+	return scanBook(tx.QueryRowContext(ctx, sqlStatement, args...))
+}
+
+// insertBookWithID recreates a deleted book under its original id.
+func insertBookWithID(ctx context.Context, tx *tracing.Tx, id int64, book *pb.Book) (*pb.Book, error) {
+	args := []interface{}{id}
+	for _, field := range bookFields {
+		value, err := bookFieldValue(book, field)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+	args = append(args, actor(ctx))
+
+	return scanBook(tx.QueryRowContext(ctx, insertBookStatement(true), args...))
+}
+
+// snapshot encodes a book for the before and after columns. A nil book is
+// stored as NULL.
+func snapshot(book *pb.Book) (interface{}, error) {
+	if book == nil {
+		return nil, nil
+	}
+	data, err := protojson.Marshal(book)
+	if err != nil {
+		return nil, err
+	}
+	// JSONB parameters must be sent as text, lib/pq sends []byte as bytea.
+	return string(data), nil
+}
+
+func decodeSnapshot(data []byte) (*pb.Book, error) {
+	if data == nil {
+		return nil, nil
+	}
+	book := &pb.Book{}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, book); err != nil {
+		return nil, fmt.Errorf("decode book snapshot: %w", err)
+	}
+	return book, nil
+}
+
+// recordRevision stores a change of the book id made in tx, and records
+// the events the change causes.
+func recordRevision(ctx context.Context, tx *tracing.Tx, id int64, op pb.BookRevision_Operation, before, after *pb.Book) error {
+	beforeValue, err := snapshot(before)
+	if err != nil {
+		return err
+	}
+	afterValue, err := snapshot(after)
+	if err != nil {
+		return err
+	}
+
+	sqlStatement := `
+		INSERT INTO book_revisions (book_id, operation, before, after, actor)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err = tx.ExecContext(ctx, sqlStatement, id, strings.ToLower(op.String()), beforeValue, afterValue, actor(ctx))
+	if err != nil {
+		return err
+	}
+	return recordBookEvents(ctx, tx, id, before, after)
+}
+
+// scanRevision reads a revision selected with revisionColumns.
+func scanRevision(row rowScanner) (*pb.BookRevision, error) {
+	revision := &pb.BookRevision{}
+	var operation string
+	var before, after []byte
+	var createdAt time.Time
+
+	err := row.Scan(
+		&revision.Id,
+		&revision.BookId,
+		&operation,
+		&before,
+		&after,
+		&revision.Actor,
+		&createdAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	revision.Operation = pb.BookRevision_Operation(pb.BookRevision_Operation_value[strings.ToUpper(operation)])
+	if revision.Before, err = decodeSnapshot(before); err != nil {
+		return nil, err
+	}
+	if revision.After, err = decodeSnapshot(after); err != nil {
+		return nil, err
+	}
+	revision.CreatedAt = timestamppb.New(createdAt)
+	return revision, nil
+}
+
+// readBookAsOf returns the book as it was after the last change made at or
+// before asOf. Books created before revisions were recorded have none from
+// their early days; their state at asOf is the before state of their first
+// later revision or, without one, their current state.
+func (s *server) readBookAsOf(ctx context.Context, id int64, asOf time.Time) (*pb.Book, error) {
+	notFound := status.Errorf(codes.NotFound, "book %d did not exist at %s", id, asOf.Format(time.RFC3339))
+
+	sqlStatement := `
+		SELECT after
+		FROM book_revisions
+		WHERE book_id = $1 AND created_at <= $2
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`
+	var after []byte
+	err := s.db.QueryRowContext(ctx, sqlStatement, id, asOf).Scan(&after)
+	switch {
+	case err == nil && after == nil:
+		return nil, notFound
+	case err == nil:
+		return decodeSnapshot(after)
+	case !errors.Is(err, sql.ErrNoRows):
+		logging.FromContext(ctx).Error("Failed to read book revision", "error", err)
+		return nil, err
+	}
+
+	book, err := s.readBookBeforeRevisions(ctx, id, asOf)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, notFound
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to read book revision", "error", err)
+		return nil, err
+	}
+	// A book created after asOf did not exist yet.
+	if book == nil || (book.GetCreatedAt() != nil && book.GetCreatedAt().AsTime().After(asOf)) {
+		return nil, notFound
+	}
+	return book, nil
+}
+
+// readBookBeforeRevisions returns the state of a book that has no revision
+// at or before asOf: the before state of its first later revision, or its
+// current state if it has none. It returns nil if the book was created
+// after asOf, and sql.ErrNoRows if it does not exist.
+func (s *server) readBookBeforeRevisions(ctx context.Context, id int64, asOf time.Time) (*pb.Book, error) {
+	sqlStatement := `
+		SELECT before
+		FROM book_revisions
+		WHERE book_id = $1 AND created_at > $2
+		ORDER BY created_at, id
+		LIMIT 1
+	`
+	var before []byte
+	err := s.db.QueryRowContext(ctx, sqlStatement, id, asOf).Scan(&before)
+	if err == nil {
+		return decodeSnapshot(before)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return scanBook(s.db.QueryRowContext(ctx, `SELECT `+bookColumns+` FROM books WHERE id = $1`, id))
+}
+
+func (s *server) ListBookRevisions(ctx context.Context, req *pb.ListBookRevisionsRequest) (*pb.ListBookRevisionsResponse, error) {
+	bookID := req.GetBookId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	pageSize, err := normalizePageSize(req.GetPageSize())
+	if err != nil {
+		return nil, err
+	}
+	// The page token is the id of the last revision returned.
+	var before int64
+	if token := req.GetPageToken(); token != "" {
+		before, err = strconv.ParseInt(token, 10, 64)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+	}
+
+	sqlStatement := `
+		SELECT ` + revisionColumns + `
+		FROM book_revisions
+		WHERE book_id = $1 AND ($2::bigint = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3
+	`
+
+	rows, err := s.db.QueryContext(ctx, sqlStatement, bookID, before, pageSize+1)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to list book revisions", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	response := &pb.ListBookRevisionsResponse{}
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to list book revisions", "error", err)
+			return nil, err
+		}
+		response.Revisions = append(response.Revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		logging.FromContext(ctx).Error("Failed to list book revisions", "error", err)
+		return nil, err
+	}
+
+	if len(response.Revisions) > pageSize {
+		response.Revisions = response.Revisions[:pageSize]
+		response.NextPageToken = strconv.FormatInt(response.Revisions[pageSize-1].GetId(), 10)
+	}
+	return response, nil
+}
+
+func (s *server) RevertBook(ctx context.Context, req *pb.RevertBookRequest) (*pb.Book, error) {
+	bookID := req.GetId()
+	revisionID := req.GetRevisionId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	sqlStatement := `
+		SELECT ` + revisionColumns + `
+		FROM book_revisions
+		WHERE id = $1 AND book_id = $2
+	`
+
+	var book *pb.Book
+	err := s.inTx(ctx, func(tx *tracing.Tx) error {
+		revision, err := scanRevision(tx.QueryRowContext(ctx, sqlStatement, revisionID, bookID))
+		if errors.Is(err, sql.ErrNoRows) {
+			return status.Errorf(codes.NotFound, "revision %d of book %d not found", revisionID, bookID)
+		}
+		if err != nil {
+			return err
+		}
+		if revision.GetAfter() == nil {
+			return status.Errorf(codes.FailedPrecondition, "revision %d deleted book %d and cannot be restored", revisionID, bookID)
+		}
+		// Revisions from before the genre taxonomy only have genre strings.
+		if err := resolveGenres(ctx, tx, revision.GetAfter()); err != nil {
+			return err
+		}
+
+		// A deleted book is recreated whole, since it has no current stock
+		// to keep.
+		before, err := lockBook(ctx, tx, bookID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			book, err = insertBookWithID(ctx, tx, bookID, revision.GetAfter())
+		case err == nil:
+			book, err = writeBookFields(ctx, tx, bookID, revision.GetAfter(), catalogBookFields)
+		}
+		if err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, bookID, pb.BookRevision_REVERT, before, book)
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); !ok {
+			logging.FromContext(ctx).Error("Failed to revert book", "revision_id", revisionID, "error", err)
+		}
+		return nil, err
+	}
+	s.books.Invalidate(ctx, bookCacheKey(bookID))
+
+	return book, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"Booking/auth"
+	pb "Booking/bookserver/test"
+)
+
+// updateTestBook sets the fields of mask of the book id as an admin.
+func updateTestBook(t *testing.T, s *server, id int64, book *pb.Book, mask ...string) *pb.Book {
+	t.Helper()
+	updated, err := s.UpdateBook(asPrincipal("admin", auth.AdminRole), &pb.UpdateBookRequest{
+		Id:         id,
+		Book:       book,
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: mask},
+	})
+	if err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+	return updated
+}
+
+// revisionTimes returns the creation times of the revisions of a book,
+// oldest first.
+func revisionTimes(t *testing.T, s *server, id int64) []time.Time {
+	t.Helper()
+	resp, err := s.ListBookRevisions(context.Background(), &pb.ListBookRevisionsRequest{BookId: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	times := make([]time.Time, len(resp.GetRevisions()))
+	for i, revision := range resp.GetRevisions() {
+		times[len(times)-1-i] = revision.GetCreatedAt().AsTime()
+	}
+	return times
+}
+
+func between(a, b time.Time) time.Time {
+	return a.Add(b.Sub(a) / 2)
+}
+
+func TestReadBookAsOf(t *testing.T) {
+	s := newTestServer(t)
+	book := createTestBook(t, s, &pb.Book{Title: "First", Author: "Author", Year: 2000})
+	updateTestBook(t, s, book.GetId(), &pb.Book{Title: "Second"}, "title")
+	updateTestBook(t, s, book.GetId(), &pb.Book{Title: "Third"}, "title")
+	times := revisionTimes(t, s, book.GetId())
+	if len(times) != 3 {
+		t.Fatalf("%d revisions, want 3", len(times))
+	}
+
+	tests := []struct {
+		name string
+		asOf time.Time
+		want string
+	}{
+		{"before the first revision", times[0].Add(-time.Second), ""},
+		{"at the first revision", times[0], "First"},
+		{"between revisions", between(times[0], times[1]), "First"},
+		{"at a revision", times[1], "Second"},
+		{"between later revisions", between(times[1], times[2]), "Second"},
+		{"after the last revision", times[2].Add(time.Hour), "Third"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.readBookAsOf(context.Background(), book.GetId(), tt.asOf)
+			if tt.want == "" {
+				if status.Code(err) != codes.NotFound {
+					t.Errorf("readBookAsOf() = %v, %v, want NotFound", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readBookAsOf() error = %v", err)
+			}
+			if got.GetTitle() != tt.want {
+				t.Errorf("title = %q, want %q", got.GetTitle(), tt.want)
+			}
+		})
+	}
+}
+
+func TestReadBookAsOfWithoutEarlyRevisions(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	// A book created before revisions were recorded.
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO books (title, author, year, language, price, quantity, created_at, updated_at)
+		VALUES ('Old', 'Author', 1990, 'en', 100, 1, now() - interval '2 days', now() - interval '2 days')
+		RETURNING id
+	`).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.readBookAsOf(ctx, id, time.Now().Add(-time.Hour))
+	if err != nil || got.GetTitle() != "Old" {
+		t.Fatalf("readBookAsOf() without revisions = %v, %v, want the current state", got, err)
+	}
+
+	updateTestBook(t, s, id, &pb.Book{Title: "New"}, "title")
+	times := revisionTimes(t, s, id)
+	tests := []struct {
+		name string
+		asOf time.Time
+		want string
+	}{
+		{"before it was created", time.Now().Add(-72 * time.Hour), ""},
+		{"before the first revision", times[0].Add(-time.Hour), "Old"},
+		{"after the first revision", times[0].Add(time.Hour), "New"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.readBookAsOf(ctx, id, tt.asOf)
+			if tt.want == "" {
+				if status.Code(err) != codes.NotFound {
+					t.Errorf("readBookAsOf() = %v, %v, want NotFound", got, err)
+				}
+				return
+			}
+			if err != nil || got.GetTitle() != tt.want {
+				t.Errorf("readBookAsOf() = %v, %v, want title %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevertBookKeepsStock(t *testing.T) {
+	s := newTestServer(t)
+	book := createTestBook(t, s, &pb.Book{Title: "Original", Author: "Author", Year: 2000, Price: 100, Quantity: 5})
+	resp, err := s.ListBookRevisions(context.Background(), &pb.ListBookRevisionsRequest{BookId: book.GetId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := resp.GetRevisions()[0]
+
+	updateTestBook(t, s, book.GetId(), &pb.Book{Title: "Changed", Price: 200}, "title", "price")
+	updateTestBook(t, s, book.GetId(), &pb.Book{Quantity: 2, Availability: pb.Book_OUT_OF_PRINT}, "quantity", "availability")
+
+	reverted, err := s.RevertBook(asPrincipal("admin", auth.AdminRole), &pb.RevertBookRequest{Id: book.GetId(), RevisionId: created.GetId()})
+	if err != nil {
+		t.Fatalf("RevertBook() error = %v", err)
+	}
+	if reverted.GetTitle() != "Original" || reverted.GetPrice() != 100 {
+		t.Errorf("title, price = %q, %d, want the reverted ones", reverted.GetTitle(), reverted.GetPrice())
+	}
+	if reverted.GetQuantity() != 2 || reverted.GetAvailability() != pb.Book_OUT_OF_PRINT {
+		t.Errorf("quantity, availability = %d, %v, want 2, OUT_OF_PRINT", reverted.GetQuantity(), reverted.GetAvailability())
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "Booking/bookserver/test"
+	"Booking/logging"
+	"Booking/tracing"
+)
+
+// seriesColumns is the column list matching scanSeries.
+const seriesColumns = "id, name, created_at"
+
+func scanSeries(row rowScanner) (*pb.Series, error) {
+	series := &pb.Series{}
+	var createdAt time.Time
+	if err := row.Scan(&series.Id, &series.Name, &createdAt); err != nil {
+		return nil, err
+	}
+	series.CreatedAt = timestamppb.New(createdAt)
+	return series, nil
+}
+
+func (s *server) CreateSeries(ctx context.Context, req *pb.CreateSeriesRequest) (*pb.Series, error) {
+	name := strings.TrimSpace(req.GetName())
+	if name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+
+	sqlStatement := `
+		INSERT INTO series (name)
+		VALUES ($1)
+		RETURNING ` + seriesColumns
+
+	series, err := scanSeries(s.db.QueryRowContext(ctx, sqlStatement, name))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return nil, status.Errorf(codes.AlreadyExists, "series %q already exists", name)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to create series", "error", err)
+		return nil, err
+	}
+	return series, nil
+}
+
+func (s *server) GetSeries(ctx context.Context, req *pb.GetSeriesRequest) (*pb.GetSeriesResponse, error) {
+	seriesID := req.GetId()
+
+	series, err := scanSeries(s.db.QueryRowContext(ctx, `SELECT `+seriesColumns+` FROM series WHERE id = $1`, seriesID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "series %d not found", seriesID)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get series", "error", err)
+		return nil, err
+	}
+
+	sqlStatement := `
+		SELECT ` + bookColumns + `
+		FROM books
+		WHERE series_id = $1
+		ORDER BY series_position
+	`
+
+	rows, err := s.db.QueryContext(ctx, sqlStatement, seriesID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get series", "error", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	response := &pb.GetSeriesResponse{Series: series}
+	for rows.Next() {
+		book, err := scanBook(rows)
+		if err != nil {
+			logging.FromContext(ctx).Error("Failed to get series", "error", err)
+			return nil, err
+		}
+		response.Books = append(response.Books, book)
+	}
+	if err := rows.Err(); err != nil {
+		logging.FromContext(ctx).Error("Failed to get series", "error", err)
+		return nil, err
+	}
+	return response, nil
+}
+
+func (s *server) SetBookSeries(ctx context.Context, req *pb.SetBookSeriesRequest) (*pb.Book, error) {
+	bookID := req.GetBookId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	var seriesID, position interface{}
+	if req.GetSeriesId() != 0 {
+		if p := req.GetPosition(); p <= 0 || math.IsInf(p, 0) || math.IsNaN(p) {
+			return nil, status.Error(codes.InvalidArgument, "position must be a positive number")
+		}
+		seriesID, position = req.GetSeriesId(), req.GetPosition()
+	}
+
+	sqlStatement := `
+		UPDATE books
+		SET series_id = $1, series_position = $2, updated_at = now(), updated_by = $3
+		WHERE id = $4
+		RETURNING ` + bookColumns
+
+	var book *pb.Book
+	err := s.inTx(ctx, func(tx *tracing.Tx) error {
+		before, err := lockBook(ctx, tx, bookID)
+		if err != nil {
+			return err
+		}
+		book, err = scanBook(tx.QueryRowContext(ctx, sqlStatement, seriesID, position, actor(ctx), bookID))
+		if err != nil {
+			return err
+		}
+		return recordRevision(ctx, tx, bookID, pb.BookRevision_UPDATE, before, book)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "book %d not found", bookID)
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case foreignKeyViolation:
+			return nil, status.Errorf(codes.NotFound, "series %d not found", req.GetSeriesId())
+		case uniqueViolation:
+			return nil, status.Errorf(codes.AlreadyExists, "position %g of series %d is taken", req.GetPosition(), req.GetSeriesId())
+		}
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to set book series", "error", err)
+		return nil, err
+	}
+	s.books.Invalidate(ctx, bookCacheKey(bookID))
+
+	return book, nil
+}
+
+func (s *server) GetSeriesNeighbors(ctx context.Context, req *pb.GetSeriesNeighborsRequest) (*pb.GetSeriesNeighborsResponse, error) {
+	bookID := req.GetBookId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	var seriesID sql.NullInt64
+	var position sql.NullFloat64
+	err := s.db.QueryRowContext(ctx, `SELECT series_id, series_position FROM books WHERE id = $1`, bookID).Scan(&seriesID, &position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "book %d not found", bookID)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get series neighbors", "error", err)
+		return nil, err
+	}
+	response := &pb.GetSeriesNeighborsResponse{}
+	if !seriesID.Valid {
+		return response, nil
+	}
+
+	neighbor := func(comparison, order string) (*pb.Book, error) {
+		sqlStatement := `
+			SELECT ` + bookColumns + `
+			FROM books
+			WHERE series_id = $1 AND series_position ` + comparison + ` $2
+			ORDER BY series_position ` + order + `
+			LIMIT 1
+		`
+		book, err := scanBook(s.db.QueryRowContext(ctx, sqlStatement, seriesID.Int64, position.Float64))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return book, err
+	}
+
+	if response.Previous, err = neighbor("<", "DESC"); err != nil {
+		logging.FromContext(ctx).Error("Failed to get series neighbors", "error", err)
+		return nil, err
+	}
+	if response.Next, err = neighbor(">", "ASC"); err != nil {
+		logging.FromContext(ctx).Error("Failed to get series neighbors", "error", err)
+		return nil, err
+	}
+	return response, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"math"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"Booking/auth"
+	pb "Booking/bookserver/test"
+)
+
+func setSeries(ctx context.Context, s *server, bookID, seriesID int64, position float64) error {
+	_, err := s.SetBookSeries(ctx, &pb.SetBookSeriesRequest{BookId: bookID, SeriesId: seriesID, Position: position})
+	return err
+}
+
+func TestSeries(t *testing.T) {
+	s := newTestServer(t)
+	admin := asPrincipal("admin", auth.AdminRole)
+	series, err := s.CreateSeries(admin, &pb.CreateSeriesRequest{Name: "Earthsea"})
+	if err != nil {
+		t.Fatalf("CreateSeries() error = %v", err)
+	}
+
+	// Books are added out of order, one of them between two others.
+	titles := map[float64]string{2: "The Tombs of Atuan", 1: "A Wizard of Earthsea", 3: "The Farthest Shore", 2.5: "Tales from Earthsea"}
+	ids := make(map[string]int64)
+	for _, position := range []float64{2, 1, 3, 2.5} {
+		book := createTestBook(t, s, &pb.Book{Title: titles[position], Author: "Ursula Le Guin", Year: 1970})
+		if err := setSeries(admin, s, book.GetId(), series.GetId(), position); err != nil {
+			t.Fatalf("SetBookSeries() error = %v", err)
+		}
+		ids[book.GetTitle()] = book.GetId()
+	}
+	standalone := createTestBook(t, s, &pb.Book{Title: "The Dispossessed", Author: "Ursula Le Guin", Year: 1974})
+
+	resp, err := s.GetSeries(context.Background(), &pb.GetSeriesRequest{Id: series.GetId()})
+	if err != nil {
+		t.Fatalf("GetSeries() error = %v", err)
+	}
+	var got []string
+	for _, book := range resp.GetBooks() {
+		got = append(got, book.GetTitle())
+	}
+	want := []string{"A Wizard of Earthsea", "The Tombs of Atuan", "Tales from Earthsea", "The Farthest Shore"}
+	if !slices.Equal(got, want) {
+		t.Errorf("series books = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name           string
+		bookID         int64
+		previous, next string
+	}{
+		{"first", ids["A Wizard of Earthsea"], "", "The Tombs of Atuan"},
+		{"middle", ids["Tales from Earthsea"], "The Tombs of Atuan", "The Farthest Shore"},
+		{"last", ids["The Farthest Shore"], "Tales from Earthsea", ""},
+		{"not in a series", standalone.GetId(), "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := s.GetSeriesNeighbors(context.Background(), &pb.GetSeriesNeighborsRequest{BookId: tt.bookID})
+			if err != nil {
+				t.Fatalf("GetSeriesNeighbors() error = %v", err)
+			}
+			if resp.GetPrevious().GetTitle() != tt.previous || resp.GetNext().GetTitle() != tt.next {
+				t.Errorf("neighbors = %q, %q, want %q, %q", resp.GetPrevious().GetTitle(), resp.GetNext().GetTitle(), tt.previous, tt.next)
+			}
+		})
+	}
+
+	if _, err := s.GetSeriesNeighbors(context.Background(), &pb.GetSeriesNeighborsRequest{BookId: 1 << 40}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetSeriesNeighbors() of a missing book error = %v, want NotFound", err)
+	}
+
+	invalid := []struct {
+		name     string
+		seriesID int64
+		position float64
+		want     codes.Code
+	}{
+		{"position taken", series.GetId(), 2, codes.AlreadyExists},
+		{"zero position", series.GetId(), 0, codes.InvalidArgument},
+		{"negative position", series.GetId(), -1, codes.InvalidArgument},
+		{"infinite position", series.GetId(), math.Inf(1), codes.InvalidArgument},
+		{"missing series", 1 << 40, 1, codes.NotFound},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := setSeries(admin, s, standalone.GetId(), tt.seriesID, tt.position); status.Code(err) != tt.want {
+				t.Errorf("SetBookSeries() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Leaving the series joins its neighbours.
+	if err := setSeries(admin, s, ids["Tales from Earthsea"], 0, 0); err != nil {
+		t.Fatalf("SetBookSeries() error = %v", err)
+	}
+	neighbors, err := s.GetSeriesNeighbors(context.Background(), &pb.GetSeriesNeighborsRequest{BookId: ids["The Tombs of Atuan"]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if neighbors.GetNext().GetTitle() != "The Farthest Shore" {
+		t.Errorf("next after removing a book = %q, want %q", neighbors.GetNext().GetTitle(), "The Farthest Shore")
+	}
+}
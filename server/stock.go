@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "Booking/bookserver/test"
+	"Booking/logging"
+	"Booking/tracing"
+)
+
+// recordStockEvents records the events caused by a change of the quantity
+// of a book: book.low_stock when it drops below the reorder threshold, and
+// book.back_in_stock for every subscriber when it goes from zero to
+// positive. The notified subscriptions are dropped.
+func recordStockEvents(ctx context.Context, tx *tracing.Tx, before, after *pb.Book) error {
+	if before == nil || after == nil {
+		return nil
+	}
+
+	if droppedBelowThreshold(before, after) {
+		event := &pb.Event{Type: eventLowStock, Payload: &pb.Event_LowStock{LowStock: &pb.LowStockEvent{Book: after}}}
+		if err := recordEvent(ctx, tx, after.GetId(), event); err != nil {
+			return err
+		}
+	}
+
+	if !cameBackInStock(before, after) {
+		return nil
+	}
+	rows, err := tx.QueryContext(ctx, `DELETE FROM back_in_stock_subscriptions WHERE book_id = $1 RETURNING customer_id`, after.GetId())
+	if err != nil {
+		return err
+	}
+	var customers []string
+	for rows.Next() {
+		var customerID string
+		if err := rows.Scan(&customerID); err != nil {
+			rows.Close()
+			return err
+		}
+		customers = append(customers, customerID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, customerID := range customers {
+		event := &pb.Event{Type: eventBackInStock, Payload: &pb.Event_BackInStock{BackInStock: &pb.BackInStockEvent{Book: after, CustomerId: customerID}}}
+		if err := recordEvent(ctx, tx, after.GetId(), event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// droppedBelowThreshold reports whether the quantity crossed the reorder
+// threshold of after downwards, so that a book already low on stock is only
+// reported once.
+func droppedBelowThreshold(before, after *pb.Book) bool {
+	threshold := after.GetReorderThreshold()
+	return threshold > 0 && before.GetQuantity() >= threshold && after.GetQuantity() < threshold
+}
+
+// cameBackInStock reports whether the quantity went from zero, or less, to
+// positive.
+func cameBackInStock(before, after *pb.Book) bool {
+	return before.GetQuantity() <= 0 && after.GetQuantity() > 0
+}
+
+func (s *server) SubscribeBackInStock(ctx context.Context, req *pb.SubscribeBackInStockRequest) (*pb.BackInStockSubscription, error) {
+	bookID := req.GetBookId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	customerID := actor(ctx)
+	if customerID == "" {
+		return nil, status.Error(codes.Unauthenticated, "subscriptions require an authenticated user")
+	}
+
+	// Subscribing again keeps the original subscription.
+	sqlStatement := `
+		INSERT INTO back_in_stock_subscriptions (book_id, customer_id)
+		VALUES ($1, $2)
+		ON CONFLICT (book_id, customer_id) DO UPDATE SET book_id = EXCLUDED.book_id
+		RETURNING created_at
+	`
+
+	var createdAt time.Time
+	err := s.db.QueryRowContext(ctx, sqlStatement, bookID, customerID).Scan(&createdAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return nil, status.Errorf(codes.NotFound, "book %d not found", bookID)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to subscribe to back-in-stock notifications", "error", err)
+		return nil, err
+	}
+	return &pb.BackInStockSubscription{
+		BookId:     bookID,
+		CustomerId: customerID,
+		CreatedAt:  timestamppb.New(createdAt),
+	}, nil
+}
+
+func (s *server) UnsubscribeBackInStock(ctx context.Context, req *pb.UnsubscribeBackInStockRequest) (*pb.UnsubscribeBackInStockResponse, error) {
+	bookID := req.GetBookId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	customerID := actor(ctx)
+	if customerID == "" {
+		return nil, status.Error(codes.Unauthenticated, "subscriptions require an authenticated user")
+	}
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM back_in_stock_subscriptions WHERE book_id = $1 AND customer_id = $2`, bookID, customerID)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to unsubscribe from back-in-stock notifications", "error", err)
+		return nil, err
+	}
+	return &pb.UnsubscribeBackInStockResponse{Success: true}, nil
+}
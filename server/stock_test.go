@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+
+	"Booking/auth"
+	pb "Booking/bookserver/test"
+)
+
+func TestStockTransitions(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after int32
+		threshold     int32
+		low, back     bool
+	}{
+		{"crosses the threshold", 5, 2, 3, true, false},
+		{"reaches the threshold", 5, 3, 3, false, false},
+		{"already below the threshold", 2, 1, 3, false, false},
+		{"sells out from above the threshold", 5, 0, 3, true, false},
+		{"no threshold", 5, 0, 0, false, false},
+		{"restocked below the threshold", 0, 2, 3, false, true},
+		{"restocked above the threshold", 0, 10, 3, false, true},
+		{"restocked from oversold", -1, 1, 0, false, true},
+		{"still out of stock", -2, 0, 0, false, false},
+		{"more stock", 1, 10, 0, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := &pb.Book{Quantity: tt.before, ReorderThreshold: tt.threshold}
+			after := &pb.Book{Quantity: tt.after, ReorderThreshold: tt.threshold}
+			if got := droppedBelowThreshold(before, after); got != tt.low {
+				t.Errorf("droppedBelowThreshold() = %v, want %v", got, tt.low)
+			}
+			if got := cameBackInStock(before, after); got != tt.back {
+				t.Errorf("cameBackInStock() = %v, want %v", got, tt.back)
+			}
+		})
+	}
+}
+
+// setQuantity sets the quantity of the book id as an admin.
+func setQuantity(t *testing.T, s *server, id int64, quantity int32) {
+	t.Helper()
+	_, err := s.UpdateBook(asPrincipal("admin", auth.AdminRole), &pb.UpdateBookRequest{
+		Id:         id,
+		Book:       &pb.Book{Quantity: quantity},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"quantity"}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateBook() error = %v", err)
+	}
+}
+
+// countEvents returns the number of outbox events of type eventType.
+func countEvents(t *testing.T, s *server, eventType string) int {
+	t.Helper()
+	var n int
+	if err := s.db.QueryRowContext(context.Background(), `SELECT count(*) FROM events WHERE type = $1`, eventType).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestLowStockEvent(t *testing.T) {
+	s := newTestServer(t)
+	book := createTestBook(t, s, &pb.Book{Title: "Low", Author: "Author", Year: 2020, Quantity: 5, ReorderThreshold: 3})
+
+	for _, quantity := range []int32{4, 2, 1, 0} {
+		setQuantity(t, s, book.GetId(), quantity)
+	}
+	if n := countEvents(t, s, eventLowStock); n != 1 {
+		t.Fatalf("%d low stock events after selling down, want 1", n)
+	}
+
+	setQuantity(t, s, book.GetId(), 5)
+	setQuantity(t, s, book.GetId(), 2)
+	if n := countEvents(t, s, eventLowStock); n != 2 {
+		t.Errorf("%d low stock events after restocking and selling again, want 2", n)
+	}
+}
+
+func TestBackInStockNotifiesSubscribersOnce(t *testing.T) {
+	s := newTestServer(t)
+	book := createTestBook(t, s, &pb.Book{Title: "Sold out", Author: "Author", Year: 2020})
+
+	for _, customer := range []string{"alice", "bob"} {
+		if _, err := s.SubscribeBackInStock(asPrincipal(customer), &pb.SubscribeBackInStockRequest{BookId: book.GetId()}); err != nil {
+			t.Fatalf("SubscribeBackInStock() error = %v", err)
+		}
+	}
+
+	setQuantity(t, s, book.GetId(), 3)
+	if n := countEvents(t, s, eventBackInStock); n != 2 {
+		t.Fatalf("%d back in stock events, want one per subscriber", n)
+	}
+	var subscriptions int
+	if err := s.db.QueryRowContext(context.Background(), `SELECT count(*) FROM back_in_stock_subscriptions`).Scan(&subscriptions); err != nil {
+		t.Fatal(err)
+	}
+	if subscriptions != 0 {
+		t.Errorf("%d subscriptions left after notifying, want 0", subscriptions)
+	}
+
+	// Without subscriptions, selling out and restocking notifies nobody.
+	setQuantity(t, s, book.GetId(), 0)
+	setQuantity(t, s, book.GetId(), 3)
+	if n := countEvents(t, s, eventBackInStock); n != 2 {
+		t.Errorf("%d back in stock events after restocking again, want 2", n)
+	}
+}
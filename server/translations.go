@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "Booking/bookserver/test"
+	"Booking/locale"
+	"Booking/logging"
+	"Booking/tracing"
+)
+
+// translationColumns is the column list matching scanTranslation, preceded
+// by book_id where translations of several books are loaded.
+const translationColumns = "locale, title, subtitle, description, updated_at"
+
+func scanTranslation(row rowScanner, dest ...interface{}) (*pb.BookTranslation, error) {
+	translation := &pb.BookTranslation{}
+	var updatedAt time.Time
+	dest = append(dest, &translation.Locale, &translation.Title, &translation.Subtitle, &translation.Description, &updatedAt)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	translation.UpdatedAt = timestamppb.New(updatedAt)
+	return translation, nil
+}
+
+// loadTranslations sets the translations of books.
+func (s *server) loadTranslations(ctx context.Context, books ...*pb.Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*pb.Book, len(books))
+	ids := make([]int64, 0, len(books))
+	for _, book := range books {
+		byID[book.GetId()] = book
+		ids = append(ids, book.GetId())
+	}
+
+	sqlStatement := `
+		SELECT book_id, ` + translationColumns + `
+		FROM book_translations
+		WHERE book_id = ANY($1)
+		ORDER BY book_id, locale
+	`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID int64
+		translation, err := scanTranslation(rows, &bookID)
+		if err != nil {
+			return err
+		}
+		if book := byID[bookID]; book != nil {
+			book.Translations = append(book.Translations, translation)
+		}
+	}
+	return rows.Err()
+}
+
+// localizeBook replaces the title of a book with that of the first
+// translation in chain and sets its subtitle, description and locale. The
+// book is left untranslated when none matches.
+func localizeBook(book *pb.Book, chain []string) {
+	for _, loc := range chain {
+		for _, translation := range book.GetTranslations() {
+			if translation.GetLocale() == loc {
+				book.Title = translation.GetTitle()
+				book.Subtitle = translation.GetSubtitle()
+				book.Description = translation.GetDescription()
+				book.Locale = loc
+				return
+			}
+		}
+	}
+}
+
+// localeChain returns the locales to try for a request, most preferred
+// first.
+func (s *server) localeChain(ctx context.Context, requested string) []string {
+	return locale.Chain(locale.Preferences(ctx, requested), s.fallbackLocales)
+}
+
+// touchBook records a change of a book that does not go through its
+// columns, so that caches and HTTP validators pick it up.
+func touchBook(ctx context.Context, tx *tracing.Tx, id int64) error {
+	_, err := tx.ExecContext(ctx, `UPDATE books SET updated_at = now(), updated_by = $1 WHERE id = $2`, actor(ctx), id)
+	return err
+}
+
+func (s *server) SetBookTranslation(ctx context.Context, req *pb.SetBookTranslationRequest) (*pb.BookTranslation, error) {
+	bookID := req.GetBookId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	translation := req.GetTranslation()
+	loc, err := locale.Canonical(translation.GetLocale())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid locale %q", translation.GetLocale())
+	}
+	if strings.TrimSpace(translation.GetTitle()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "title is required")
+	}
+
+	sqlStatement := `
+		INSERT INTO book_translations (book_id, locale, title, subtitle, description)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (book_id, locale) DO UPDATE
+		SET title = EXCLUDED.title, subtitle = EXCLUDED.subtitle, description = EXCLUDED.description, updated_at = now()
+		RETURNING ` + translationColumns
+
+	var saved *pb.BookTranslation
+	err = s.inTx(ctx, func(tx *tracing.Tx) error {
+		if _, err := lockBook(ctx, tx, bookID); err != nil {
+			return err
+		}
+		var err error
+		saved, err = scanTranslation(tx.QueryRowContext(ctx, sqlStatement,
+			bookID, loc, translation.GetTitle(), translation.GetSubtitle(), translation.GetDescription()))
+		if err != nil {
+			return err
+		}
+		return touchBook(ctx, tx, bookID)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "book %d not found", bookID)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to set book translation", "error", err)
+		return nil, err
+	}
+	s.books.Invalidate(ctx, bookCacheKey(bookID))
+
+	return saved, nil
+}
+
+func (s *server) ListBookTranslations(ctx context.Context, req *pb.ListBookTranslationsRequest) (*pb.ListBookTranslationsResponse, error) {
+	bookID := req.GetBookId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM books WHERE id = $1)`, bookID).Scan(&exists)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to list book translations", "error", err)
+		return nil, err
+	}
+	if !exists {
+		return nil, status.Errorf(codes.NotFound, "book %d not found", bookID)
+	}
+
+	book := &pb.Book{Id: bookID}
+	if err := s.loadTranslations(ctx, book); err != nil {
+		logging.FromContext(ctx).Error("Failed to list book translations", "error", err)
+		return nil, err
+	}
+	return &pb.ListBookTranslationsResponse{Translations: book.GetTranslations()}, nil
+}
+
+func (s *server) DeleteBookTranslation(ctx context.Context, req *pb.DeleteBookTranslationRequest) (*pb.DeleteBookTranslationResponse, error) {
+	bookID := req.GetBookId()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("book.id", bookID))
+
+	loc, err := locale.Canonical(req.GetLocale())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid locale %q", req.GetLocale())
+	}
+
+	var deleted bool
+	err = s.inTx(ctx, func(tx *tracing.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM book_translations WHERE book_id = $1 AND locale = $2`, bookID, loc)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil || n == 0 {
+			return err
+		}
+		deleted = true
+		return touchBook(ctx, tx, bookID)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to delete book translation", "error", err)
+		return nil, err
+	}
+	if deleted {
+		s.books.Invalidate(ctx, bookCacheKey(bookID))
+	}
+
+	return &pb.DeleteBookTranslationResponse{Success: true}, nil
+}
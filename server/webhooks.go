@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "Booking/bookserver/test"
+	"Booking/logging"
+	"Booking/webhooks"
+)
+
+func webhookEndpointToProto(e *webhooks.Endpoint) *pb.WebhookEndpoint {
+	return &pb.WebhookEndpoint{
+		Id:          e.ID,
+		Url:         e.URL,
+		EventTypes:  e.EventTypes,
+		Description: e.Description,
+		Enabled:     e.Enabled,
+		CreatedBy:   e.CreatedBy,
+		CreatedAt:   timestamppb.New(e.CreatedAt),
+	}
+}
+
+func webhookDeliveryToProto(d *webhooks.Delivery) *pb.WebhookDelivery {
+	delivery := &pb.WebhookDelivery{
+		Id:             d.ID,
+		EndpointId:     d.EndpointID,
+		EventId:        d.EventID,
+		EventType:      d.EventType,
+		Status:         pb.WebhookDelivery_Status(pb.WebhookDelivery_Status_value[strings.ToUpper(d.Status)]),
+		Attempts:       int32(d.Attempts),
+		LastStatusCode: int32(d.LastStatusCode),
+		LastError:      d.LastError,
+		CreatedAt:      timestamppb.New(d.CreatedAt),
+		Payload:        string(d.Payload),
+	}
+	if d.Status == webhooks.StatusPending {
+		delivery.NextAttemptAt = timestamppb.New(d.NextAttemptAt)
+	}
+	if d.DeliveredAt.Valid {
+		delivery.DeliveredAt = timestamppb.New(d.DeliveredAt.Time)
+	}
+	return delivery
+}
+
+// webhookEndpointFromProto validates an endpoint of a request.
+func webhookEndpointFromProto(endpoint *pb.WebhookEndpoint) (*webhooks.Endpoint, error) {
+	if endpoint == nil {
+		return nil, status.Error(codes.InvalidArgument, "endpoint is required")
+	}
+	for _, eventType := range endpoint.GetEventTypes() {
+		if !slices.Contains(eventTypes, eventType) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown event type %q", eventType)
+		}
+	}
+	return &webhooks.Endpoint{
+		URL:         strings.TrimSpace(endpoint.GetUrl()),
+		EventTypes:  endpoint.GetEventTypes(),
+		Description: endpoint.GetDescription(),
+		Enabled:     endpoint.GetEnabled(),
+	}, nil
+}
+
+func (s *server) CreateWebhookEndpoint(ctx context.Context, req *pb.CreateWebhookEndpointRequest) (*pb.CreateWebhookEndpointResponse, error) {
+	endpoint, err := webhookEndpointFromProto(req.GetEndpoint())
+	if err != nil {
+		return nil, err
+	}
+	endpoint.CreatedBy = actor(ctx)
+
+	created, secret, err := s.webhooks.CreateEndpoint(ctx, endpoint)
+	if errors.Is(err, webhooks.ErrInvalidURL) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to create webhook endpoint", "error", err)
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Webhook endpoint created", "webhook_endpoint_id", created.ID)
+	return &pb.CreateWebhookEndpointResponse{
+		Endpoint: webhookEndpointToProto(created),
+		Secret:   secret,
+	}, nil
+}
+
+func (s *server) GetWebhookEndpoint(ctx context.Context, req *pb.GetWebhookEndpointRequest) (*pb.WebhookEndpoint, error) {
+	endpoint, err := s.webhooks.GetEndpoint(ctx, req.GetId())
+	if errors.Is(err, webhooks.ErrEndpointNotFound) {
+		return nil, status.Errorf(codes.NotFound, "webhook endpoint %d not found", req.GetId())
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get webhook endpoint", "error", err)
+		return nil, err
+	}
+	return webhookEndpointToProto(endpoint), nil
+}
+
+func (s *server) ListWebhookEndpoints(ctx context.Context, req *pb.ListWebhookEndpointsRequest) (*pb.ListWebhookEndpointsResponse, error) {
+	endpoints, err := s.webhooks.ListEndpoints(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to list webhook endpoints", "error", err)
+		return nil, err
+	}
+
+	response := &pb.ListWebhookEndpointsResponse{Endpoints: make([]*pb.WebhookEndpoint, 0, len(endpoints))}
+	for _, endpoint := range endpoints {
+		response.Endpoints = append(response.Endpoints, webhookEndpointToProto(endpoint))
+	}
+	return response, nil
+}
+
+func (s *server) UpdateWebhookEndpoint(ctx context.Context, req *pb.UpdateWebhookEndpointRequest) (*pb.WebhookEndpoint, error) {
+	endpoint, err := webhookEndpointFromProto(req.GetEndpoint())
+	if err != nil {
+		return nil, err
+	}
+	endpoint.ID = req.GetId()
+
+	updated, err := s.webhooks.UpdateEndpoint(ctx, endpoint)
+	switch {
+	case errors.Is(err, webhooks.ErrInvalidURL):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, webhooks.ErrEndpointNotFound):
+		return nil, status.Errorf(codes.NotFound, "webhook endpoint %d not found", req.GetId())
+	case err != nil:
+		logging.FromContext(ctx).Error("Failed to update webhook endpoint", "error", err)
+		return nil, err
+	}
+	return webhookEndpointToProto(updated), nil
+}
+
+func (s *server) DeleteWebhookEndpoint(ctx context.Context, req *pb.DeleteWebhookEndpointRequest) (*pb.DeleteWebhookEndpointResponse, error) {
+	err := s.webhooks.DeleteEndpoint(ctx, req.GetId())
+	if errors.Is(err, webhooks.ErrEndpointNotFound) {
+		return nil, status.Errorf(codes.NotFound, "webhook endpoint %d not found", req.GetId())
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to delete webhook endpoint", "error", err)
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Webhook endpoint deleted", "webhook_endpoint_id", req.GetId())
+	return &pb.DeleteWebhookEndpointResponse{Success: true}, nil
+}
+
+func (s *server) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	pageSize, err := normalizePageSize(req.GetPageSize())
+	if err != nil {
+		return nil, err
+	}
+	before, err := idPageToken(req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	var deliveryStatus string
+	if st := req.GetStatus(); st != pb.WebhookDelivery_STATUS_UNSPECIFIED {
+		deliveryStatus = strings.ToLower(st.String())
+	}
+
+	// One extra delivery tells whether there is a next page.
+	deliveries, err := s.webhooks.ListDeliveries(ctx, req.GetEndpointId(), deliveryStatus, before, pageSize+1)
+	if errors.Is(err, webhooks.ErrEndpointNotFound) {
+		return nil, status.Errorf(codes.NotFound, "webhook endpoint %d not found", req.GetEndpointId())
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to list webhook deliveries", "error", err)
+		return nil, err
+	}
+
+	response := &pb.ListWebhookDeliveriesResponse{}
+	for _, delivery := range deliveries {
+		response.Deliveries = append(response.Deliveries, webhookDeliveryToProto(delivery))
+	}
+	if len(response.Deliveries) > pageSize {
+		response.Deliveries = response.Deliveries[:pageSize]
+		response.NextPageToken = strconv.FormatInt(response.Deliveries[pageSize-1].GetId(), 10)
+	}
+	return response, nil
+}
+
+func (s *server) GetWebhookDelivery(ctx context.Context, req *pb.GetWebhookDeliveryRequest) (*pb.WebhookDelivery, error) {
+	delivery, attempts, err := s.webhooks.GetDelivery(ctx, req.GetId())
+	if errors.Is(err, webhooks.ErrDeliveryNotFound) {
+		return nil, status.Errorf(codes.NotFound, "webhook delivery %d not found", req.GetId())
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to get webhook delivery", "error", err)
+		return nil, err
+	}
+
+	response := webhookDeliveryToProto(delivery)
+	for _, a := range attempts {
+		response.AttemptLog = append(response.AttemptLog, &pb.WebhookDeliveryAttempt{
+			AttemptedAt:  timestamppb.New(a.AttemptedAt),
+			StatusCode:   int32(a.StatusCode),
+			Error:        a.Error,
+			DurationMs:   a.Duration.Milliseconds(),
+			ResponseBody: a.ResponseBody,
+		})
+	}
+	return response, nil
+}
+
+func (s *server) ReplayWebhookDeliveries(ctx context.Context, req *pb.ReplayWebhookDeliveriesRequest) (*pb.ReplayWebhookDeliveriesResponse, error) {
+	replayed, err := s.webhooks.Replay(ctx, req.GetEndpointId(), req.GetDeliveryIds())
+	if errors.Is(err, webhooks.ErrEndpointNotFound) {
+		return nil, status.Errorf(codes.NotFound, "webhook endpoint %d not found", req.GetEndpointId())
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("Failed to replay webhook deliveries", "error", err)
+		return nil, err
+	}
+
+	logging.FromContext(ctx).Info("Webhook deliveries replayed", "webhook_endpoint_id", req.GetEndpointId(), "count", replayed)
+	return &pb.ReplayWebhookDeliveriesResponse{Replayed: int32(replayed)}, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// workerGroup runs background workers that must be stopped before the
+// database pool they depend on is closed.
+type workerGroup struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func newWorkerGroup() *workerGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &workerGroup{ctx: ctx, cancel: cancel}
+}
+
+// Go starts fn in its own goroutine. The context passed to fn is cancelled
+// when Stop is called.
+func (g *workerGroup) Go(fn func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		fn(g.ctx)
+	}()
+}
+
+// Stop cancels all workers and waits for them to return.
+func (g *workerGroup) Stop() {
+	g.cancel()
+	g.wg.Wait()
+}
@@ -0,0 +1,228 @@
+// Package tlsconfig builds the TLS configuration of the gRPC and HTTP
+// listeners and of the gateway's connection to the gRPC server. Certificates
+// are read from disk and reloaded when the files change, so that they can be
+// rotated without a restart.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"Booking/config"
+)
+
+// clientAuthTypes maps the config values to the tls client auth modes.
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"none":     tls.NoClientCert,
+	"optional": tls.VerifyClientCertIfGiven,
+	"require":  tls.RequireAndVerifyClientCert,
+}
+
+// material is one loaded set of certificate files.
+type material struct {
+	cert *tls.Certificate
+	pool *x509.CertPool
+}
+
+// Reloader holds a certificate, and optionally a CA pool, loaded from files
+// that are watched for changes. It is safe for concurrent use.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	current atomic.Pointer[material]
+
+	mu       sync.Mutex
+	modTimes map[string]time.Time
+}
+
+// NewReloader loads the key pair and, if caFile is not empty, the CA
+// bundle. certFile and keyFile may be empty when only a CA is needed.
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reloader) files() []string {
+	var files []string
+	for _, f := range []string{r.certFile, r.keyFile, r.caFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+func (r *Reloader) stat() (map[string]time.Time, error) {
+	modTimes := make(map[string]time.Time)
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[f] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// Reload reads the files again. The current certificates are kept if the
+// files are invalid.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTimes, err := r.stat()
+	if err != nil {
+		return fmt.Errorf("stat certificate files: %w", err)
+	}
+
+	m := &material{}
+	if r.certFile != "" {
+		cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return fmt.Errorf("load key pair: %w", err)
+		}
+		m.cert = &cert
+	}
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("read CA file: %w", err)
+		}
+		m.pool = x509.NewCertPool()
+		if !m.pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in CA file %s", r.caFile)
+		}
+	}
+
+	r.current.Store(m)
+	r.modTimes = modTimes
+	return nil
+}
+
+func (r *Reloader) changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTimes, err := r.stat()
+	if err != nil {
+		return false
+	}
+	for f, t := range modTimes {
+		if !t.Equal(r.modTimes[f]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch reloads the certificates whenever one of the files changes,
+// checking every interval until ctx is cancelled.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.Reload(); err != nil {
+				slog.Error("Failed to reload certificates, keeping the previous ones", "cert_file", r.certFile, "error", err)
+				continue
+			}
+			slog.Info("Certificates reloaded", "cert_file", r.certFile)
+		}
+	}
+}
+
+// GetCertificate is meant for tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := r.current.Load().cert
+	if cert == nil {
+		return nil, errors.New("no certificate configured")
+	}
+	return cert, nil
+}
+
+// GetClientCertificate is meant for tls.Config.GetClientCertificate. It
+// returns an empty certificate when none is configured, which sends no
+// client certificate.
+func (r *Reloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	if cert := r.current.Load().cert; cert != nil {
+		return cert, nil
+	}
+	return &tls.Certificate{}, nil
+}
+
+// CAPool returns the current CA pool, or nil when no CA file is configured.
+func (r *Reloader) CAPool() *x509.CertPool {
+	return r.current.Load().pool
+}
+
+// ServerConfig returns the TLS configuration of a listener and the
+// Reloader behind it. Client certificates are verified against the client
+// CA, which is reloaded along with the server certificate.
+func ServerConfig(cfg config.TLSConfig) (*tls.Config, *Reloader, error) {
+	r, err := NewReloader(cfg.CertFile, cfg.KeyFile, cfg.ClientCAFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	clientAuth, ok := clientAuthTypes[cfg.ClientAuth]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported client auth mode %q", cfg.ClientAuth)
+	}
+
+	base := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+		ClientAuth:     clientAuth,
+	}
+	if clientAuth == tls.NoClientCert {
+		return base, r, nil
+	}
+
+	// The client CA pool is not looked up through a callback, so a copy of
+	// the config with the current pool is made for every handshake. That
+	// copy does not see the ALPN protocols added by grpc and net/http to
+	// their own copies of the config, hence the explicit NextProtos.
+	base.NextProtos = []string{"h2", "http/1.1"}
+	tlsCfg := base.Clone()
+	tlsCfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := base.Clone()
+		c.ClientCAs = r.CAPool()
+		return c, nil
+	}
+	return tlsCfg, r, nil
+}
+
+// ClientConfig returns the TLS configuration for dialing a server and the
+// Reloader of the client certificate. The CA file is read once; the system
+// roots are trusted when none is configured.
+func ClientConfig(cfg config.TLSClientConfig) (*tls.Config, *Reloader, error) {
+	r, err := NewReloader(cfg.CertFile, cfg.KeyFile, cfg.CAFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &tls.Config{
+		MinVersion:           tls.VersionTLS12,
+		ServerName:           cfg.ServerName,
+		RootCAs:              r.CAPool(),
+		GetClientCertificate: r.GetClientCertificate,
+	}, r, nil
+}
@@ -0,0 +1,262 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Booking/config"
+)
+
+// testCA is a certificate authority issuing certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for localhost with serial.
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// handshake runs a TLS handshake between server and client over a pipe and
+// returns the error of each side.
+func handshake(serverCfg, clientCfg *tls.Config) (serverErr, clientErr error, serverCert *x509.Certificate) {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		server := tls.Server(serverConn, serverCfg)
+		err := server.Handshake()
+		if err == nil {
+			// TLS 1.3 clients finish before the server has checked their
+			// certificate; reading surfaces a rejection.
+			_, err = server.Write([]byte{1})
+		}
+		serverConn.Close()
+		done <- err
+	}()
+
+	client := tls.Client(clientConn, clientCfg)
+	clientErr = client.Handshake()
+	if clientErr == nil {
+		serverCert = client.ConnectionState().PeerCertificates[0]
+		_, clientErr = client.Read(make([]byte, 1))
+	}
+	clientConn.Close()
+	return <-done, clientErr, serverCert
+}
+
+func TestWatchServesRotatedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	certPEM, keyPEM := ca.issue(t, 10, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	serverCfg, reloader, err := ServerConfig(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: "none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloader.Watch(ctx, 10*time.Millisecond)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientCfg := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+
+	_, clientErr, cert := handshake(serverCfg, clientCfg)
+	if clientErr != nil {
+		t.Fatalf("handshake: %v", clientErr)
+	}
+	if cert.SerialNumber.Int64() != 10 {
+		t.Fatalf("serial = %v, want 10", cert.SerialNumber)
+	}
+
+	certPEM, keyPEM = ca.issue(t, 11, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	// Coarse file system clocks may leave the modification time unchanged.
+	later := time.Now().Add(time.Minute)
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, clientErr, cert = handshake(serverCfg, clientCfg)
+		if clientErr != nil {
+			t.Fatalf("handshake after rotation: %v", clientErr)
+		}
+		if cert.SerialNumber.Int64() == 11 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("serial = %v after rotation, want 11", cert.SerialNumber)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReloadKeepsCertificateOnInvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	certPEM, keyPEM := ca.issue(t, 10, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	r, err := NewReloader(certFile, keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, certFile, []byte("not a certificate"))
+	if err := r.Reload(); err == nil {
+		t.Fatal("Reload() of an invalid certificate succeeded")
+	}
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaf.SerialNumber.Int64() != 10 {
+		t.Errorf("serial = %v, want the previous certificate's 10", leaf.SerialNumber)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, otherCA := newTestCA(t), newTestCA(t)
+
+	certFile, keyFile := filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	certPEM, keyPEM := ca.issue(t, 10, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	caFile := filepath.Join(dir, "ca.crt")
+	writeFile(t, caFile, ca.pem)
+
+	serverCfg, _, err := ServerConfig(config.TLSConfig{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		ClientCAFile: caFile,
+		ClientAuth:   "require",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientConfig := func(t *testing.T, issuer *testCA) *tls.Config {
+		t.Helper()
+		cfg := config.TLSClientConfig{CAFile: caFile, ServerName: "localhost"}
+		if issuer != nil {
+			certPEM, keyPEM := issuer.issue(t, 20, x509.ExtKeyUsageClientAuth)
+			cfg.CertFile = filepath.Join(t.TempDir(), "client.crt")
+			cfg.KeyFile = filepath.Join(t.TempDir(), "client.key")
+			writeFile(t, cfg.CertFile, certPEM)
+			writeFile(t, cfg.KeyFile, keyPEM)
+		}
+		clientCfg, _, err := ClientConfig(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return clientCfg
+	}
+
+	tests := []struct {
+		name   string
+		issuer *testCA
+		ok     bool
+	}{
+		{"signed by the client CA", ca, true},
+		{"signed by another CA", otherCA, false},
+		{"no client certificate", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverErr, _, _ := handshake(serverCfg, clientConfig(t, tt.issuer))
+			if tt.ok && serverErr != nil {
+				t.Errorf("server handshake: %v", serverErr)
+			}
+			if !tt.ok && serverErr == nil {
+				t.Error("server accepted the client")
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "Booking/tracing"
+
+// DB wraps a *sql.DB and records a span around every statement it runs.
+type DB struct {
+	*sql.DB
+}
+
+// WrapDB returns a traced view of db.
+func WrapDB(db *sql.DB) *DB {
+	return &DB{DB: db}
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startSQLSpan(ctx, query)
+	res, err := db.DB.ExecContext(ctx, query, args...)
+	endSQLSpan(span, err)
+	return res, err
+}
+
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startSQLSpan(ctx, query)
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	endSQLSpan(span, err)
+	return rows, err
+}
+
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startSQLSpan(ctx, query)
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	endSQLSpan(span, row.Err())
+	return row
+}
+
+// BeginTx starts a transaction whose statements are traced like those of
+// db.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	ctx, span := startSQLSpan(ctx, "BEGIN")
+	tx, err := db.DB.BeginTx(ctx, opts)
+	endSQLSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx}, nil
+}
+
+// Tx wraps a *sql.Tx and records a span around every statement it runs.
+type Tx struct {
+	*sql.Tx
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startSQLSpan(ctx, query)
+	res, err := tx.Tx.ExecContext(ctx, query, args...)
+	endSQLSpan(span, err)
+	return res, err
+}
+
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startSQLSpan(ctx, query)
+	rows, err := tx.Tx.QueryContext(ctx, query, args...)
+	endSQLSpan(span, err)
+	return rows, err
+}
+
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startSQLSpan(ctx, query)
+	row := tx.Tx.QueryRowContext(ctx, query, args...)
+	endSQLSpan(span, row.Err())
+	return row
+}
+
+func startSQLSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	query = strings.TrimSpace(query)
+	operation := query
+	if i := strings.IndexFunc(query, func(r rune) bool { return r == ' ' || r == '\n' || r == '\t' }); i > 0 {
+		operation = query[:i]
+	}
+	operation = strings.ToUpper(operation)
+
+	return otel.Tracer(instrumentationName).Start(ctx, "SQL "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperation(operation),
+			attribute.String("db.statement", query),
+		),
+	)
+}
+
+func endSQLSpan(span trace.Span, err error) {
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
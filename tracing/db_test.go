@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider that records ended spans for the
+// rest of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func attributeValue(span sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+func TestSQLSpan(t *testing.T) {
+	recorder := recordSpans(t)
+
+	tests := []struct {
+		query     string
+		err       error
+		name      string
+		operation string
+		status    codes.Code
+	}{
+		{"\n\t\tselect id FROM books WHERE id = $1", nil, "SQL SELECT", "SELECT", codes.Unset},
+		{"UPDATE\nbooks SET quantity = 0", errors.New("deadlock detected"), "SQL UPDATE", "UPDATE", codes.Error},
+		{"BEGIN", nil, "SQL BEGIN", "BEGIN", codes.Unset},
+		{"SELECT 1", sql.ErrNoRows, "SQL SELECT", "SELECT", codes.Unset},
+	}
+	for _, tt := range tests {
+		_, span := startSQLSpan(context.Background(), tt.query)
+		endSQLSpan(span, tt.err)
+
+		ended := recorder.Ended()
+		got := ended[len(ended)-1]
+		if got.Name() != tt.name {
+			t.Errorf("span name = %q, want %q", got.Name(), tt.name)
+		}
+		if op := attributeValue(got, "db.operation"); op != tt.operation {
+			t.Errorf("db.operation = %q, want %q", op, tt.operation)
+		}
+		if system := attributeValue(got, "db.system"); system != "postgresql" {
+			t.Errorf("db.system = %q, want postgresql", system)
+		}
+		if got.Status().Code != tt.status {
+			t.Errorf("span status for %v = %v, want %v", tt.err, got.Status().Code, tt.status)
+		}
+	}
+}
+
+func TestSQLSpanParent(t *testing.T) {
+	recorder := recordSpans(t)
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "ReadBook")
+	_, span := startSQLSpan(ctx, "SELECT 1")
+	endSQLSpan(span, nil)
+	parent.End()
+
+	ended := recorder.Ended()
+	if len(ended) != 2 || ended[0].Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("SQL span is not a child of the RPC span")
+	}
+}
@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+
+	"Booking/config"
+)
+
+func TestSetup(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	ctx := context.Background()
+
+	shutdown, err := Setup(ctx, config.TracingConfig{Exporter: "none"})
+	if err != nil {
+		t.Fatalf("Setup(none) error = %v", err)
+	}
+	if otel.GetTracerProvider() != previous {
+		t.Error("Setup(none) replaced the tracer provider")
+	}
+	if err := shutdown(ctx); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+
+	if _, err := Setup(ctx, config.TracingConfig{Exporter: "zipkin"}); err == nil {
+		t.Error("Setup() with an unknown exporter succeeded")
+	}
+
+	shutdown, err = Setup(ctx, config.TracingConfig{Exporter: "stdout", SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("Setup(stdout) error = %v", err)
+	}
+	_, span := otel.Tracer("test").Start(ctx, "ReadBook")
+	if !span.SpanContext().IsSampled() {
+		t.Error("span is not sampled with a sample ratio of 1")
+	}
+	span.End()
+	if err := shutdown(ctx); err != nil {
+		t.Errorf("shutdown() error = %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"Booking/config"
+	"Booking/tracing"
+)
+
+// Headers of a delivery besides SignatureHeader.
+const (
+	DeliveryIDHeader = "Webhook-Id"
+	EventTypeHeader  = "Webhook-Event"
+)
+
+// maxResponseBody is the length of the response body start kept in the
+// attempt log.
+const maxResponseBody = 1024
+
+// Dispatcher sends due deliveries. Every replica runs one; deliveries are
+// leased while they are sent, so that each attempt is made once.
+type Dispatcher struct {
+	db     *tracing.DB
+	client *http.Client
+	cfg    config.WebhooksConfig
+}
+
+// NewDispatcher returns a Dispatcher. Redirects are not followed, so that
+// an endpoint cannot send deliveries elsewhere.
+func NewDispatcher(db *tracing.DB, cfg config.WebhooksConfig) *Dispatcher {
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &Dispatcher{db: db, client: client, cfg: cfg}
+}
+
+// job is a leased delivery.
+type job struct {
+	id        int64
+	eventType string
+	payload   []byte
+	attempts  int
+	url       string
+	secret    string
+}
+
+// Run sends due deliveries until ctx is cancelled, then waits for those
+// being sent. At most Concurrency deliveries are sent at once; every
+// PollInterval, as many due deliveries are claimed as there are free slots,
+// so that a slow endpoint holds up only the slots of its own deliveries.
+func (d *Dispatcher) Run(ctx context.Context) {
+	slots := make(chan struct{}, d.cfg.Concurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.dispatchDue(ctx, slots, &wg); err != nil && ctx.Err() == nil {
+				slog.Error("Failed to dispatch webhook deliveries", "error", err)
+			}
+		}
+	}
+}
+
+// dispatchDue claims due deliveries for the free slots and sends each in a
+// goroutine that frees its slot when done.
+func (d *Dispatcher) dispatchDue(ctx context.Context, slots chan struct{}, wg *sync.WaitGroup) error {
+	for {
+		free := cap(slots) - len(slots)
+		if free == 0 {
+			return nil
+		}
+		jobs, err := d.claim(ctx, free)
+		if err != nil {
+			return err
+		}
+		for _, j := range jobs {
+			// Only Run takes slots, so this does not block.
+			slots <- struct{}{}
+			wg.Add(1)
+			go func(j job) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				d.deliver(ctx, j)
+			}(j)
+		}
+		if len(jobs) < free {
+			return nil
+		}
+	}
+}
+
+// claim leases up to limit due deliveries to enabled endpoints for long
+// enough to send them. A delivery whose replica stopped is retried when its
+// lease runs out.
+func (d *Dispatcher) claim(ctx context.Context, limit int) ([]job, error) {
+	sqlStatement := `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = now() + make_interval(secs => $2::float8)
+		FROM webhook_endpoints e
+		WHERE e.id = d.endpoint_id AND d.id IN (
+			SELECT dd.id
+			FROM webhook_deliveries dd
+			JOIN webhook_endpoints de ON de.id = dd.endpoint_id
+			WHERE dd.status = 'pending' AND dd.next_attempt_at <= now() AND de.enabled
+			ORDER BY dd.next_attempt_at, dd.id
+			LIMIT $1
+			FOR UPDATE OF dd SKIP LOCKED
+		)
+		RETURNING d.id, d.event_type, d.payload, d.attempts, e.url, e.secret
+	`
+	lease := 2 * d.cfg.Timeout
+	rows, err := d.db.QueryContext(ctx, sqlStatement, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []job
+	for rows.Next() {
+		var j job
+		var payload string
+		if err := rows.Scan(&j.id, &j.eventType, &payload, &j.attempts, &j.url, &j.secret); err != nil {
+			return nil, err
+		}
+		j.payload = []byte(payload)
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// deliver makes one attempt at a delivery and records its outcome.
+func (d *Dispatcher) deliver(ctx context.Context, j job) {
+	start := time.Now()
+	statusCode, responseBody, err := Send(ctx, d.client, j.url, j.secret, j.id, j.eventType, j.payload)
+	duration := time.Since(start)
+	if ctx.Err() != nil {
+		// Shutting down; the lease runs out and the delivery is retried.
+		return
+	}
+
+	var errText string
+	switch {
+	case err != nil:
+		errText = err.Error()
+	case statusCode < 200 || statusCode > 299:
+		errText = "HTTP " + strconv.Itoa(statusCode)
+	}
+	attempts := j.attempts + 1
+	status := StatusPending
+	switch {
+	case errText == "":
+		status = StatusSucceeded
+	case attempts >= d.cfg.MaxAttempts:
+		status = StatusFailed
+	}
+
+	if err := d.record(ctx, j.id, attempts, status, statusCode, errText, duration, responseBody); err != nil {
+		slog.Error("Failed to record webhook delivery attempt", "delivery_id", j.id, "error", err)
+	}
+}
+
+func (d *Dispatcher) record(ctx context.Context, id int64, attempts int, status string, statusCode int, errText string, duration time.Duration, responseBody string) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO webhook_delivery_attempts (delivery_id, status_code, error, duration_ms, response_body)
+		VALUES ($1, $2, $3, $4, $5)
+	`, id, statusCode, errText, duration.Milliseconds(), responseBody)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET attempts = $2, status = $3::text, next_attempt_at = $4, last_status_code = $5, last_error = $6,
+			delivered_at = CASE WHEN $3::text = 'succeeded' THEN now() END
+		WHERE id = $1
+	`, id, attempts, status, time.Now().Add(d.backoff(attempts)), statusCode, errText)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// backoff returns the delay before the next attempt after attempts failed
+// ones: InitialBackoff doubled for every further attempt, at most
+// MaxBackoff, less up to a quarter so that retries after an outage of an
+// endpoint are spread out.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.InitialBackoff
+	for i := 1; i < attempts && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.cfg.MaxBackoff {
+		delay = d.cfg.MaxBackoff
+	}
+	return delay - time.Duration(rand.Int63n(int64(delay/4)+1))
+}
+
+// Send POSTs a payload to url, signed with secret, and returns the response
+// status code with the start of the response body.
+func Send(ctx context.Context, client *http.Client, url, secret string, deliveryID int64, eventType string, payload []byte) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Booking-Webhooks/1")
+	req.Header.Set(DeliveryIDHeader, strconv.FormatInt(deliveryID, 10))
+	req.Header.Set(EventTypeHeader, eventType)
+	req.Header.Set(SignatureHeader, Sign(secret, time.Now(), payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		return resp.StatusCode, "", fmt.Errorf("read response: %w", err)
+	}
+	// The rest is drained so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, validUTF8(body), nil
+}
+
+// validUTF8 returns body as a string that can be stored in a TEXT column,
+// which rejects NUL bytes and invalid UTF-8 such as a rune cut off by
+// maxResponseBody.
+func validUTF8(body []byte) string {
+	return strings.ToValidUTF8(strings.ReplaceAll(string(body), "\x00", ""), "\uFFFD")
+}
@@ -0,0 +1,125 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"Booking/config"
+)
+
+func TestSend(t *testing.T) {
+	const secret = "whsec_test"
+	payload := []byte(`{"type":"book.updated"}`)
+
+	var verifyErr error
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		header = r.Header.Clone()
+		verifyErr = Verify(secret, r.Header.Get(SignatureHeader), body, time.Now(), time.Minute)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = io.WriteString(w, "queued")
+	}))
+	defer srv.Close()
+
+	statusCode, body, err := Send(context.Background(), srv.Client(), srv.URL, secret, 42, "book.updated", payload)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if statusCode != http.StatusAccepted || body != "queued" {
+		t.Errorf("Send() = %d, %q, want %d, %q", statusCode, body, http.StatusAccepted, "queued")
+	}
+	if verifyErr != nil {
+		t.Errorf("Verify() of the sent signature = %v", verifyErr)
+	}
+	if got := header.Get(DeliveryIDHeader); got != "42" {
+		t.Errorf("%s = %q, want %q", DeliveryIDHeader, got, "42")
+	}
+	if got := header.Get(EventTypeHeader); got != "book.updated" {
+		t.Errorf("%s = %q, want %q", EventTypeHeader, got, "book.updated")
+	}
+}
+
+func TestSendTruncatesResponseBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = io.WriteString(w, strings.Repeat("é", maxResponseBody))
+	}))
+	defer srv.Close()
+
+	statusCode, body, err := Send(context.Background(), srv.Client(), srv.URL, "s", 1, "book.created", []byte("{}"))
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if statusCode != http.StatusInternalServerError {
+		t.Errorf("status code = %d, want %d", statusCode, http.StatusInternalServerError)
+	}
+	if len(body) > maxResponseBody+len("�") {
+		t.Errorf("len(body) = %d, want at most %d", len(body), maxResponseBody)
+	}
+	if !strings.HasPrefix(body, "éé") {
+		t.Errorf("body = %q, want the start of the response", body[:10])
+	}
+}
+
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	redirected := false
+	target := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+	srv := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer srv.Close()
+
+	d := NewDispatcher(nil, config.WebhooksConfig{Timeout: time.Second})
+	statusCode, _, err := Send(context.Background(), d.client, srv.URL, "s", 1, "book.created", []byte("{}"))
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if statusCode != http.StatusTemporaryRedirect || redirected {
+		t.Errorf("status code = %d, redirected = %v, want %d, false", statusCode, redirected, http.StatusTemporaryRedirect)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{cfg: config.WebhooksConfig{
+		InitialBackoff: 30 * time.Second,
+		MaxBackoff:     time.Hour,
+	}}
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{100, time.Hour},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 100; i++ {
+			got := d.backoff(tt.attempts)
+			if got > tt.want || got < tt.want-tt.want/4 {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", tt.attempts, got, tt.want-tt.want/4, tt.want)
+			}
+		}
+	}
+}
+
+func TestBackoffNeverNegative(t *testing.T) {
+	for _, initial := range []time.Duration{0, 1, 3, time.Millisecond} {
+		d := &Dispatcher{cfg: config.WebhooksConfig{InitialBackoff: initial, MaxBackoff: time.Second}}
+		for attempts := 1; attempts < 50; attempts++ {
+			if got := d.backoff(attempts); got < 0 {
+				t.Fatalf("backoff(%d) with InitialBackoff %v = %v, want >= 0", attempts, initial, got)
+			}
+		}
+	}
+}
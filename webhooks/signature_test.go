@@ -0,0 +1,50 @@
+package webhooks
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"id":"1"}`)
+	now := time.Unix(1700000000, 0)
+	valid := Sign(secret, now, body)
+
+	tests := []struct {
+		name   string
+		header string
+		body   []byte
+		now    time.Time
+		ok     bool
+	}{
+		{"valid", valid, body, now, true},
+		{"within tolerance before", valid, body, now.Add(5 * time.Minute), true},
+		{"within tolerance after", valid, body, now.Add(-5 * time.Minute), true},
+		{"expired", valid, body, now.Add(5*time.Minute + time.Second), false},
+		{"from the future", valid, body, now.Add(-5*time.Minute - time.Second), false},
+		{"other body", valid, []byte(`{"id":"2"}`), now, false},
+		{"other secret", Sign("whsec_other", now, body), body, now, false},
+		{"one of several signatures", valid + ",v1=00ff", body, now, true},
+		{"spaces around parts", strings.ReplaceAll(valid, ",", " , "), body, now, true},
+		{"empty", "", body, now, false},
+		{"no timestamp", valid[strings.Index(valid, ",")+1:], body, now, false},
+		{"no signature", valid[:strings.Index(valid, ",")], body, now, false},
+		{"timestamp not a number", strings.Replace(valid, "t=", "t=x", 1), body, now, false},
+		{"signature not hex", valid[:strings.Index(valid, "v1=")] + "v1=zz", body, now, false},
+		{"garbage", "garbage", body, now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(secret, tt.header, tt.body, tt.now, 5*time.Minute)
+			if tt.ok && err != nil {
+				t.Errorf("Verify() = %v, want nil", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify() = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
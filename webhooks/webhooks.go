@@ -0,0 +1,374 @@
+// Package webhooks delivers catalog events to HTTP endpoints. Deliveries are
+// queued in the transaction that records an event, POSTed by a Dispatcher
+// with an HMAC signature of the payload, and retried with exponential
+// backoff. Every attempt is logged.
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/lib/pq"
+
+	"Booking/tracing"
+)
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const (
+	endpointColumns = "id, url, event_types, description, enabled, created_by, created_at"
+	deliveryColumns = "id, endpoint_id, event_id, event_type, status, attempts, next_attempt_at, " +
+		"last_status_code, last_error, created_at, delivered_at"
+
+	secretPrefix = "whsec_"
+)
+
+var (
+	// ErrEndpointNotFound is returned when an endpoint id does not exist.
+	ErrEndpointNotFound = errors.New("webhook endpoint not found")
+	// ErrDeliveryNotFound is returned when a delivery id does not exist.
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	// ErrInvalidURL is returned for endpoint URLs that are not absolute
+	// http or https URLs.
+	ErrInvalidURL = errors.New("webhook URL must be an absolute http or https URL")
+)
+
+// Endpoint is a registered receiver of events. Its secret is only returned
+// by CreateEndpoint.
+type Endpoint struct {
+	ID  int64
+	URL string
+	// EventTypes filters the delivered events; all are delivered when it
+	// is empty.
+	EventTypes  []string
+	Description string
+	Enabled     bool
+	CreatedBy   string
+	CreatedAt   time.Time
+}
+
+// Delivery is the delivery of an event to an endpoint. Payload is only set
+// by GetDelivery.
+type Delivery struct {
+	ID             int64
+	EndpointID     int64
+	EventID        int64
+	EventType      string
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	DeliveredAt    sql.NullTime
+	Payload        []byte
+}
+
+// Attempt is a logged delivery attempt.
+type Attempt struct {
+	AttemptedAt  time.Time
+	StatusCode   int
+	Error        string
+	Duration     time.Duration
+	ResponseBody string
+}
+
+// Store manages endpoints and deliveries in the webhook_* tables.
+type Store struct {
+	db *tracing.DB
+}
+
+func NewStore(db *tracing.DB) *Store {
+	return &Store{db: db}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEndpoint(row rowScanner) (*Endpoint, error) {
+	e := &Endpoint{}
+	eventTypes := pgtype.TextArray{}
+	err := row.Scan(&e.ID, &e.URL, &eventTypes, &e.Description, &e.Enabled, &e.CreatedBy, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := eventTypes.AssignTo(&e.EventTypes); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func scanDelivery(row rowScanner, dest ...interface{}) (*Delivery, error) {
+	d := &Delivery{}
+	dest = append([]interface{}{
+		&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt,
+	}, dest...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// validateURL checks an endpoint URL. Private addresses are allowed, so
+// that endpoints can be local receivers, as only admins register them.
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+func eventTypesArray(eventTypes []string) (*pgtype.TextArray, error) {
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+	array := &pgtype.TextArray{}
+	if err := array.Set(eventTypes); err != nil {
+		return nil, err
+	}
+	return array, nil
+}
+
+// generateSecret returns a new signing secret.
+func generateSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// CreateEndpoint registers an endpoint and returns it with its signing
+// secret.
+func (s *Store) CreateEndpoint(ctx context.Context, e *Endpoint) (*Endpoint, string, error) {
+	if err := validateURL(e.URL); err != nil {
+		return nil, "", err
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, "", fmt.Errorf("generate webhook secret: %w", err)
+	}
+	eventTypes, err := eventTypesArray(e.EventTypes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sqlStatement := `
+		INSERT INTO webhook_endpoints (url, secret, event_types, description, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + endpointColumns
+
+	created, err := scanEndpoint(s.db.QueryRowContext(ctx, sqlStatement, e.URL, secret, eventTypes, e.Description, e.Enabled, e.CreatedBy))
+	if err != nil {
+		return nil, "", err
+	}
+	return created, secret, nil
+}
+
+func (s *Store) GetEndpoint(ctx context.Context, id int64) (*Endpoint, error) {
+	e, err := scanEndpoint(s.db.QueryRowContext(ctx, `SELECT `+endpointColumns+` FROM webhook_endpoints WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrEndpointNotFound
+	}
+	return e, err
+}
+
+// ListEndpoints returns the endpoints ordered by id.
+func (s *Store) ListEndpoints(ctx context.Context) ([]*Endpoint, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+endpointColumns+` FROM webhook_endpoints ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []*Endpoint
+	for rows.Next() {
+		e, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, rows.Err()
+}
+
+// UpdateEndpoint replaces the URL, event types, description and enabled
+// flag of the endpoint e.ID.
+func (s *Store) UpdateEndpoint(ctx context.Context, e *Endpoint) (*Endpoint, error) {
+	if err := validateURL(e.URL); err != nil {
+		return nil, err
+	}
+	eventTypes, err := eventTypesArray(e.EventTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStatement := `
+		UPDATE webhook_endpoints
+		SET url = $1, event_types = $2, description = $3, enabled = $4
+		WHERE id = $5
+		RETURNING ` + endpointColumns
+
+	updated, err := scanEndpoint(s.db.QueryRowContext(ctx, sqlStatement, e.URL, eventTypes, e.Description, e.Enabled, e.ID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrEndpointNotFound
+	}
+	return updated, err
+}
+
+// DeleteEndpoint deletes an endpoint with its deliveries.
+func (s *Store) DeleteEndpoint(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrEndpointNotFound
+	}
+	return nil
+}
+
+// ListDeliveries returns up to limit deliveries to an endpoint with ids
+// below beforeID, or any id when it is 0, newest first. An empty status
+// matches all deliveries.
+func (s *Store) ListDeliveries(ctx context.Context, endpointID int64, status string, beforeID int64, limit int) ([]*Delivery, error) {
+	if _, err := s.GetEndpoint(ctx, endpointID); err != nil {
+		return nil, err
+	}
+
+	sqlStatement := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries
+		WHERE endpoint_id = $1 AND ($2::text = '' OR status = $2) AND ($3::bigint = 0 OR id < $3)
+		ORDER BY id DESC
+		LIMIT $4
+	`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, endpointID, status, beforeID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetDelivery returns a delivery with its payload and its attempts, oldest
+// first.
+func (s *Store) GetDelivery(ctx context.Context, id int64) (*Delivery, []*Attempt, error) {
+	var payload string
+	d, err := scanDelivery(s.db.QueryRowContext(ctx, `SELECT `+deliveryColumns+`, payload FROM webhook_deliveries WHERE id = $1`, id), &payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	d.Payload = []byte(payload)
+
+	sqlStatement := `
+		SELECT attempted_at, status_code, error, duration_ms, response_body
+		FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY id
+	`
+	rows, err := s.db.QueryContext(ctx, sqlStatement, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var attempts []*Attempt
+	for rows.Next() {
+		a := &Attempt{}
+		var durationMS int64
+		if err := rows.Scan(&a.AttemptedAt, &a.StatusCode, &a.Error, &durationMS, &a.ResponseBody); err != nil {
+			return nil, nil, err
+		}
+		a.Duration = time.Duration(durationMS) * time.Millisecond
+		attempts = append(attempts, a)
+	}
+	return d, attempts, rows.Err()
+}
+
+// Replay makes failed deliveries to an endpoint pending again, with their
+// attempts reset. ids selects the deliveries; all failed deliveries to the
+// endpoint are replayed when it is empty. It returns the number replayed.
+func (s *Store) Replay(ctx context.Context, endpointID int64, ids []int64) (int64, error) {
+	if _, err := s.GetEndpoint(ctx, endpointID); err != nil {
+		return 0, err
+	}
+	if ids == nil {
+		ids = []int64{}
+	}
+
+	sqlStatement := `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = now()
+		WHERE endpoint_id = $1 AND status = 'failed' AND (cardinality($2::bigint[]) = 0 OR id = ANY($2))
+	`
+	res, err := s.db.ExecContext(ctx, sqlStatement, endpointID, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Enqueue queues the delivery of an event to every enabled endpoint whose
+// filter matches its type. The deliveries are sent once tx commits.
+func Enqueue(ctx context.Context, tx *tracing.Tx, eventID int64, eventType string, payload []byte) error {
+	sqlStatement := `
+		INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, payload)
+		SELECT id, $1, $2, $3
+		FROM webhook_endpoints
+		WHERE enabled AND (cardinality(event_types) = 0 OR $2 = ANY(event_types))
+	`
+	// JSONB parameters must be sent as text, lib/pq sends []byte as bytea.
+	_, err := tx.ExecContext(ctx, sqlStatement, eventID, eventType, string(payload))
+	return err
+}
+
+// Cleanup deletes deliveries created more than retention ago, with their
+// attempts, every interval until ctx is cancelled.
+func Cleanup(ctx context.Context, db *tracing.DB, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE created_at < $1`, now.Add(-retention)); err != nil {
+				slog.Error("Failed to delete old webhook deliveries", "error", err)
+			}
+		}
+	}
+}